@@ -2,10 +2,20 @@ module github.com/seaung/Luna
 
 go 1.22.4
 
-require github.com/traefik/yaegi v0.16.1
+require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/andybalholm/cascadia v1.3.2
+	github.com/antchfx/htmlquery v1.3.3
+	github.com/antchfx/xpath v1.3.2
+	github.com/manifoldco/promptui v0.9.0
+	github.com/traefik/yaegi v0.16.1
+	golang.org/x/crypto v0.28.0
+	golang.org/x/net v0.30.0
+)
 
 require (
 	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
-	github.com/manifoldco/promptui v0.9.0 // indirect
-	golang.org/x/sys v0.0.0-20181122145206-62eef0e2fa9b // indirect
+	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
+	golang.org/x/sys v0.26.0 // indirect
+	golang.org/x/text v0.19.0 // indirect
 )
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+const authUsage = `用法:
+  set auth <host> basic <user> <password>
+  set auth <host> digest <user> <password>
+  set auth <host> ntlm <[domain\]user> <password> [workstation]
+  set auth <host> bearer <token>
+  set auth <host> header <name> <value>
+  unset auth <host>
+<host> 以点开头时匹配所有子域名，如 .example.com`
+
+// setAuth 解析 "set auth" 参数并为主机配置认证方式
+func (s *Shell) setAuth(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf(authUsage)
+	}
+
+	host, kind, params := args[0], args[1], args[2:]
+
+	var auth network.Authenticator
+	switch kind {
+	case "basic":
+		if len(params) < 2 {
+			return fmt.Errorf(authUsage)
+		}
+		auth = network.BasicAuth{Username: params[0], Password: params[1]}
+	case "digest":
+		if len(params) < 2 {
+			return fmt.Errorf(authUsage)
+		}
+		auth = network.NewDigestAuth(params[0], params[1])
+	case "ntlm":
+		if len(params) < 2 {
+			return fmt.Errorf(authUsage)
+		}
+		ntlm := network.NTLMAuth{Username: params[0], Password: params[1]}
+		if i := strings.IndexByte(ntlm.Username, '\\'); i >= 0 {
+			ntlm.Domain, ntlm.Username = ntlm.Username[:i], ntlm.Username[i+1:]
+		}
+		if len(params) > 2 {
+			ntlm.Workstation = params[2]
+		}
+		auth = ntlm
+	case "bearer":
+		auth = network.BearerAuth{Token: params[0]}
+	case "header":
+		if len(params) < 2 {
+			return fmt.Errorf(authUsage)
+		}
+		auth = network.HeaderAuth{Name: params[0], Value: strings.Join(params[1:], " ")}
+	default:
+		return fmt.Errorf("未知的认证方式: %s\n%s", kind, authUsage)
+	}
+
+	s.Auth.Set(host, auth)
+	fmt.Printf("auth => %s (%s)\n", host, kind)
+	return nil
+}
+
+// showAuth 列出所有主机的认证方式，不显示密码和令牌
+func (s *Shell) showAuth() error {
+	entries := s.Auth.List()
+	if len(entries) == 0 {
+		fmt.Println("没有配置认证")
+		return nil
+	}
+
+	fmt.Println("认证:")
+	fmt.Println("=====")
+
+	for _, e := range entries {
+		kind, detail := "", ""
+		switch a := e.Auth.(type) {
+		case network.BasicAuth:
+			kind, detail = "basic", a.Username
+		case *network.DigestAuth:
+			kind, detail = "digest", a.Username
+		case network.NTLMAuth:
+			kind, detail = "ntlm", a.Username
+			if a.Domain != "" {
+				detail = a.Domain + `\` + a.Username
+			}
+		case network.BearerAuth:
+			kind = "bearer"
+		case network.HeaderAuth:
+			kind, detail = "header", a.Name
+		}
+
+		fmt.Printf("%-30s %-8s %s\n", e.Host, kind, detail)
+	}
+
+	return nil
+}
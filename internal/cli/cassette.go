@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+// cassette 根据 cassette 和 cassette_mode 选项返回共享的磁带，未设置选项时使用环境变量
+// 路径和模式不变时复用已打开的磁带，使多次执行的录制追加到同一文件、回放进度得以保留
+func (s *Shell) cassette() (*network.Cassette, error) {
+	opts := s.Context.Options
+
+	path, ok := opts["cassette"]
+	if !ok {
+		path = os.Getenv(network.CassetteEnv)
+	}
+	if path == "" {
+		s.closeCassette()
+		return nil, nil
+	}
+
+	modeValue, ok := opts["cassette_mode"]
+	if !ok {
+		modeValue = os.Getenv(network.CassetteModeEnv)
+	}
+	mode := network.CassetteReplay
+	if modeValue != "" {
+		m, err := network.ParseCassetteMode(modeValue)
+		if err != nil {
+			return nil, err
+		}
+		mode = m
+	}
+
+	if c := s.Cassette; c != nil && c.Path == path && c.Mode == mode {
+		return c, nil
+	}
+
+	c, err := network.OpenCassette(path, mode)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[*] 磁带 %s (%s)\n", path, mode)
+
+	s.closeCassette()
+	s.Cassette = c
+	return c, nil
+}
+
+// closeCassette 关闭当前的磁带，录制文件在每次交换后都已完整写入
+func (s *Shell) closeCassette() {
+	if s.Cassette == nil {
+		return
+	}
+	if err := s.Cassette.Close(); err != nil {
+		fmt.Printf("[!] 关闭磁带失败: %v\n", err)
+	}
+	s.Cassette = nil
+}
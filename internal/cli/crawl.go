@@ -0,0 +1,269 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/seaung/Luna/internal/crawler"
+	"github.com/seaung/Luna/internal/network"
+)
+
+const crawlUsage = "crawl <<url>|list [host]|show <id>|export <file>|clear>"
+
+// cmdCrawl 爬取站点并管理端点清单
+func (s *Shell) cmdCrawl(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("用法: %s", crawlUsage)
+	}
+
+	switch args[0] {
+	case "list":
+		host := ""
+		if len(args) > 1 {
+			host = args[1]
+		}
+		return s.crawlList(host)
+	case "show":
+		if len(args) < 2 {
+			return fmt.Errorf("用法: crawl show <id>")
+		}
+		return s.crawlShow(args[1])
+	case "export":
+		if len(args) < 2 {
+			return fmt.Errorf("用法: crawl export <file>")
+		}
+		return s.crawlExport(args[1])
+	case "clear":
+		s.Inventory.Clear()
+		fmt.Println("端点清单已清空")
+		return nil
+	default:
+		return s.crawl(args[0])
+	}
+}
+
+// crawlConfig 根据shell选项生成爬虫配置
+func (s *Shell) crawlConfig() (crawler.Config, error) {
+	var config crawler.Config
+	opts := s.Context.Options
+
+	if v, ok := opts["crawl_depth"]; ok {
+		n, err := parseCount(v)
+		if err != nil {
+			return config, fmt.Errorf("无效的crawl_depth: %v", err)
+		}
+		config.MaxDepth = n
+	}
+
+	if v, ok := opts["crawl_max_pages"]; ok {
+		n, err := parseCount(v)
+		if err != nil {
+			return config, fmt.Errorf("无效的crawl_max_pages: %v", err)
+		}
+		config.MaxPages = n
+	}
+
+	if v, ok := opts["crawl_scope"]; ok {
+		hosts, err := crawler.ParseScopeHosts(v)
+		if err != nil {
+			return config, err
+		}
+		config.Scope.Hosts = hosts
+	}
+
+	if v, ok := opts["crawl_include"]; ok {
+		list, err := crawler.ParsePatterns(v)
+		if err != nil {
+			return config, err
+		}
+		config.Scope.Include = list
+	}
+
+	if v, ok := opts["crawl_exclude"]; ok {
+		list, err := crawler.ParsePatterns(v)
+		if err != nil {
+			return config, err
+		}
+		config.Scope.Exclude = list
+	}
+
+	if v, ok := opts["crawl_obey_robots"]; ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return config, fmt.Errorf("无效的crawl_obey_robots: %s", v)
+		}
+		config.ObeyRobots = b
+	}
+
+	return config, nil
+}
+
+// crawl 从种子URL开始爬取，结果累积到端点清单
+func (s *Shell) crawl(seed string) error {
+	config, err := s.crawlConfig()
+	if err != nil {
+		return err
+	}
+	httpConfig, err := s.httpClientConfig()
+	if err != nil {
+		return err
+	}
+
+	s.scanSeq++
+	client := network.Tagged(network.NewHTTPClient(httpConfig), network.Tags{
+		Scan:   fmt.Sprintf("scan-%d", s.scanSeq),
+		Plugin: "crawler",
+		Target: seed,
+	})
+
+	before := s.Inventory.Len()
+	fmt.Printf("正在爬取 %s...\n", seed)
+	pages, err := crawler.New(client, config).Crawl(context.Background(), seed, s.Inventory)
+	if err != nil {
+		return fmt.Errorf("爬取失败: %v", err)
+	}
+
+	fmt.Printf("请求 %d 个页面，新增 %d 个端点，共 %d 个，使用 'crawl list' 查看\n", pages, s.Inventory.Len()-before, s.Inventory.Len())
+	return nil
+}
+
+// crawlList 列出端点清单，指定主机时只列出该主机的端点
+func (s *Shell) crawlList(host string) error {
+	endpoints := s.Inventory.List()
+	if host != "" {
+		endpoints = s.Inventory.ForHost(host)
+	}
+	if len(endpoints) == 0 {
+		fmt.Println("没有端点，使用 'crawl <url>' 爬取站点")
+		return nil
+	}
+
+	fmt.Println("端点清单:")
+	fmt.Println("=========")
+
+	for _, e := range endpoints {
+		names := make([]string, 0, len(e.Params))
+		for _, p := range e.Params {
+			names = append(names, p.Name)
+		}
+		status := "-"
+		if e.StatusCode != 0 {
+			status = strconv.Itoa(e.StatusCode)
+		}
+		fmt.Printf("#%-5d %-4s %-6s %-8s %s", e.ID, status, e.Method, e.Source, e.URL)
+		if len(names) > 0 {
+			fmt.Printf(" [%s]", strings.Join(names, ", "))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// crawlShow 显示端点的详细信息
+func (s *Shell) crawlShow(idStr string) error {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return fmt.Errorf("无效的ID: %s", idStr)
+	}
+
+	e, ok := s.Inventory.Get(id)
+	if !ok {
+		return fmt.Errorf("找不到端点: %d", id)
+	}
+
+	fmt.Printf("端点 #%d\n", e.ID)
+	fmt.Printf("方法: %s\n", e.Method)
+	fmt.Printf("URL: %s\n", e.URL)
+	fmt.Printf("目标: %s\n", e.Target())
+	if e.Enctype != "" {
+		fmt.Printf("编码: %s\n", e.Enctype)
+	}
+	fmt.Printf("来源: %s (深度 %d)\n", e.Source, e.Depth)
+	if e.Referer != "" {
+		fmt.Printf("发现于: %s\n", e.Referer)
+	}
+	if e.StatusCode != 0 {
+		fmt.Printf("响应: %d %s\n", e.StatusCode, e.ContentType)
+	}
+
+	if len(e.Params) > 0 {
+		fmt.Println("参数:")
+		for _, p := range e.Params {
+			fmt.Printf("  %-6s %s=%s", p.Location, p.Name, p.Value)
+			if p.Type != "" {
+				fmt.Printf(" (%s)", p.Type)
+			}
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+// crawlExport 将端点清单导出为JSON
+func (s *Shell) crawlExport(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建文件失败: %v", err)
+	}
+	defer f.Close()
+
+	if err := s.Inventory.WriteJSON(f); err != nil {
+		return fmt.Errorf("导出端点失败: %v", err)
+	}
+
+	fmt.Printf("已导出 %d 个端点到 %s\n", s.Inventory.Len(), path)
+	return nil
+}
+
+// cmdScan 对端点清单中的每个目标执行插件，指定主机时只扫描该主机的端点
+func (s *Shell) cmdScan(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("用法: %s", s.Commands["scan"].Usage)
+	}
+
+	pluginName := args[0]
+	if _, exists := s.PluginMgr.GetPlugin(pluginName); !exists {
+		return fmt.Errorf("找不到插件: %s", pluginName)
+	}
+
+	endpoints := s.Inventory.List()
+	if len(args) > 1 {
+		endpoints = s.Inventory.ForHost(args[1])
+	}
+
+	// 同一URL和查询参数的端点只扫描一次，插件可通过 env.Endpoints 获取方法和请求体参数
+	var targets []string
+	seen := make(map[string]bool)
+	for _, e := range endpoints {
+		if t := e.Target(); !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("没有可扫描的端点，请先使用 'crawl <url>' 爬取站点")
+	}
+
+	fmt.Printf("使用插件 '%s' 扫描 %d 个目标...\n", pluginName, len(targets))
+	vulnerable := 0
+	for _, target := range targets {
+		result, err := s.executePlugin(pluginName, target)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case result.Err != nil:
+			fmt.Printf("[-] %s: %v\n", target, result.Err)
+		case result.Vulnerable:
+			vulnerable++
+			fmt.Printf("[!] 目标 '%s' 存在漏洞!\n", target)
+		}
+	}
+
+	fmt.Printf("扫描完成，%d/%d 个目标存在漏洞\n", vulnerable, len(targets))
+	return nil
+}
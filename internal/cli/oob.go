@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/seaung/Luna/internal/oob"
+)
+
+const oobUsage = "oob <start [http=<addr>] [dns=<addr>] [domain=<domain>] [host=<host>] [ip=<ip>] [ttl=<duration>]|stop|status>"
+
+// cmdOOB 管理带外回连服务
+func (s *Shell) cmdOOB(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("用法: %s", oobUsage)
+	}
+
+	switch args[0] {
+	case "start":
+		return s.oobStart(args[1:])
+	case "stop":
+		if s.OOB == nil {
+			return fmt.Errorf("回连服务未运行")
+		}
+		if err := s.OOB.Stop(); err != nil {
+			return err
+		}
+		fmt.Println("回连服务已停止")
+		return nil
+	case "status":
+		return s.oobStatus()
+	default:
+		return fmt.Errorf("未知的oob子命令: %s", args[0])
+	}
+}
+
+// oobStart 按参数启动回连服务，http=off 或 dns=off 可关闭对应监听
+func (s *Shell) oobStart(args []string) error {
+	if s.OOB != nil && s.OOB.Running() {
+		return fmt.Errorf("回连服务已在运行，请先执行 oob stop")
+	}
+
+	config := oob.DefaultConfig()
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("用法: %s", oobUsage)
+		}
+		if value == "off" {
+			value = ""
+		}
+
+		switch key {
+		case "http":
+			config.HTTPAddr = value
+		case "dns":
+			config.DNSAddr = value
+		case "domain":
+			config.Domain = value
+		case "host":
+			config.PublicHost = value
+		case "ip":
+			if config.IP = net.ParseIP(value); config.IP == nil {
+				return fmt.Errorf("无效的ip: %s", value)
+			}
+		case "ttl":
+			ttl, err := parseDuration(value)
+			if err != nil || ttl <= 0 {
+				return fmt.Errorf("无效的ttl: %s", value)
+			}
+			config.TokenTTL = ttl
+		default:
+			return fmt.Errorf("未知的oob参数: %s", key)
+		}
+	}
+
+	server := oob.NewServer(config)
+	if err := server.Start(); err != nil {
+		return err
+	}
+	s.OOB = server
+
+	return s.oobStatus()
+}
+
+// oobStatus 显示回连服务状态和最近的交互
+func (s *Shell) oobStatus() error {
+	if s.OOB == nil || !s.OOB.Running() {
+		fmt.Println("回连服务未运行")
+	} else {
+		config := s.OOB.Config()
+		fmt.Println("回连服务:")
+		fmt.Println("=========")
+		if addr := s.OOB.HTTPAddr(); addr != "" {
+			fmt.Printf("HTTP: %s\n", addr)
+		}
+		if addr := s.OOB.DNSAddr(); addr != "" {
+			fmt.Printf("DNS: %s\n", addr)
+		}
+		if config.Domain != "" {
+			fmt.Printf("域名: %s\n", config.Domain)
+		}
+		fmt.Printf("令牌: %d\n", s.OOB.Tokens())
+	}
+
+	if s.OOB == nil {
+		return nil
+	}
+
+	interactions := s.OOB.Interactions()
+	if len(interactions) == 0 {
+		return nil
+	}
+
+	// 只显示最近的交互
+	const recent = 20
+	if len(interactions) > recent {
+		interactions = interactions[len(interactions)-recent:]
+	}
+
+	fmt.Println()
+	fmt.Println("最近的交互:")
+	fmt.Println("===========")
+	for _, it := range interactions {
+		owner := "(未匹配)"
+		if it.Token != "" {
+			owner = it.Plugin + "@" + it.Target
+		}
+		line, _, _ := strings.Cut(it.Data, "\n")
+		fmt.Printf("%s %-4s %-21s %-30s %s\n",
+			it.Time.Format("15:04:05"), it.Protocol, it.RemoteAddr, owner, strings.TrimSpace(line))
+	}
+
+	return nil
+}
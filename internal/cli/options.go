@@ -0,0 +1,370 @@
+package cli
+
+import (
+	"fmt"
+	"net/http/cookiejar"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seaung/Luna/internal/crawler"
+	"github.com/seaung/Luna/internal/network"
+	"github.com/seaung/Luna/pkg/helper"
+	"github.com/seaung/Luna/pkg/sdk"
+)
+
+// optionValidators 在设置选项时校验取值，便于尽早发现错误
+var optionValidators = map[string]func(string) error{
+	"timeout": func(v string) error {
+		_, err := parseDuration(v)
+		return err
+	},
+	"retry_status": func(v string) error {
+		_, err := parseStatusCodes(v)
+		return err
+	},
+	"proxy": func(v string) error {
+		_, err := network.ParseProxyURL(v)
+		return err
+	},
+	"cassette_mode": func(v string) error {
+		_, err := network.ParseCassetteMode(v)
+		return err
+	},
+	"protocol": func(v string) error {
+		_, err := network.ParseProtocol(v)
+		return err
+	},
+	"hosts": func(v string) error {
+		_, err := network.ParseHosts(v)
+		return err
+	},
+	"hosts_file": func(v string) error {
+		_, err := network.LoadHostsFile(v)
+		return err
+	},
+	"dns": func(v string) error {
+		_, err := network.ParseDNSServer(v)
+		return err
+	},
+	"redirects": func(v string) error {
+		_, err := network.ParseRedirectPolicy(v)
+		return err
+	},
+	"tls_insecure": func(v string) error {
+		_, err := strconv.ParseBool(v)
+		return err
+	},
+	"tls_min": func(v string) error {
+		_, err := network.ParseTLSVersion(v)
+		return err
+	},
+	"tls_max": func(v string) error {
+		_, err := network.ParseTLSVersion(v)
+		return err
+	},
+	"tls_ciphers": func(v string) error {
+		_, err := network.ParseCipherSuites(splitList(v))
+		return err
+	},
+	"max_body": func(v string) error {
+		_, err := parseSize(v)
+		return err
+	},
+	"rate": func(v string) error {
+		_, err := parseRate(v)
+		return err
+	},
+	"rate_burst": func(v string) error {
+		_, err := parseCount(v)
+		return err
+	},
+	"max_conns": func(v string) error {
+		_, err := parseCount(v)
+		return err
+	},
+	"host_rate": func(v string) error {
+		_, err := parseRate(v)
+		return err
+	},
+	"host_max_conns": func(v string) error {
+		_, err := parseCount(v)
+		return err
+	},
+	"crawl_depth": func(v string) error {
+		_, err := parseCount(v)
+		return err
+	},
+	"crawl_max_pages": func(v string) error {
+		_, err := parseCount(v)
+		return err
+	},
+	"crawl_scope": func(v string) error {
+		_, err := crawler.ParseScopeHosts(v)
+		return err
+	},
+	"crawl_include": func(v string) error {
+		_, err := crawler.ParsePatterns(v)
+		return err
+	},
+	"crawl_exclude": func(v string) error {
+		_, err := crawler.ParsePatterns(v)
+		return err
+	},
+	"crawl_obey_robots": func(v string) error {
+		_, err := strconv.ParseBool(v)
+		return err
+	},
+}
+
+// parseDuration 解析时长选项，纯数字按秒处理
+func parseDuration(value string) (time.Duration, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// parseSize 解析字节数选项，支持 KB、MB、GB 后缀，纯数字按字节处理
+func parseSize(value string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(value))
+	unit := int64(1)
+	for _, suffix := range []struct {
+		name string
+		size int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(v, suffix.name) {
+			v, unit = strings.TrimSuffix(v, suffix.name), suffix.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("无效的大小: %s", value)
+	}
+	return n * unit, nil
+}
+
+// parseStatusCodes 解析逗号分隔的状态码列表，"default" 表示常见的可重试状态码
+func parseStatusCodes(value string) ([]int, error) {
+	if value == "default" {
+		return network.DefaultRetryStatusCodes, nil
+	}
+
+	var codes []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("无效的状态码: %s", part)
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// splitList 解析逗号分隔的列表选项
+func splitList(value string) []string {
+	var list []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// httpClientConfig 根据shell选项生成HTTP客户端配置
+func (s *Shell) httpClientConfig() (network.HTTPClientConfig, error) {
+	config := network.DefaultHTTPClientConfig()
+	opts := s.Context.Options
+
+	if v, ok := opts["timeout"]; ok {
+		d, err := parseDuration(v)
+		if err != nil {
+			return config, fmt.Errorf("无效的timeout: %s", v)
+		}
+		config.Timeout = d
+	}
+
+	if v, ok := opts["retries"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return config, fmt.Errorf("无效的retries: %s", v)
+		}
+		config.MaxRetries = n
+	}
+
+	if v, ok := opts["retry_status"]; ok {
+		codes, err := parseStatusCodes(v)
+		if err != nil {
+			return config, err
+		}
+		config.RetryOnStatus = codes
+	}
+
+	if v, ok := opts["retry_non_idempotent"]; ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return config, fmt.Errorf("无效的retry_non_idempotent: %s", v)
+		}
+		config.RetryNonIdempotent = b
+	}
+
+	if v, ok := opts["max_body"]; ok {
+		n, err := parseSize(v)
+		if err != nil {
+			return config, err
+		}
+		config.MaxBodySize = n
+	}
+
+	if v, ok := opts["protocol"]; ok {
+		protocol, err := network.ParseProtocol(v)
+		if err != nil {
+			return config, err
+		}
+		config.Protocol = protocol
+	}
+
+	if v, ok := opts["proxy"]; ok {
+		config.Proxy = v
+	}
+
+	if v, ok := opts["no_proxy"]; ok {
+		config.NoProxy = splitList(v)
+	}
+
+	// hosts选项中的映射覆盖hosts_file中的同名主机
+	if v, ok := opts["hosts_file"]; ok {
+		hosts, err := network.LoadHostsFile(v)
+		if err != nil {
+			return config, fmt.Errorf("无效的hosts_file: %v", err)
+		}
+		config.Hosts = hosts
+	}
+	if v, ok := opts["hosts"]; ok {
+		hosts, err := network.ParseHosts(v)
+		if err != nil {
+			return config, err
+		}
+		if config.Hosts == nil {
+			config.Hosts = hosts
+		} else {
+			for host, ip := range hosts {
+				config.Hosts[host] = ip
+			}
+		}
+	}
+
+	if v, ok := opts["dns"]; ok {
+		server, err := network.ParseDNSServer(v)
+		if err != nil {
+			return config, err
+		}
+		config.DNSServer = server
+	}
+
+	if v, ok := opts["tls_insecure"]; ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return config, fmt.Errorf("无效的tls_insecure: %s", v)
+		}
+		config.TLS.InsecureSkipVerify = b
+	}
+
+	config.TLS.CAFile = opts["tls_ca"]
+	config.TLS.CertFile = opts["tls_cert"]
+	config.TLS.KeyFile = opts["tls_key"]
+	config.TLS.ServerName = opts["tls_sni"]
+	config.TLS.MinVersion = opts["tls_min"]
+	config.TLS.MaxVersion = opts["tls_max"]
+	if v, ok := opts["tls_ciphers"]; ok {
+		config.TLS.CipherSuites = splitList(v)
+	}
+
+	if v, ok := opts["cookies"]; ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return config, fmt.Errorf("无效的cookies: %s", v)
+		}
+		config.CookieJar = b
+	}
+	// Cookie Jar 保存在shell上，未定义会话的主机返回的Cookie在多次执行之间保留
+	if config.CookieJar && s.Cookies == nil {
+		s.Cookies, _ = cookiejar.New(nil)
+	}
+	config.Jar = s.Cookies
+	config.Sessions = s.Sessions
+	config.Auth = s.Auth
+	config.Traffic = s.Traffic
+
+	if err := s.applyRateLimits(); err != nil {
+		return config, err
+	}
+	config.RateLimiter = s.Limiter
+
+	cassette, err := s.cassette()
+	if err != nil {
+		return config, err
+	}
+	config.Cassette = cassette
+
+	if v, ok := opts["redirects"]; ok {
+		policy, err := network.ParseRedirectPolicy(v)
+		if err != nil {
+			return config, err
+		}
+		config.Redirect = policy
+	}
+
+	if v, ok := opts["user_agent"]; ok {
+		config.DefaultHeaders["User-Agent"] = v
+	}
+
+	return config, nil
+}
+
+// services 根据shell选项生成注入插件的宿主服务
+func (s *Shell) services() (sdk.Services, error) {
+	config, err := s.httpClientConfig()
+	if err != nil {
+		return sdk.Services{}, err
+	}
+
+	level := helper.LevelInfo
+	if v, ok := s.Context.Options["log_level"]; ok {
+		if level, err = helper.ParseLevel(v); err != nil {
+			return sdk.Services{}, err
+		}
+	}
+
+	svc := sdk.Services{
+		HTTP:      network.NewHTTPClient(config),
+		Raw:       network.NewRawClient(config),
+		Socket:    network.NewSocketClient(config),
+		WebSocket: network.NewWebSocketClient(config),
+		KV:        s.KV,
+		LogLevel:  level,
+		Endpoints: s.Inventory,
+	}
+
+	// 内置回连服务优先于外部回连域名
+	if server := s.OOB; server != nil && server.Running() {
+		svc.OOB = func(pluginName, target string) sdk.OOB {
+			return server.Client(pluginName, target)
+		}
+	} else if domain, ok := s.Context.Options["oob_domain"]; ok {
+		svc.OOB = func(pluginName, target string) sdk.OOB {
+			return sdk.StaticOOB{Domain: domain}
+		}
+	}
+
+	return svc, nil
+}
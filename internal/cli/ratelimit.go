@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+const limitUsage = `用法:
+  set limit <host> <rps>[/<max_conns>]
+  unset limit <host>
+<host> 以点开头时匹配所有子域名，如 .example.com；<rps> 为0表示不限速`
+
+// parseRate 解析每秒请求数选项
+func parseRate(value string) (float64, error) {
+	rps, err := strconv.ParseFloat(value, 64)
+	if err != nil || rps < 0 {
+		return 0, fmt.Errorf("必须是非负数: %s", value)
+	}
+	return rps, nil
+}
+
+// parseCount 解析非负整数选项
+func parseCount(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("必须是非负整数: %s", value)
+	}
+	return n, nil
+}
+
+// applyRateLimits 将限速选项同步到共享的限速器
+// 限速器在多次执行之间保留状态，修改选项只会更新限制
+func (s *Shell) applyRateLimits() error {
+	opts := s.Context.Options
+
+	var global, host network.RateLimit
+	var err error
+	if v, ok := opts["rate"]; ok {
+		if global.RPS, err = parseRate(v); err != nil {
+			return fmt.Errorf("无效的rate: %v", err)
+		}
+	}
+	if v, ok := opts["rate_burst"]; ok {
+		if global.Burst, err = parseCount(v); err != nil {
+			return fmt.Errorf("无效的rate_burst: %v", err)
+		}
+	}
+	if v, ok := opts["max_conns"]; ok {
+		if global.MaxConns, err = parseCount(v); err != nil {
+			return fmt.Errorf("无效的max_conns: %v", err)
+		}
+	}
+	if v, ok := opts["host_rate"]; ok {
+		if host.RPS, err = parseRate(v); err != nil {
+			return fmt.Errorf("无效的host_rate: %v", err)
+		}
+	}
+	if v, ok := opts["host_max_conns"]; ok {
+		if host.MaxConns, err = parseCount(v); err != nil {
+			return fmt.Errorf("无效的host_max_conns: %v", err)
+		}
+	}
+
+	s.Limiter.SetGlobal(global)
+	s.Limiter.SetHostDefault(host)
+	return nil
+}
+
+// setLimit 解析 "set limit" 参数并为主机设置限制
+func (s *Shell) setLimit(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf(limitUsage)
+	}
+
+	limit, err := network.ParseRateLimit(args[1])
+	if err != nil {
+		return err
+	}
+
+	s.Limiter.SetHost(args[0], limit)
+	fmt.Printf("limit => %s (%s)\n", args[0], limit)
+	return nil
+}
+
+// showRates 显示限速配置和当前速率
+func (s *Shell) showRates() error {
+	if err := s.applyRateLimits(); err != nil {
+		return err
+	}
+
+	rules := s.Limiter.HostRules()
+	if len(rules) > 0 {
+		fmt.Println("主机限制:")
+		fmt.Println("=========")
+
+		hosts := make([]string, 0, len(rules))
+		for host := range rules {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		for _, host := range hosts {
+			fmt.Printf("%-30s %s\n", host, rules[host])
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("当前速率:")
+	fmt.Println("=========")
+	fmt.Printf("%-30s %-26s %-10s %-8s %s\n", "主机", "限制", "速率", "并发", "总数")
+
+	for _, st := range s.Limiter.Stats() {
+		scope := st.Scope
+		if scope == "*" {
+			scope = "* (全局)"
+		}
+		fmt.Printf("%-30s %-26s %-10s %-8d %d\n",
+			scope, st.Limit, fmt.Sprintf("%.1f/s", st.CurrentRPS), st.InFlight, st.Total)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+const sessionUsage = `用法:
+  set session <host> cookie [ttl=<duration>]
+  set session <host> form <login_url> <field=value&...> [ttl=<duration>]
+  set session <host> token <token_url> <json_body> [field=<path>] [header=<name>] [prefix=<prefix>] [ttl=<duration>]
+  set session <host> header <name> <value>
+  unset session <host>
+<host> 以点开头时匹配所有子域名，如 .example.com`
+
+// setSession 解析 "set session" 参数并注册会话
+func (s *Shell) setSession(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf(sessionUsage)
+	}
+
+	host, kind := args[0], args[1]
+	params, kv := splitSessionParams(args[2:])
+
+	var login network.LoginRecipe
+	switch kind {
+	case "cookie":
+	case "form":
+		if len(params) < 2 {
+			return fmt.Errorf(sessionUsage)
+		}
+		fields, err := url.ParseQuery(params[1])
+		if err != nil {
+			return fmt.Errorf("无效的表单字段: %v", err)
+		}
+		login = network.FormLogin{URL: params[0], Fields: fields}
+	case "token":
+		if len(params) < 2 {
+			return fmt.Errorf(sessionUsage)
+		}
+		login = network.TokenLogin{
+			URL:        params[0],
+			Body:       []byte(params[1]),
+			TokenField: kv["field"],
+			Header:     kv["header"],
+			Prefix:     kv["prefix"],
+		}
+	case "header":
+		if len(params) < 2 {
+			return fmt.Errorf(sessionUsage)
+		}
+		login = network.StaticHeaders{params[0]: strings.Join(params[1:], " ")}
+	default:
+		return fmt.Errorf("未知的会话类型: %s\n%s", kind, sessionUsage)
+	}
+
+	sess := network.NewSession(host, login)
+	if v, ok := kv["ttl"]; ok {
+		ttl, err := parseDuration(v)
+		if err != nil {
+			return fmt.Errorf("无效的ttl: %s", v)
+		}
+		sess.TTL = ttl
+	}
+
+	s.Sessions.Add(sess)
+	fmt.Printf("session => %s (%s)\n", host, kind)
+	return nil
+}
+
+// splitSessionParams 将参数拆分为位置参数和 key=value 形式的选项
+// 只有 field、header、prefix、ttl 被视为选项，其余参数原样保留
+func splitSessionParams(args []string) ([]string, map[string]string) {
+	var params []string
+	kv := make(map[string]string)
+
+	for _, arg := range args {
+		if i := strings.IndexByte(arg, '='); i > 0 {
+			switch key := arg[:i]; key {
+			case "field", "header", "prefix", "ttl":
+				kv[key] = arg[i+1:]
+				continue
+			}
+		}
+		params = append(params, arg)
+	}
+
+	return params, kv
+}
+
+// showSessions 列出所有会话
+func (s *Shell) showSessions() error {
+	sessions := s.Sessions.List()
+	if len(sessions) == 0 {
+		fmt.Println("没有定义会话")
+		return nil
+	}
+
+	fmt.Println("会话:")
+	fmt.Println("=====")
+
+	for _, sess := range sessions {
+		kind := "cookie"
+		switch sess.Login.(type) {
+		case network.FormLogin:
+			kind = "form"
+		case network.TokenLogin:
+			kind = "token"
+		case network.StaticHeaders:
+			kind = "header"
+		}
+
+		status := "未登录"
+		if t := sess.LoggedInAt(); !t.IsZero() {
+			status = "登录于 " + t.Format("15:04:05")
+		}
+
+		ttl := "不过期"
+		if sess.TTL > 0 {
+			ttl = sess.TTL.Round(time.Second).String()
+		}
+
+		fmt.Printf("%-30s %-8s %-10s %s\n", sess.Host, kind, ttl, status)
+	}
+
+	return nil
+}
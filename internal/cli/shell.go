@@ -2,11 +2,17 @@ package cli
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/manifoldco/promptui"
+	"github.com/seaung/Luna/internal/crawler"
+	"github.com/seaung/Luna/internal/network"
+	"github.com/seaung/Luna/internal/oob"
 	"github.com/seaung/Luna/internal/plugin"
+	"github.com/seaung/Luna/internal/storage"
 )
 
 // Command 表示一个CLI命令
@@ -28,10 +34,21 @@ type CommandContext struct {
 type Shell struct {
 	Commands       map[string]Command
 	PluginMgr      *plugin.PluginManager
+	Results        storage.Store
+	KV             *storage.KVStore
+	Sessions       *network.SessionStore
+	Auth           *network.AuthStore
+	Traffic        *network.TrafficLog
+	Limiter        *network.RateLimiter
+	OOB            *oob.Server
+	Cassette       *network.Cassette
+	Cookies        http.CookieJar
+	Inventory      *crawler.Inventory
 	Context        CommandContext
 	Prompt         string
 	History        []string
 	HistoryMaxSize int
+	scanSeq        int
 }
 
 // NewShell 创建一个新的Shell实例
@@ -39,6 +56,13 @@ func NewShell() *Shell {
 	return &Shell{
 		Commands:       make(map[string]Command),
 		PluginMgr:      plugin.NewPluginManager(),
+		Results:        storage.NewMemoryStore(),
+		KV:             storage.NewKVStore(),
+		Sessions:       network.NewSessionStore(),
+		Auth:           network.NewAuthStore(),
+		Traffic:        network.NewTrafficLog(0),
+		Limiter:        network.NewRateLimiter(),
+		Inventory:      crawler.NewInventory(),
 		Prompt:         "luna > ",
 		History:        make([]string, 0),
 		HistoryMaxSize: 100,
@@ -154,10 +178,38 @@ func (s *Shell) setupCommands() {
 	s.RegisterCommand(Command{
 		Name:        "show",
 		Description: "显示信息",
-		Usage:       "show [options|plugins]",
+		Usage:       "show [options|plugins|results|output <id>|sessions|auth|rates]",
 		Action:      s.cmdShow,
 	})
 
+	s.RegisterCommand(Command{
+		Name:        "traffic",
+		Description: "浏览和导出HTTP流量记录",
+		Usage:       trafficUsage,
+		Action:      s.cmdTraffic,
+	})
+
+	s.RegisterCommand(Command{
+		Name:        "crawl",
+		Description: "爬取站点并管理端点清单",
+		Usage:       crawlUsage,
+		Action:      s.cmdCrawl,
+	})
+
+	s.RegisterCommand(Command{
+		Name:        "scan",
+		Description: "对端点清单中的每个目标执行插件",
+		Usage:       "scan <plugin_name> [host]",
+		Action:      s.cmdScan,
+	})
+
+	s.RegisterCommand(Command{
+		Name:        "oob",
+		Description: "管理带外回连服务",
+		Usage:       oobUsage,
+		Action:      s.cmdOOB,
+	})
+
 	s.RegisterCommand(Command{
 		Name:        "history",
 		Description: "显示命令历史",
@@ -281,13 +333,16 @@ func (s *Shell) cmdExecPlugin(args []string) error {
 	}
 
 	fmt.Printf("执行插件 '%s'...\n", pluginName)
-	success, err := s.PluginMgr.ExecutePlugin(pluginName, target)
-
+	result, err := s.executePlugin(pluginName, target)
 	if err != nil {
-		return fmt.Errorf("执行插件失败: %v", err)
+		return err
 	}
 
-	if success {
+	if result.Err != nil {
+		return fmt.Errorf("执行插件失败: %v", result.Err)
+	}
+
+	if result.Vulnerable {
 		fmt.Println("插件执行成功")
 	} else {
 		fmt.Println("插件执行失败")
@@ -322,11 +377,49 @@ func (s *Shell) cmdSetOption(args []string) error {
 	option := args[0]
 	value := args[1]
 
+	// 会话定义包含多个参数，单独处理
+	if option == "session" {
+		return s.setSession(args[1:])
+	}
+
+	if option == "auth" {
+		return s.setAuth(args[1:])
+	}
+
+	if option == "limit" {
+		return s.setLimit(args[1:])
+	}
+
+	if validate, ok := optionValidators[option]; ok {
+		if err := validate(value); err != nil {
+			return fmt.Errorf("无效的 %s: %v", option, err)
+		}
+	}
+
 	// 特殊处理target选项
 	if option == "target" {
 		s.Context.Target = value
 	}
 
+	// 特殊处理traffic_limit选项
+	if option == "traffic_limit" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("traffic_limit 必须是正整数")
+		}
+		s.Traffic.SetLimit(n)
+	}
+
+	// 特殊处理verbose选项
+	if option == "verbose" {
+		verbose, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("verbose 的值必须是 true 或 false")
+		}
+		s.PluginMgr.SetVerbose(verbose)
+		value = strconv.FormatBool(verbose)
+	}
+
 	// 保存到选项映射
 	s.Context.Options[option] = value
 	fmt.Printf("%s => %s\n", option, value)
@@ -341,11 +434,52 @@ func (s *Shell) cmdUnsetOption(args []string) error {
 
 	option := args[0]
 
+	if option == "session" {
+		if len(args) < 2 {
+			return fmt.Errorf("用法: unset session <host>")
+		}
+		if !s.Sessions.Remove(args[1]) {
+			return fmt.Errorf("会话不存在: %s", args[1])
+		}
+		fmt.Printf("会话 %s 已清除\n", args[1])
+		return nil
+	}
+
+	if option == "auth" {
+		if len(args) < 2 {
+			return fmt.Errorf("用法: unset auth <host>")
+		}
+		if !s.Auth.Remove(args[1]) {
+			return fmt.Errorf("主机认证不存在: %s", args[1])
+		}
+		fmt.Printf("主机 %s 的认证已清除\n", args[1])
+		return nil
+	}
+
+	if option == "limit" {
+		if len(args) < 2 {
+			return fmt.Errorf("用法: unset limit <host>")
+		}
+		if !s.Limiter.RemoveHost(args[1]) {
+			return fmt.Errorf("主机限制不存在: %s", args[1])
+		}
+		fmt.Printf("主机 %s 的限制已清除\n", args[1])
+		return nil
+	}
+
 	// 特殊处理target选项
 	if option == "target" {
 		s.Context.Target = ""
 	}
 
+	if option == "verbose" {
+		s.PluginMgr.SetVerbose(false)
+	}
+
+	if option == "traffic_limit" {
+		s.Traffic.SetLimit(0)
+	}
+
 	// 从选项映射中删除
 	delete(s.Context.Options, option)
 	fmt.Printf("%s 已清除\n", option)
@@ -391,28 +525,22 @@ func (s *Shell) cmdRunPlugin(args []string) error {
 		return fmt.Errorf("请先使用 'set target <target_value>' 设置目标")
 	}
 
-	plugins := s.PluginMgr.ListPlugins()
-	var targetPlugin plugin.VulnPlugin
-
-	for _, p := range plugins {
-		if p.Meta().Name == s.Context.PluginName {
-			targetPlugin = p
-			break
-		}
-	}
-
-	if targetPlugin == nil {
+	if _, exists := s.PluginMgr.GetPlugin(s.Context.PluginName); !exists {
 		return fmt.Errorf("找不到插件: %s", s.Context.PluginName)
 	}
 
 	fmt.Printf("正在运行插件 '%s' 检测目标 '%s'...\n", s.Context.PluginName, s.Context.Target)
 
-	vuln, err := targetPlugin.Run(s.Context.Target)
+	result, err := s.executePlugin(s.Context.PluginName, s.Context.Target)
 	if err != nil {
-		return fmt.Errorf("插件运行失败: %v", err)
+		return err
+	}
+
+	if result.Err != nil {
+		return fmt.Errorf("插件运行失败: %v", result.Err)
 	}
 
-	if vuln {
+	if result.Vulnerable {
 		fmt.Printf("[!] 目标 '%s' 存在漏洞!\n", s.Context.Target)
 	} else {
 		fmt.Printf("[+] 目标 '%s' 安全\n", s.Context.Target)
@@ -421,6 +549,45 @@ func (s *Shell) cmdRunPlugin(args []string) error {
 	return nil
 }
 
+// executePlugin 执行插件并将结果和捕获的输出保存到结果存储
+func (s *Shell) executePlugin(pluginName, target string) (*plugin.ExecResult, error) {
+	svc, err := s.services()
+	if err != nil {
+		return nil, err
+	}
+	s.scanSeq++
+	svc.Scan = fmt.Sprintf("scan-%d", s.scanSeq)
+	s.PluginMgr.SetServices(svc)
+
+	result, err := s.PluginMgr.Execute(pluginName, target)
+	if err != nil {
+		return nil, fmt.Errorf("执行插件失败: %v", err)
+	}
+
+	record := &storage.Result{
+		Plugin:     result.Plugin,
+		Target:     result.Target,
+		Vulnerable: result.Vulnerable,
+		Output:     result.Output.String(),
+		StartedAt:  result.StartedAt,
+		FinishedAt: result.FinishedAt,
+	}
+	if result.Err != nil {
+		record.Error = result.Err.Error()
+	}
+
+	id, err := s.Results.Save(record)
+	if err != nil {
+		return nil, fmt.Errorf("保存执行结果失败: %v", err)
+	}
+
+	if n := result.Output.Len(); n > 0 && s.Context.Options["verbose"] != "true" {
+		fmt.Printf("插件输出 %d 字节已保存，使用 'show output %d' 查看\n", n, id)
+	}
+
+	return result, nil
+}
+
 // cmdShow 显示信息
 func (s *Shell) cmdShow(args []string) error {
 	if len(args) < 1 {
@@ -442,6 +609,19 @@ func (s *Shell) cmdShow(args []string) error {
 		}
 	case "plugins":
 		return s.cmdListPlugins(nil)
+	case "results":
+		return s.showResults()
+	case "sessions":
+		return s.showSessions()
+	case "auth":
+		return s.showAuth()
+	case "rates":
+		return s.showRates()
+	case "output":
+		if len(args) < 2 {
+			return fmt.Errorf("用法: show output <id>")
+		}
+		return s.showOutput(args[1])
 	default:
 		return fmt.Errorf("未知的show子命令: %s", args[0])
 	}
@@ -449,6 +629,60 @@ func (s *Shell) cmdShow(args []string) error {
 	return nil
 }
 
+// showResults 列出所有执行结果
+func (s *Shell) showResults() error {
+	results, err := s.Results.List()
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Println("没有执行结果")
+		return nil
+	}
+
+	fmt.Println("执行结果:")
+	fmt.Println("=========")
+
+	for _, r := range results {
+		status := "安全"
+		if r.Error != "" {
+			status = "错误"
+		} else if r.Vulnerable {
+			status = "存在漏洞"
+		}
+		fmt.Printf("#%-4d %-20s %-30s %-8s %s\n", r.ID, r.Plugin, r.Target, status, r.StartedAt.Format("15:04:05"))
+	}
+
+	return nil
+}
+
+// showOutput 显示指定执行结果捕获的插件输出
+func (s *Shell) showOutput(idStr string) error {
+	id, err := strconv.Atoi(strings.TrimPrefix(idStr, "#"))
+	if err != nil {
+		return fmt.Errorf("无效的结果ID: %s", idStr)
+	}
+
+	r, err := s.Results.Get(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("插件: %s  目标: %s\n", r.Plugin, r.Target)
+	fmt.Println("=========")
+	if r.Output == "" {
+		fmt.Println("(无输出)")
+	} else {
+		fmt.Print(r.Output)
+	}
+	if r.Error != "" {
+		fmt.Printf("错误: %s\n", r.Error)
+	}
+
+	return nil
+}
+
 // cmdHistory 显示命令历史
 func (s *Shell) cmdHistory(args []string) error {
 	if len(s.History) == 0 {
@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+const trafficUsage = "traffic <list|show <id>|export <file>|clear> [plugin=<name>] [target=<target>] [scan=<id>]"
+
+// cmdTraffic 浏览和导出流量记录
+func (s *Shell) cmdTraffic(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("用法: %s", trafficUsage)
+	}
+
+	switch args[0] {
+	case "list":
+		return s.trafficList(parseTrafficFilter(args[1:]))
+	case "show":
+		if len(args) < 2 {
+			return fmt.Errorf("用法: traffic show <id>")
+		}
+		return s.trafficShow(args[1])
+	case "export":
+		if len(args) < 2 {
+			return fmt.Errorf("用法: traffic export <file> [plugin=<name>] [target=<target>] [scan=<id>]")
+		}
+		return s.trafficExport(args[1], parseTrafficFilter(args[2:]))
+	case "clear":
+		s.Traffic.Clear()
+		fmt.Println("流量记录已清空")
+		return nil
+	default:
+		return fmt.Errorf("未知的traffic子命令: %s", args[0])
+	}
+}
+
+// parseTrafficFilter 解析 key=value 形式的筛选条件
+func parseTrafficFilter(args []string) network.TrafficFilter {
+	var filter network.TrafficFilter
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "plugin":
+			filter.Plugin = value
+		case "target":
+			filter.Target = value
+		case "scan":
+			filter.Scan = value
+		}
+	}
+	return filter
+}
+
+// trafficList 列出流量记录
+func (s *Shell) trafficList(filter network.TrafficFilter) error {
+	entries := s.Traffic.List(filter)
+	if len(entries) == 0 {
+		fmt.Println("没有流量记录")
+		return nil
+	}
+
+	fmt.Println("流量记录:")
+	fmt.Println("=========")
+
+	for _, e := range entries {
+		fmt.Printf("#%-5d %s %-16s %s\n", e.ID, e.StartedAt.Format("15:04:05"), e.Tags.Plugin, e.Summary())
+	}
+
+	return nil
+}
+
+// trafficShow 显示单条流量记录的完整请求和响应
+func (s *Shell) trafficShow(idStr string) error {
+	id, err := strconv.Atoi(strings.TrimPrefix(idStr, "#"))
+	if err != nil {
+		return fmt.Errorf("无效的记录ID: %s", idStr)
+	}
+
+	e, ok := s.Traffic.Get(id)
+	if !ok {
+		return fmt.Errorf("流量记录 #%d 不存在", id)
+	}
+
+	fmt.Printf("#%d  扫描: %s  插件: %s  目标: %s  耗时: %s\n", e.ID, e.Tags.Scan, e.Tags.Plugin, e.Tags.Target, e.Duration)
+	if e.Socket {
+		showSocketEntry(e)
+		return nil
+	}
+	if e.RemoteAddr != "" {
+		t := e.Timing
+		fmt.Printf("对端: %s  DNS: %s  连接: %s  TLS: %s  首字节: %s\n", e.RemoteAddr, t.DNS, t.Connect, t.TLS, t.TTFB)
+	}
+	fmt.Println("---------- 请求 ----------")
+	fmt.Printf("%s %s %s\n", e.Method, e.URL, e.Proto)
+	printHeaders(e.RequestHeaders)
+	fmt.Println()
+	if len(e.RequestBody) > 0 {
+		fmt.Println(string(e.RequestBody))
+	}
+
+	fmt.Println("---------- 响应 ----------")
+	if e.Error != "" {
+		fmt.Printf("错误: %s\n", e.Error)
+		return nil
+	}
+	fmt.Printf("%s %s\n", e.ResponseProto, e.Status)
+	printHeaders(e.ResponseHeaders)
+	fmt.Println()
+	fmt.Println(string(e.ResponseBody))
+	if e.WebSocket {
+		showWebSocketMessages(e)
+	}
+	if e.Truncated {
+		fmt.Println("(内容已截断)")
+	}
+
+	return nil
+}
+
+// showWebSocketMessages 按时间顺序显示WebSocket会话收发的帧，二进制帧以十六进制显示
+func showWebSocketMessages(e *network.TrafficEntry) {
+	fmt.Printf("---------- 消息 (%d) ----------\n", len(e.Messages))
+	for _, m := range e.Messages {
+		dir := "<"
+		if m.Sent {
+			dir = ">"
+		}
+		offset := m.Time.Sub(e.StartedAt).Round(time.Millisecond)
+
+		if m.Opcode == network.WSBinary || !utf8.Valid(m.Data) {
+			fmt.Printf("%s +%s opcode=%d %d 字节\n%s", dir, offset, m.Opcode, len(m.Data), hex.Dump(m.Data))
+			continue
+		}
+		fmt.Printf("%s +%s opcode=%d %s\n", dir, offset, m.Opcode, m.Data)
+	}
+}
+
+// showSocketEntry 以十六进制形式显示套接字会话收发的数据
+func showSocketEntry(e *network.TrafficEntry) {
+	fmt.Printf("%s %s  %s\n", e.Method, e.URL, e.Status)
+	if e.Error != "" {
+		fmt.Printf("错误: %s\n", e.Error)
+	}
+	fmt.Println("---------- 发送 ----------")
+	fmt.Print(hex.Dump(e.RequestBody))
+	fmt.Println("---------- 接收 ----------")
+	fmt.Print(hex.Dump(e.ResponseBody))
+	if e.Truncated {
+		fmt.Println("(内容已截断)")
+	}
+}
+
+// printHeaders 按名称排序打印请求头
+func printHeaders(h map[string][]string) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, v := range h[name] {
+			fmt.Printf("%s: %s\n", name, v)
+		}
+	}
+}
+
+// trafficExport 将流量记录导出为HAR文件
+func (s *Shell) trafficExport(path string, filter network.TrafficFilter) error {
+	entries := s.Traffic.List(filter)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建文件失败: %v", err)
+	}
+	defer f.Close()
+
+	if err := network.WriteHAR(f, entries); err != nil {
+		return fmt.Errorf("导出HAR失败: %v", err)
+	}
+
+	fmt.Printf("已导出 %d 条记录到 %s\n", len(entries), path)
+	return nil
+}
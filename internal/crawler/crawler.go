@@ -0,0 +1,396 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+// 爬虫的默认参数
+const (
+	DefaultMaxDepth    = 3
+	DefaultMaxPages    = 500
+	DefaultConcurrency = 4
+)
+
+// maxSitemaps 限制一次爬取读取的站点地图数量，避免嵌套索引过大
+const maxSitemaps = 20
+
+// maxRedirects 限制从一个页面开始连续跟随的重定向次数，重定向不计入深度
+const maxRedirects = 10
+
+// Config 配置爬虫，数值字段为0时使用默认值
+type Config struct {
+	// MaxDepth 是从种子页面开始跟随链接的最大层数
+	MaxDepth int
+	// MaxPages 是一次爬取最多请求的页面数，包括robots.txt和站点地图
+	MaxPages int
+	// Concurrency 是同时请求的页面数，实际速率仍受客户端的速率限制约束
+	Concurrency int
+	Scope       Scope
+	// ObeyRobots 为true时不访问robots.txt禁止的路径；默认会访问这些路径并记录为端点
+	ObeyRobots bool
+	// SkipRobots 和 SkipSitemap 不读取robots.txt和站点地图
+	SkipRobots  bool
+	SkipSitemap bool
+}
+
+// Crawler 使用HTTP客户端爬取站点，生成端点清单
+// 只发送GET请求，不提交表单，表单和脚本中发现的接口只记录到清单
+type Crawler struct {
+	client network.HTTPClient
+	config Config
+}
+
+// New 创建爬虫，请求经由client发送，共享其代理、会话、认证和流量记录
+func New(client network.HTTPClient, config Config) *Crawler {
+	if config.MaxDepth <= 0 {
+		config.MaxDepth = DefaultMaxDepth
+	}
+	if config.MaxPages <= 0 {
+		config.MaxPages = DefaultMaxPages
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultConcurrency
+	}
+	return &Crawler{client: client, config: config}
+}
+
+// task 是待请求的页面
+type task struct {
+	url     *url.URL
+	depth   int
+	source  string
+	referer string
+	// redirects 是到达该页面已经过的连续重定向次数
+	redirects int
+}
+
+// crawlRun 是一次爬取的状态
+type crawlRun struct {
+	*Crawler
+	inv    *Inventory
+	scope  Scope
+	robots *Robots
+
+	mu      sync.Mutex
+	visited map[string]bool
+	pages   int
+}
+
+// Crawl 从种子URL开始爬取，发现的端点加入inv，返回请求的页面数
+// 范围未指定主机时只爬取种子URL的主机，DefaultExclude 总是生效；上下文取消时返回已请求的页面数和上下文的错误
+func (c *Crawler) Crawl(ctx context.Context, seed string, inv *Inventory) (int, error) {
+	u, err := url.Parse(strings.TrimSpace(seed))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return 0, fmt.Errorf("无效的种子URL: %s", seed)
+	}
+	u.Fragment = ""
+
+	// 不自动跟随重定向，范围内的Location作为新页面请求，避免把会话和认证信息发往范围外的主机
+	ctx = network.WithRedirectPolicy(ctx, network.RedirectPolicy{Mode: network.RedirectNone})
+
+	r := &crawlRun{
+		Crawler: c,
+		inv:     inv,
+		scope:   c.config.Scope,
+		visited: make(map[string]bool),
+	}
+	if len(r.scope.Hosts) == 0 {
+		r.scope.Hosts = []string{strings.ToLower(u.Hostname())}
+	}
+	r.scope.Exclude = append(append([]*regexp.Regexp(nil), r.scope.Exclude...), DefaultExclude)
+
+	frontier := []task{{url: u, source: SourceSeed}}
+	root := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}
+	if !c.config.SkipRobots {
+		frontier = append(frontier, r.loadRobots(ctx, root)...)
+	}
+	if !c.config.SkipSitemap {
+		frontier = append(frontier, r.loadSitemaps(ctx, root)...)
+	}
+
+	for len(frontier) > 0 {
+		if err := ctx.Err(); err != nil {
+			return r.pages, err
+		}
+		frontier = r.visitAll(ctx, frontier)
+	}
+
+	return r.pages, ctx.Err()
+}
+
+// visitAll 并发请求一层页面，返回下一层待请求的页面
+func (r *crawlRun) visitAll(ctx context.Context, tasks []task) []task {
+	var (
+		mu   sync.Mutex
+		next []task
+		wg   sync.WaitGroup
+	)
+	sem := make(chan struct{}, r.config.Concurrency)
+
+	for _, t := range tasks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t task) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			children := r.visit(ctx, t)
+			mu.Lock()
+			next = append(next, children...)
+			mu.Unlock()
+		}(t)
+	}
+	wg.Wait()
+
+	return next
+}
+
+// visit 请求页面，记录端点并返回页面中发现的下一层链接
+func (r *crawlRun) visit(ctx context.Context, t task) []task {
+	if !r.shouldVisit(t.url) || !r.reserve(t.url) {
+		return nil
+	}
+
+	resp, err := r.client.Get(ctx, t.url.String(), nil)
+	if err != nil {
+		return nil
+	}
+	// 脚本中的接口可能只接受其他方法，GET返回404时仍然记录
+	if resp.StatusCode == http.StatusNotFound {
+		if t.source == SourceScript && !isScript(t.url) {
+			r.record(t.url, t.source, t.referer, t.depth, resp.StatusCode, "")
+		}
+		return nil
+	}
+
+	contentType := resp.Headers.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(resp.Body)
+	}
+	page := t.url
+	if !isScript(t.url) {
+		r.record(t.url, t.source, t.referer, t.depth, resp.StatusCode, contentType)
+	}
+
+	if next := redirectTarget(t.url, resp); next != nil {
+		if t.redirects >= maxRedirects || !r.scope.Allows(next) {
+			return nil
+		}
+		return []task{{url: next, depth: t.depth, source: SourceRedirect, referer: t.url.String(), redirects: t.redirects + 1}}
+	}
+
+	var (
+		links []Link
+		forms []Form
+	)
+	switch {
+	case strings.Contains(contentType, "html"):
+		links, forms = ExtractHTML(page, resp.Body)
+	case strings.Contains(contentType, "javascript") || isScript(page):
+		links = ExtractScript(page, resp.Body)
+	default:
+		return nil
+	}
+
+	var children []task
+	for _, f := range forms {
+		if !r.scope.Allows(f.Action) {
+			continue
+		}
+		params := append(QueryParams(f.Action), f.Params...)
+		r.inv.Add(&Endpoint{
+			Method:  f.Method,
+			URL:     stripQuery(f.Action),
+			Params:  params,
+			Enctype: f.Enctype,
+			Source:  SourceForm,
+			Referer: page.String(),
+			Depth:   t.depth + 1,
+		})
+		if f.Method == http.MethodGet && t.depth+1 <= r.config.MaxDepth {
+			children = append(children, task{url: f.Action, depth: t.depth + 1, source: SourceForm, referer: page.String()})
+		}
+	}
+
+	for _, l := range links {
+		if !r.scope.Allows(l.URL) || isStatic(l.URL) {
+			continue
+		}
+		if t.depth+1 > r.config.MaxDepth {
+			// 超出深度的链接不再请求，但仍记录为端点
+			if !isScript(l.URL) {
+				r.record(l.URL, l.Source, page.String(), t.depth+1, 0, "")
+			}
+			continue
+		}
+		children = append(children, task{url: l.URL, depth: t.depth + 1, source: l.Source, referer: page.String()})
+	}
+
+	return children
+}
+
+// shouldVisit 判断页面是否需要请求
+func (r *crawlRun) shouldVisit(u *url.URL) bool {
+	if !r.scope.Allows(u) || isStatic(u) {
+		return false
+	}
+	if r.config.ObeyRobots && r.robots != nil && !r.robots.Allowed(u.RequestURI()) {
+		return false
+	}
+	return true
+}
+
+// reserve 标记页面已访问并占用一个请求名额，已访问或名额用完时返回false
+// 路径相同、查询参数名相同的URL视为同一页面
+func (r *crawlRun) reserve(u *url.URL) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := visitKey(u)
+	if r.visited[key] || r.pages >= r.config.MaxPages {
+		return false
+	}
+	r.visited[key] = true
+	r.pages++
+	return true
+}
+
+// record 将页面记录为GET端点
+func (r *crawlRun) record(u *url.URL, source, referer string, depth, status int, contentType string) {
+	r.inv.Add(&Endpoint{
+		Method:      http.MethodGet,
+		URL:         stripQuery(u),
+		Params:      QueryParams(u),
+		Source:      source,
+		Referer:     referer,
+		Depth:       depth,
+		StatusCode:  status,
+		ContentType: contentType,
+	})
+}
+
+// fetch 请求robots.txt或站点地图，计入请求名额，跟随范围内的重定向，非200响应返回nil
+func (r *crawlRun) fetch(ctx context.Context, u *url.URL) []byte {
+	for i := 0; i <= maxRedirects; i++ {
+		if !r.reserve(u) {
+			return nil
+		}
+		resp, err := r.client.Get(ctx, u.String(), nil)
+		if err != nil {
+			return nil
+		}
+		next := redirectTarget(u, resp)
+		if next == nil {
+			if resp.StatusCode != http.StatusOK {
+				return nil
+			}
+			return resp.Body
+		}
+		if !r.scope.Allows(next) {
+			return nil
+		}
+		u = next
+	}
+	return nil
+}
+
+// loadRobots 读取robots.txt，其中的路径作为待请求页面返回
+func (r *crawlRun) loadRobots(ctx context.Context, root *url.URL) []task {
+	robotsURL := root.ResolveReference(&url.URL{Path: "/robots.txt"})
+	data := r.fetch(ctx, robotsURL)
+	if data == nil {
+		return nil
+	}
+	r.robots = ParseRobots(data)
+
+	var tasks []task
+	for _, p := range r.robots.Paths {
+		u := root.ResolveReference(&url.URL{Path: p})
+		tasks = append(tasks, task{url: u, depth: 1, source: SourceRobots, referer: robotsURL.String()})
+	}
+	return tasks
+}
+
+// loadSitemaps 读取robots.txt声明的站点地图，未声明时尝试/sitemap.xml，展开嵌套的索引
+func (r *crawlRun) loadSitemaps(ctx context.Context, root *url.URL) []task {
+	var queue []string
+	if r.robots != nil {
+		queue = append(queue, r.robots.Sitemaps...)
+	}
+	if len(queue) == 0 {
+		queue = []string{root.ResolveReference(&url.URL{Path: "/sitemap.xml"}).String()}
+	}
+
+	var tasks []task
+	for n := 0; len(queue) > 0 && n < maxSitemaps; n++ {
+		loc := queue[0]
+		queue = queue[1:]
+
+		u, err := url.Parse(loc)
+		if err != nil || !r.scope.Allows(u) {
+			continue
+		}
+		data := r.fetch(ctx, u)
+		if data == nil {
+			continue
+		}
+		pages, nested, err := ParseSitemap(data)
+		if err != nil {
+			continue
+		}
+		queue = append(queue, nested...)
+
+		for _, p := range pages {
+			if pu, err := url.Parse(p); err == nil {
+				pu.Fragment = ""
+				tasks = append(tasks, task{url: pu, depth: 1, source: SourceSitemap, referer: loc})
+			}
+		}
+	}
+	return tasks
+}
+
+// redirectTarget 返回重定向响应的Location相对于请求URL解析后的地址，不是重定向时返回nil
+func redirectTarget(u *url.URL, resp *network.HTTPResponse) *url.URL {
+	if resp.StatusCode < 300 || resp.StatusCode > 399 {
+		return nil
+	}
+	loc := resp.Headers.Get("Location")
+	if loc == "" {
+		return nil
+	}
+	next, err := u.Parse(loc)
+	if err != nil {
+		return nil
+	}
+	next.Fragment = ""
+	return next
+}
+
+// stripQuery 返回不含查询字符串和片段的URL
+func stripQuery(u *url.URL) string {
+	cp := *u
+	cp.RawQuery = ""
+	cp.ForceQuery = false
+	cp.Fragment = ""
+	return cp.String()
+}
+
+// visitKey 返回判断页面是否重复的键，由不含查询的URL和排序后的查询参数名组成
+func visitKey(u *url.URL) string {
+	names := make([]string, 0)
+	for name := range u.Query() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return stripQuery(u) + "?" + strings.Join(names, "&")
+}
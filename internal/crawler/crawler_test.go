@@ -0,0 +1,254 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+// testSite 是爬虫测试使用的站点，记录每个路径被请求的次数
+type testSite struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+func (s *testSite) hit(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+// newTestSite 启动站点，other 是站点链接到的范围外的地址
+func newTestSite(t *testing.T, other string) *testSite {
+	t.Helper()
+
+	pages := map[string]string{
+		"/": `<html><body>
+			<a href="/a">A</a>
+			<a href="/logout">Log out</a>
+			<a href="` + other + `/external">External</a>
+			<img src="/logo.png"><a href="/logo.png">logo</a>
+			<form action="/search"><input name="q" value="shoes"></form>
+			<form method="post" action="/login"><input name="user"><input type="password" name="pass"></form>
+			<script src="/app.js"></script>
+			<script>fetch("/api/inline")</script>
+		</body></html>`,
+		"/a":          `<a href="/b?id=1">B</a>`,
+		"/redirects":  `<a href="/out">out</a> <a href="/in">in</a>`,
+		"/target":     `<p>target</p>`,
+		"/b":          `<a href="/c">C</a>`,
+		"/c":          `<a href="/d">D</a>`,
+		"/admin/":     `<p>admin</p>`,
+		"/from-map":   `<p>from sitemap</p>`,
+		"/search":     `<p>results</p>`,
+		"/api/inline": `<p>inline</p>`,
+	}
+
+	s := &testSite{hits: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		s.mu.Unlock()
+
+		switch path := r.URL.Path; {
+		case path == "/out":
+			http.Redirect(w, r, other+"/landing", http.StatusFound)
+			return
+		case path == "/in":
+			http.Redirect(w, r, "/target", http.StatusMovedPermanently)
+			return
+		case strings.HasPrefix(path, "/deep/"):
+			// 每层页面的GET表单都指向更深一层
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<form action="more/"><input name="q"></form>`))
+			return
+		case path == "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow: /admin/\nSitemap: http://%s/sitemap.xml\n", r.Host)
+			return
+		case path == "/sitemap.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprintf(w, `<urlset><url><loc>http://%s/from-map</loc></url></urlset>`, r.Host)
+			return
+		case path == "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Write([]byte(`const api = "/api/v1/items"; const page = "/a";`))
+			return
+		}
+
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func crawl(t *testing.T, seed string, config Config) *Inventory {
+	t.Helper()
+
+	httpConfig := network.DefaultHTTPClientConfig()
+	httpConfig.MaxRetries = 0
+	inv := NewInventory()
+	if _, err := New(network.NewHTTPClient(httpConfig), config).Crawl(context.Background(), seed, inv); err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	return inv
+}
+
+// endpoints 按 "方法 路径" 索引清单
+func endpoints(inv *Inventory, base string) map[string]*Endpoint {
+	m := make(map[string]*Endpoint)
+	for _, e := range inv.List() {
+		m[e.Method+" "+strings.TrimPrefix(e.URL, base)] = e
+	}
+	return m
+}
+
+func TestCrawl(t *testing.T) {
+	other := newTestSite(t, "")
+	// 同一IP的不同主机名，范围只包含种子URL的主机
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+	site := newTestSite(t, otherURL)
+
+	inv := crawl(t, site.URL+"/", Config{MaxDepth: 2})
+	got := endpoints(inv, site.URL)
+
+	tests := []struct {
+		key    string
+		source string
+		depth  int
+		status int
+	}{
+		{"GET /", SourceSeed, 0, 200},
+		{"GET /a", SourceLink, 1, 200},
+		{"GET /b", SourceLink, 2, 200},
+		// 超出深度的链接只记录，不请求
+		{"GET /c", SourceLink, 3, 0},
+		{"GET /search", SourceForm, 1, 200},
+		{"POST /login", SourceForm, 1, 0},
+		{"GET /api/inline", SourceScript, 1, 200},
+		// 脚本中的接口GET返回404时仍然记录
+		{"GET /api/v1/items", SourceScript, 2, 404},
+		{"GET /admin/", SourceRobots, 1, 200},
+		{"GET /from-map", SourceSitemap, 1, 200},
+	}
+	for _, tt := range tests {
+		e, ok := got[tt.key]
+		if !ok {
+			t.Errorf("%s missing from inventory", tt.key)
+			continue
+		}
+		if e.Source != tt.source || e.Depth != tt.depth || e.StatusCode != tt.status {
+			t.Errorf("%s = source %s depth %d status %d, want %s %d %d",
+				tt.key, e.Source, e.Depth, e.StatusCode, tt.source, tt.depth, tt.status)
+		}
+	}
+
+	if e := got["GET /b"]; e != nil && (len(e.Params) != 1 || e.Params[0].Name != "id") {
+		t.Errorf("/b params = %+v, want id", e.Params)
+	}
+	if e := got["POST /login"]; e != nil && (len(e.Params) != 2 || e.Params[1].Location != ParamBody || e.Params[1].Type != "password") {
+		t.Errorf("/login params = %+v", e.Params)
+	}
+
+	for _, key := range []string{"GET /logout", "GET /logo.png", "GET /app.js", "GET /d"} {
+		if _, ok := got[key]; ok {
+			t.Errorf("%s should not be in the inventory", key)
+		}
+	}
+	for _, e := range inv.List() {
+		if strings.Contains(e.URL, "localhost") {
+			t.Errorf("out-of-scope endpoint recorded: %s", e.URL)
+		}
+	}
+
+	for path, want := range map[string]int{"/logout": 0, "/c": 0, "/logo.png": 0, "/a": 1, "/app.js": 1} {
+		if n := site.hit(path); n != want {
+			t.Errorf("%s requested %d times, want %d", path, n, want)
+		}
+	}
+	if n := other.hit("/external"); n != 0 {
+		t.Errorf("out-of-scope host requested %d times", n)
+	}
+}
+
+func TestCrawlObeyRobots(t *testing.T) {
+	site := newTestSite(t, "http://localhost")
+
+	inv := crawl(t, site.URL+"/", Config{ObeyRobots: true, SkipSitemap: true})
+	if n := site.hit("/admin/"); n != 0 {
+		t.Errorf("disallowed path requested %d times", n)
+	}
+	if n := site.hit("/sitemap.xml"); n != 0 {
+		t.Errorf("sitemap requested %d times with SkipSitemap", n)
+	}
+	if _, ok := endpoints(inv, site.URL)["GET /from-map"]; ok {
+		t.Error("sitemap page crawled with SkipSitemap")
+	}
+}
+
+func TestCrawlMaxPages(t *testing.T) {
+	site := newTestSite(t, "http://localhost")
+
+	httpConfig := network.DefaultHTTPClientConfig()
+	httpConfig.MaxRetries = 0
+	pages, err := New(network.NewHTTPClient(httpConfig), Config{MaxPages: 3}).Crawl(context.Background(), site.URL+"/", NewInventory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 3 {
+		t.Errorf("pages = %d, want 3", pages)
+	}
+}
+
+func TestCrawlInvalidSeed(t *testing.T) {
+	for _, seed := range []string{"", "ftp://example.com/", "http://", "example.com"} {
+		if _, err := New(nil, Config{}).Crawl(context.Background(), seed, NewInventory()); err == nil {
+			t.Errorf("seed %q accepted", seed)
+		}
+	}
+}
+
+func TestCrawlFormDepthLimit(t *testing.T) {
+	site := newTestSite(t, "http://localhost")
+
+	crawl(t, site.URL+"/deep/", Config{MaxDepth: 2, SkipRobots: true, SkipSitemap: true})
+	for path, want := range map[string]int{"/deep/": 1, "/deep/more/": 1, "/deep/more/more/": 1, "/deep/more/more/more/": 0} {
+		if n := site.hit(path); n != want {
+			t.Errorf("%s requested %d times, want %d", path, n, want)
+		}
+	}
+}
+
+func TestCrawlRedirectScope(t *testing.T) {
+	other := newTestSite(t, "")
+	site := newTestSite(t, strings.Replace(other.URL, "127.0.0.1", "localhost", 1))
+
+	inv := crawl(t, site.URL+"/redirects", Config{SkipRobots: true, SkipSitemap: true})
+	got := endpoints(inv, site.URL)
+
+	if n := other.hit("/landing"); n != 0 {
+		t.Errorf("off-scope redirect target requested %d times", n)
+	}
+	if e := got["GET /out"]; e == nil || e.StatusCode != http.StatusFound {
+		t.Errorf("redirecting page = %+v, want recorded with 302", e)
+	}
+	if e := got["GET /target"]; e == nil || e.Source != SourceRedirect || e.StatusCode != http.StatusOK {
+		t.Errorf("in-scope redirect target = %+v", e)
+	}
+	if n := site.hit("/target"); n != 1 {
+		t.Errorf("/target requested %d times, want 1", n)
+	}
+}
@@ -0,0 +1,243 @@
+package crawler
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Link 是页面中发现的一个链接
+type Link struct {
+	URL *url.URL
+	// Source 是 SourceLink 或 SourceScript
+	Source string
+}
+
+// Form 是页面中的一个表单
+type Form struct {
+	Method  string
+	Action  *url.URL
+	Enctype string
+	Params  []Param
+}
+
+// linkAttrs 是包含链接的元素属性
+var linkAttrs = map[string]string{
+	"a":      "href",
+	"area":   "href",
+	"link":   "href",
+	"iframe": "src",
+	"frame":  "src",
+	"script": "src",
+	"embed":  "src",
+	"object": "data",
+}
+
+// jsURLPattern 匹配脚本中引号包围的URL和路径，参考LinkFinder的规则：
+// 完整URL、以/开头的路径，以及带有常见动态页面扩展名的相对路径
+var jsURLPattern = regexp.MustCompile("[\"'`]" +
+	`((?:https?:)?//[^"'` + "`" + `\s<>]{3,}` +
+	`|/[a-zA-Z0-9_\-.~%]+(?:/[a-zA-Z0-9_\-.~%{}$]*)*(?:\?[^"'` + "`" + `\s<>]*)?` +
+	`|[a-zA-Z0-9_\-]+(?:/[a-zA-Z0-9_\-.]+)*\.(?:php|asp|aspx|jsp|jspx|do|action|json|html?|cgi|pl)(?:\?[^"'` + "`" + `\s<>]*)?)` +
+	"[\"'`]")
+
+// ExtractHTML 从HTML页面中提取链接、表单和内联脚本中的URL
+// base 是页面的URL，页面中的 <base href> 会覆盖它
+func ExtractHTML(base *url.URL, body []byte) ([]Link, []Form) {
+	var (
+		links  []Link
+		forms  []Form
+		form   *Form
+		script bool
+		text   bytes.Buffer
+	)
+
+	resolve := func(ref string) *url.URL {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || strings.HasPrefix(ref, "#") {
+			return nil
+		}
+		u, err := base.Parse(ref)
+		if err != nil {
+			return nil
+		}
+		u.Fragment = ""
+		return u
+	}
+	addLink := func(ref, source string) {
+		if u := resolve(ref); u != nil {
+			links = append(links, Link{URL: u, Source: source})
+		}
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if form != nil {
+				forms = append(forms, *form)
+			}
+			return links, forms
+
+		case html.TextToken:
+			if script {
+				text.Write(z.Text())
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "form":
+				if form != nil {
+					forms = append(forms, *form)
+					form = nil
+				}
+			case "script":
+				if script {
+					links = append(links, ExtractScript(base, text.Bytes())...)
+					text.Reset()
+					script = false
+				}
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			attrs := make(map[string]string, len(tok.Attr))
+			for _, a := range tok.Attr {
+				attrs[a.Key] = a.Val
+			}
+
+			if attr, ok := linkAttrs[tok.Data]; ok {
+				if v := attrs[attr]; v != "" {
+					addLink(v, SourceLink)
+				}
+			}
+
+			switch tok.Data {
+			case "base":
+				if u := resolve(attrs["href"]); u != nil {
+					base = u
+				}
+			case "script":
+				if attrs["src"] == "" && tt == html.StartTagToken {
+					script = true
+				}
+			case "meta":
+				// <meta http-equiv="refresh" content="0; url=/next">
+				content := attrs["content"]
+				if i := strings.Index(strings.ToLower(content), "url="); i >= 0 && strings.EqualFold(attrs["http-equiv"], "refresh") {
+					addLink(strings.Trim(content[i+4:], `'" `), SourceLink)
+				}
+			case "form":
+				if form != nil {
+					forms = append(forms, *form)
+				}
+				form = newForm(base, attrs)
+			case "input", "button", "textarea", "select":
+				if form == nil {
+					continue
+				}
+				if action := attrs["formaction"]; action != "" {
+					addLink(action, SourceLink)
+				}
+				name := attrs["name"]
+				if name == "" {
+					continue
+				}
+				typ := attrs["type"]
+				if typ == "" {
+					typ = tok.Data
+					if tok.Data == "input" {
+						typ = "text"
+					}
+				}
+				if typ == "image" || typ == "reset" {
+					continue
+				}
+				form.Params = append(form.Params, Param{Name: name, Location: form.location(), Value: attrs["value"], Type: typ})
+			case "option":
+				// 下拉框取第一个选项的值作为示例值
+				if form != nil && len(form.Params) > 0 {
+					last := &form.Params[len(form.Params)-1]
+					if last.Type == "select" && last.Value == "" {
+						last.Value = attrs["value"]
+					}
+				}
+			}
+		}
+	}
+}
+
+// newForm 根据form元素的属性创建表单，未指定action时提交到当前页面
+func newForm(base *url.URL, attrs map[string]string) *Form {
+	f := &Form{
+		Method:  strings.ToUpper(strings.TrimSpace(attrs["method"])),
+		Enctype: attrs["enctype"],
+	}
+	if f.Method != "POST" {
+		f.Method = "GET"
+	}
+	if f.Method == "POST" && f.Enctype == "" {
+		f.Enctype = "application/x-www-form-urlencoded"
+	}
+
+	action, err := base.Parse(strings.TrimSpace(attrs["action"]))
+	if err != nil {
+		cp := *base
+		action = &cp
+	}
+	action.Fragment = ""
+	f.Action = action
+	return f
+}
+
+// location 返回表单字段所在的位置
+func (f *Form) location() ParamLocation {
+	if f.Method == "POST" {
+		return ParamBody
+	}
+	return ParamQuery
+}
+
+// ExtractScript 从JavaScript代码中提取URL和路径
+func ExtractScript(base *url.URL, code []byte) []Link {
+	var links []Link
+	for _, m := range jsURLPattern.FindAllSubmatch(code, -1) {
+		ref := string(m[1])
+		// 模板字符串中的插值之后的部分无法确定
+		if i := strings.Index(ref, "${"); i >= 0 {
+			ref = ref[:i]
+		}
+		if ref == "" || ref == "/" || ref == "//" || strings.HasPrefix(ref, "//") && !strings.Contains(ref[2:], ".") {
+			continue
+		}
+		u, err := base.Parse(ref)
+		if err != nil {
+			continue
+		}
+		u.Fragment = ""
+		links = append(links, Link{URL: u, Source: SourceScript})
+	}
+	return links
+}
+
+// QueryParams 按名称顺序返回URL查询字符串中的参数
+func QueryParams(u *url.URL) []Param {
+	query := u.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	params := make([]Param, 0, len(names))
+	for _, name := range names {
+		params = append(params, Param{Name: name, Location: ParamQuery, Value: query.Get(name)})
+	}
+	return params
+}
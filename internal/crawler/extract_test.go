@@ -0,0 +1,131 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func linkURLs(links []Link) string {
+	var list []string
+	for _, l := range links {
+		list = append(list, l.Source+" "+l.URL.String())
+	}
+	return strings.Join(list, "\n")
+}
+
+func TestExtractHTMLLinks(t *testing.T) {
+	base, _ := url.Parse("http://example.com/dir/page")
+	body := `<html><head>
+		<meta http-equiv="Refresh" content="0; URL='/next'">
+		<link rel="stylesheet" href="style.css">
+	</head><body>
+		<a href="rel">relative</a>
+		<a href="#top">fragment only</a>
+		<a href="/abs#section">absolute</a>
+		<iframe src="//cdn.example.net/frame"></iframe>
+		<script src="/js/app.js"></script>
+		<script>var u = '/api/data?x=1'; load("report.php");</script>
+		<base href="http://example.com/other/">
+		<a href="after-base">after base</a>
+	</body></html>`
+
+	links, _ := ExtractHTML(base, []byte(body))
+	want := strings.Join([]string{
+		"link http://example.com/next",
+		"link http://example.com/dir/style.css",
+		"link http://example.com/dir/rel",
+		"link http://example.com/abs",
+		"link http://cdn.example.net/frame",
+		"link http://example.com/js/app.js",
+		"script http://example.com/api/data?x=1",
+		"script http://example.com/dir/report.php",
+		"link http://example.com/other/after-base",
+	}, "\n")
+	if got := linkURLs(links); got != want {
+		t.Errorf("links:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestExtractHTMLForms(t *testing.T) {
+	base, _ := url.Parse("http://example.com/account/")
+	body := `
+		<form action="/search?lang=en">
+			<input name="q" value="test">
+			<input type="submit" name="go" value="Go">
+			<input type="image" name="img">
+		</form>
+		<form method="POST" enctype="multipart/form-data">
+			<input type="hidden" name="csrf" value="abc">
+			<textarea name="bio"></textarea>
+			<select name="role"><option value="user">User</option><option value="admin">Admin</option></select>
+			<button formaction="/account/delete" name="del">Delete</button>
+		</form>
+		<form method="post" action="login">
+			<input name="user"><input type="password" name="pass">`
+
+	links, forms := ExtractHTML(base, []byte(body))
+	if len(forms) != 3 {
+		t.Fatalf("forms = %d, want 3", len(forms))
+	}
+
+	search := forms[0]
+	if search.Method != "GET" || search.Action.String() != "http://example.com/search?lang=en" {
+		t.Errorf("search form = %s %s", search.Method, search.Action)
+	}
+	if len(search.Params) != 2 || search.Params[0].Value != "test" || search.Params[0].Location != ParamQuery {
+		t.Errorf("search params = %+v", search.Params)
+	}
+
+	profile := forms[1]
+	if profile.Method != "POST" || profile.Enctype != "multipart/form-data" || profile.Action.String() != base.String() {
+		t.Errorf("profile form = %s %s %s", profile.Method, profile.Action, profile.Enctype)
+	}
+	var names []string
+	for _, p := range profile.Params {
+		names = append(names, p.Name+"="+p.Value+":"+p.Type)
+	}
+	if got := strings.Join(names, ","); got != "csrf=abc:hidden,bio=:textarea,role=user:select,del=:button" {
+		t.Errorf("profile params = %s", got)
+	}
+	if got := linkURLs(links); got != "link http://example.com/account/delete" {
+		t.Errorf("formaction links = %s", got)
+	}
+
+	// 未闭合的表单在文档结束时仍然返回
+	login := forms[2]
+	if login.Enctype != "application/x-www-form-urlencoded" || login.Action.String() != "http://example.com/account/login" {
+		t.Errorf("login form = %s %s", login.Action, login.Enctype)
+	}
+	if len(login.Params) != 2 || login.Params[1].Location != ParamBody {
+		t.Errorf("login params = %+v", login.Params)
+	}
+}
+
+func TestExtractScript(t *testing.T) {
+	base, _ := url.Parse("https://example.com/static/app.js")
+	code := []byte("fetch('/api/v1/users');\n" +
+		"const full = \"https://api.example.com/v2/items\";\n" +
+		"axios.get(`/api/orders/${id}/detail`);\n" +
+		"const page = 'admin/settings.php?tab=1';\n" +
+		"const slash = '/'; const proto = '//'; const bare = 'just text';\n" +
+		"const noDot = '//localhost';\n")
+
+	want := strings.Join([]string{
+		"script https://example.com/api/v1/users",
+		"script https://api.example.com/v2/items",
+		"script https://example.com/api/orders/",
+		"script https://example.com/static/admin/settings.php?tab=1",
+	}, "\n")
+	if got := linkURLs(ExtractScript(base, code)); got != want {
+		t.Errorf("links:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestQueryParams(t *testing.T) {
+	u, _ := url.Parse("http://example.com/?b=2&a=1&a=3")
+	params := QueryParams(u)
+	if len(params) != 2 || params[0].Name != "a" || params[0].Value != "1" || params[1].Name != "b" {
+		t.Errorf("params = %+v", params)
+	}
+}
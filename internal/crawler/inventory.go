@@ -0,0 +1,198 @@
+package crawler
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ParamLocation 是参数所在的位置
+type ParamLocation string
+
+const (
+	// ParamQuery 是URL查询字符串中的参数
+	ParamQuery ParamLocation = "query"
+	// ParamBody 是表单请求体中的参数
+	ParamBody ParamLocation = "body"
+)
+
+// 端点的发现来源
+const (
+	SourceSeed     = "seed"
+	SourceLink     = "link"
+	SourceForm     = "form"
+	SourceScript   = "script"
+	SourceRobots   = "robots"
+	SourceSitemap  = "sitemap"
+	SourceRedirect = "redirect"
+)
+
+// Param 是端点接受的一个参数
+type Param struct {
+	Name     string        `json:"name"`
+	Location ParamLocation `json:"location"`
+	// Value 是页面中出现的示例值，如表单的默认值或链接中的取值
+	Value string `json:"value,omitempty"`
+	// Type 是表单控件的类型，如 text、hidden、password
+	Type string `json:"type,omitempty"`
+}
+
+// Endpoint 是爬虫发现的一个端点，同一方法和URL（不含查询字符串）只记录一次，参数合并
+type Endpoint struct {
+	ID     int    `json:"id"`
+	Method string `json:"method"`
+	// URL 不包含查询字符串和片段，查询参数记录在Params中
+	URL    string  `json:"url"`
+	Params []Param `json:"params,omitempty"`
+	// Enctype 是表单的编码类型，非表单端点为空
+	Enctype string `json:"enctype,omitempty"`
+	// Source 是首次发现该端点的来源，取值见Source常量
+	Source string `json:"source"`
+	// Referer 是发现该端点的页面
+	Referer string `json:"referer,omitempty"`
+	Depth   int    `json:"depth"`
+	// StatusCode 和 ContentType 来自爬取时的响应，未请求过的端点（如表单）为空
+	StatusCode  int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// Target 返回带有查询参数示例值的URL，可直接作为插件的目标
+func (e *Endpoint) Target() string {
+	query := url.Values{}
+	for _, p := range e.Params {
+		if p.Location == ParamQuery {
+			query.Add(p.Name, p.Value)
+		}
+	}
+	if len(query) == 0 {
+		return e.URL
+	}
+	return e.URL + "?" + query.Encode()
+}
+
+// Host 返回端点的主机名
+func (e *Endpoint) Host() string {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// addParams 合并参数，名称和位置相同的参数只保留一个，保留已有的示例值
+func (e *Endpoint) addParams(params []Param) {
+	for _, p := range params {
+		found := false
+		for i := range e.Params {
+			if e.Params[i].Name == p.Name && e.Params[i].Location == p.Location {
+				if e.Params[i].Value == "" {
+					e.Params[i].Value = p.Value
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			e.Params = append(e.Params, p)
+		}
+	}
+}
+
+// Inventory 是端点清单，可在多次爬取之间累积，并发安全
+type Inventory struct {
+	mu        sync.Mutex
+	endpoints []*Endpoint
+	index     map[string]*Endpoint
+}
+
+// NewInventory 创建一个空的端点清单
+func NewInventory() *Inventory {
+	return &Inventory{index: make(map[string]*Endpoint)}
+}
+
+// Add 添加端点，已存在时合并参数并补全响应信息，返回是否为新端点
+func (inv *Inventory) Add(e *Endpoint) bool {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	key := strings.ToUpper(e.Method) + " " + e.URL
+	if old, ok := inv.index[key]; ok {
+		old.addParams(e.Params)
+		if old.StatusCode == 0 {
+			old.StatusCode, old.ContentType = e.StatusCode, e.ContentType
+		}
+		return false
+	}
+
+	cp := *e
+	cp.Method = strings.ToUpper(cp.Method)
+	cp.Params = append([]Param(nil), e.Params...)
+	cp.ID = len(inv.endpoints) + 1
+	inv.endpoints = append(inv.endpoints, &cp)
+	inv.index[key] = &cp
+	return true
+}
+
+// Get 根据ID获取端点
+func (inv *Inventory) Get(id int) (*Endpoint, bool) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if id < 1 || id > len(inv.endpoints) {
+		return nil, false
+	}
+	return inv.endpoints[id-1], true
+}
+
+// List 按发现顺序返回所有端点
+func (inv *Inventory) List() []*Endpoint {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	list := make([]*Endpoint, len(inv.endpoints))
+	copy(list, inv.endpoints)
+	return list
+}
+
+// ForHost 返回属于指定主机的端点，host 可以带端口或是完整URL
+func (inv *Inventory) ForHost(host string) []*Endpoint {
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Hostname()
+	} else if h, _, ok := strings.Cut(host, ":"); ok {
+		host = h
+	}
+
+	var list []*Endpoint
+	for _, e := range inv.List() {
+		if strings.EqualFold(e.Host(), host) {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// Len 返回端点数量
+func (inv *Inventory) Len() int {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	return len(inv.endpoints)
+}
+
+// Clear 清空清单
+func (inv *Inventory) Clear() {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	inv.endpoints = nil
+	inv.index = make(map[string]*Endpoint)
+}
+
+// WriteJSON 将清单以JSON数组写入w
+func (inv *Inventory) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(inv.List())
+}
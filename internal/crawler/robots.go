@@ -0,0 +1,168 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Robots 是解析后的robots.txt
+type Robots struct {
+	// Paths 是所有规则组中出现的路径，通配符之后的部分被去掉，常暴露后台和接口
+	Paths []string
+	// Sitemaps 是声明的站点地图
+	Sitemaps []string
+
+	rules []robotsRule
+}
+
+// robotsRule 是适用于所有爬虫（User-agent: *）的一条规则
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// ParseRobots 解析robots.txt
+func ParseRobots(data []byte) *Robots {
+	r := &Robots{}
+	seen := make(map[string]bool)
+
+	var agents []string
+	inRules := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// 规则之后出现的User-agent开始新的规则组
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, value)
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			if p := robotsPath(value); p != "" && !seen[p] {
+				seen[p] = true
+				r.Paths = append(r.Paths, p)
+			}
+			for _, agent := range agents {
+				if agent == "*" {
+					r.rules = append(r.rules, robotsRule{allow: key == "allow", pattern: value, re: robotsPattern(value)})
+					break
+				}
+			}
+		case "sitemap":
+			if value != "" {
+				r.Sitemaps = append(r.Sitemaps, value)
+			}
+		}
+	}
+
+	return r
+}
+
+// Allowed 判断路径是否允许访问，匹配最长的规则，长度相同时Allow优先
+func (r *Robots) Allowed(path string) bool {
+	best, allowed := -1, true
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			best, allowed = n, rule.allow
+		}
+	}
+	return allowed
+}
+
+// robotsPath 去掉规则中的通配符部分，返回可直接访问的路径
+func robotsPath(pattern string) string {
+	if i := strings.IndexAny(pattern, "*$"); i >= 0 {
+		pattern = pattern[:i]
+	}
+	if !strings.HasPrefix(pattern, "/") || pattern == "/" {
+		return ""
+	}
+	return pattern
+}
+
+// robotsPattern 将规则转换为正则表达式，* 匹配任意字符，结尾的 $ 表示路径结束
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// sitemapXML 同时描述 urlset 和 sitemapindex
+type sitemapXML struct {
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// ParseSitemap 解析站点地图，返回其中的页面和嵌套的站点地图，支持gzip压缩和纯文本格式
+func ParseSitemap(data []byte) (urls, sitemaps []string, err error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(trimmed, []byte("<")) {
+		// 纯文本站点地图每行一个URL
+		for _, line := range strings.Split(string(trimmed), "\n") {
+			if line = strings.TrimSpace(line); strings.HasPrefix(line, "http") {
+				urls = append(urls, line)
+			}
+		}
+		return urls, nil, nil
+	}
+
+	var doc sitemapXML
+	if err := xml.Unmarshal(trimmed, &doc); err != nil {
+		return nil, nil, err
+	}
+	for _, u := range doc.URLs {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			urls = append(urls, loc)
+		}
+	}
+	for _, s := range doc.Sitemaps {
+		if loc := strings.TrimSpace(s.Loc); loc != "" {
+			sitemaps = append(sitemaps, loc)
+		}
+	}
+	return urls, sitemaps, nil
+}
@@ -0,0 +1,96 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func TestParseRobots(t *testing.T) {
+	r := ParseRobots([]byte(`# comment
+User-agent: Googlebot
+Disallow: /private-google/
+
+User-agent: *
+Disallow: /admin/
+Disallow: /*.bak$
+Allow: /admin/public
+Disallow: /tmp/*/cache   # trailing comment
+Disallow:
+
+Sitemap: https://example.com/sitemap.xml
+Sitemap: https://example.com/news.xml
+`))
+
+	if got := strings.Join(r.Paths, ","); got != "/private-google/,/admin/,/admin/public,/tmp/" {
+		t.Errorf("paths = %s", got)
+	}
+	if len(r.Sitemaps) != 2 || r.Sitemaps[1] != "https://example.com/news.xml" {
+		t.Errorf("sitemaps = %q", r.Sitemaps)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"/private-google/x", true},
+		{"/admin/", false},
+		{"/admin/users", false},
+		{"/admin/public/page", true},
+		{"/db.bak", false},
+		{"/db.bak.txt", true},
+		{"/tmp/a/cache/x", false},
+		{"/tmp/a/other", true},
+	}
+	for _, tt := range tests {
+		if got := r.Allowed(tt.path); got != tt.want {
+			t.Errorf("Allowed(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseSitemap(t *testing.T) {
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc> https://example.com/a </loc></url>
+  <url><loc>https://example.com/b</loc></url>
+</urlset>`
+	index := `<sitemapindex><sitemap><loc>https://example.com/s1.xml</loc></sitemap></sitemapindex>`
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(urlset))
+	zw.Close()
+
+	tests := []struct {
+		name     string
+		data     []byte
+		urls     string
+		sitemaps string
+	}{
+		{"urlset", []byte(urlset), "https://example.com/a,https://example.com/b", ""},
+		{"index", []byte(index), "", "https://example.com/s1.xml"},
+		{"gzip", gz.Bytes(), "https://example.com/a,https://example.com/b", ""},
+		{"text", []byte("https://example.com/x\n\n  http://example.com/y\nnot a url\n"), "https://example.com/x,http://example.com/y", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, sitemaps, err := ParseSitemap(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(urls, ","); got != tt.urls {
+				t.Errorf("urls = %s, want %s", got, tt.urls)
+			}
+			if got := strings.Join(sitemaps, ","); got != tt.sitemaps {
+				t.Errorf("sitemaps = %s, want %s", got, tt.sitemaps)
+			}
+		})
+	}
+
+	if _, _, err := ParseSitemap([]byte("<urlset><url>")); err == nil {
+		t.Error("malformed XML accepted")
+	}
+}
@@ -0,0 +1,114 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DefaultExclude 是默认不访问的URL，避免爬虫注销当前会话
+var DefaultExclude = regexp.MustCompile(`(?i)log-?out|sign-?out|log-?off`)
+
+// staticExtensions 是不需要请求的静态资源扩展名
+var staticExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".ico": true, ".svg": true, ".webp": true,
+	".css": true, ".woff": true, ".woff2": true, ".ttf": true, ".eot": true, ".otf": true,
+	".mp3": true, ".mp4": true, ".avi": true, ".mov": true, ".webm": true, ".flv": true,
+	".pdf": true, ".zip": true, ".gz": true, ".tar": true, ".rar": true, ".7z": true, ".exe": true, ".dmg": true, ".iso": true,
+}
+
+// Scope 决定哪些URL属于爬取范围
+type Scope struct {
+	// Hosts 是允许的主机，以点开头时匹配所有子域名，为空时只允许种子URL的主机
+	Hosts []string
+	// Include 不为空时URL必须匹配其中一个表达式
+	Include []*regexp.Regexp
+	// Exclude 匹配任意一个表达式的URL不在范围内
+	Exclude []*regexp.Regexp
+}
+
+// ParseScopeHosts 解析逗号分隔的主机列表
+func ParseScopeHosts(value string) ([]string, error) {
+	var hosts []string
+	for _, h := range strings.Split(value, ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			continue
+		}
+		if strings.ContainsAny(h, "/:") {
+			return nil, fmt.Errorf("无效的主机: %s (只需主机名，如 example.com 或 .example.com)", h)
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
+
+// ParsePatterns 编译逗号分隔的正则表达式列表
+func ParsePatterns(value string) ([]*regexp.Regexp, error) {
+	var list []*regexp.Regexp
+	for _, p := range strings.Split(value, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则表达式 %q: %w", p, err)
+		}
+		list = append(list, re)
+	}
+	return list, nil
+}
+
+// Allows 判断URL是否在范围内，只接受http和https
+func (s *Scope) Allows(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	if !s.allowsHost(u.Hostname()) {
+		return false
+	}
+
+	raw := u.String()
+	for _, re := range s.Exclude {
+		if re.MatchString(raw) {
+			return false
+		}
+	}
+	if len(s.Include) == 0 {
+		return true
+	}
+	for _, re := range s.Include {
+		if re.MatchString(raw) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHost 判断主机是否在允许列表中，精确匹配或以点开头的子域名匹配
+func (s *Scope) allowsHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range s.Hosts {
+		if pattern == host {
+			return true
+		}
+		if strings.HasPrefix(pattern, ".") && (host == pattern[1:] || strings.HasSuffix(host, pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStatic 判断URL是否指向静态资源
+func isStatic(u *url.URL) bool {
+	return staticExtensions[strings.ToLower(path.Ext(u.Path))]
+}
+
+// isScript 判断URL是否指向JavaScript文件
+func isScript(u *url.URL) bool {
+	ext := strings.ToLower(path.Ext(u.Path))
+	return ext == ".js" || ext == ".mjs"
+}
@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+func TestScopeAllows(t *testing.T) {
+	scope := Scope{
+		Hosts:   []string{"example.com", ".api.example.org"},
+		Include: []*regexp.Regexp{regexp.MustCompile(`/app/`)},
+		Exclude: []*regexp.Regexp{DefaultExclude, regexp.MustCompile(`\.bak$`)},
+	}
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"http://example.com/app/index", true},
+		{"https://EXAMPLE.com:8443/app/x", true},
+		{"http://www.example.com/app/x", false},
+		{"http://api.example.org/app/x", true},
+		{"http://v1.api.example.org/app/x", true},
+		{"http://evilapi.example.org/app/x", false},
+		{"http://example.com/other", false},
+		{"http://example.com/app/Logout", false},
+		{"http://example.com/app/sign-out?next=/", false},
+		{"http://example.com/app/db.bak", false},
+		{"ftp://example.com/app/x", false},
+		{"javascript:alert(1)", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := scope.Allows(u); got != tt.want {
+			t.Errorf("Allows(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParseScopeHosts(t *testing.T) {
+	hosts, err := ParseScopeHosts(" Example.com, ,.api.example.com ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 2 || hosts[0] != "example.com" || hosts[1] != ".api.example.com" {
+		t.Errorf("hosts = %q", hosts)
+	}
+
+	for _, value := range []string{"http://example.com", "example.com:80", "example.com/path"} {
+		if _, err := ParseScopeHosts(value); err == nil {
+			t.Errorf("ParseScopeHosts(%q) accepted", value)
+		}
+	}
+}
+
+func TestParsePatterns(t *testing.T) {
+	list, err := ParsePatterns(`/admin, \.php$`)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("ParsePatterns = %v, %v", list, err)
+	}
+	if _, err := ParsePatterns(`(unclosed`); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
@@ -0,0 +1,602 @@
+package network
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/seaung/Luna/pkg/helper"
+)
+
+// maxAuthRounds 限制一次请求应答认证质询的次数
+const maxAuthRounds = 2
+
+// Authenticator 为发往某个主机的请求提供认证
+type Authenticator interface {
+	// Apply 在首次发送请求前设置认证信息，不覆盖请求中已设置的值
+	Apply(req *http.Request) error
+	// Challenge 根据401响应的质询为重发的请求设置认证信息
+	// retry 是上一次发送的请求的副本，返回false表示无法应答该质询
+	Challenge(retry *http.Request, resp *http.Response) (bool, error)
+}
+
+// BasicAuth 是HTTP Basic认证，每个请求都预先携带凭据
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Apply 实现Authenticator接口
+func (a BasicAuth) Apply(req *http.Request) error {
+	if req.Header.Get("Authorization") == "" {
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+	return nil
+}
+
+// Challenge 实现Authenticator接口，凭据已随请求发送，收到质询说明凭据无效
+func (a BasicAuth) Challenge(retry *http.Request, resp *http.Response) (bool, error) {
+	return false, nil
+}
+
+// BearerAuth 在Authorization请求头中携带Bearer令牌
+type BearerAuth struct {
+	Token string
+}
+
+// Apply 实现Authenticator接口
+func (a BearerAuth) Apply(req *http.Request) error {
+	if req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+	return nil
+}
+
+// Challenge 实现Authenticator接口
+func (a BearerAuth) Challenge(retry *http.Request, resp *http.Response) (bool, error) {
+	return false, nil
+}
+
+// HeaderAuth 在指定请求头中携带API Key等固定凭据
+type HeaderAuth struct {
+	Name  string
+	Value string
+}
+
+// Apply 实现Authenticator接口
+func (a HeaderAuth) Apply(req *http.Request) error {
+	if req.Header.Get(a.Name) == "" {
+		req.Header.Set(a.Name, a.Value)
+	}
+	return nil
+}
+
+// Challenge 实现Authenticator接口
+func (a HeaderAuth) Challenge(retry *http.Request, resp *http.Response) (bool, error) {
+	return false, nil
+}
+
+// DigestAuth 是HTTP Digest认证，支持 MD5、SHA-256 及其 -sess 变体
+// 收到质询后缓存服务器的nonce，之后的请求预先携带认证信息
+type DigestAuth struct {
+	Username string
+	Password string
+
+	mu        sync.Mutex
+	challenge *digestChallenge
+	nc        uint32
+}
+
+// NewDigestAuth 创建Digest认证
+func NewDigestAuth(username, password string) *DigestAuth {
+	return &DigestAuth{Username: username, Password: password}
+}
+
+// digestChallenge 是服务器给出的Digest质询参数
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+}
+
+// Apply 实现Authenticator接口，已缓存质询时使用递增的nonce计数预先认证
+func (a *DigestAuth) Apply(req *http.Request) error {
+	if req.Header.Get("Authorization") != "" {
+		return nil
+	}
+
+	a.mu.Lock()
+	c := a.challenge
+	if c == nil {
+		a.mu.Unlock()
+		return nil
+	}
+	a.nc++
+	nc := a.nc
+	a.mu.Unlock()
+
+	return a.authorize(req, c, nc)
+}
+
+// Challenge 实现Authenticator接口
+func (a *DigestAuth) Challenge(retry *http.Request, resp *http.Response) (bool, error) {
+	c := selectDigestChallenge(parseChallenges(resp.Header.Values("WWW-Authenticate")))
+	if c == nil {
+		return false, nil
+	}
+
+	// 已用同一nonce认证仍被拒绝说明凭据无效，只有nonce过期时才重新应答
+	if prev := parseChallenges([]string{retry.Header.Get("Authorization")}); len(prev) > 0 &&
+		strings.EqualFold(prev[0].Scheme, "Digest") &&
+		prev[0].Params["nonce"] == c.nonce && !strings.EqualFold(c.stale, "true") {
+		return false, nil
+	}
+
+	a.mu.Lock()
+	a.challenge = &c.digestChallenge
+	a.nc = 1
+	a.mu.Unlock()
+
+	return true, a.authorize(retry, &c.digestChallenge, 1)
+}
+
+// authorize 计算Digest应答并设置Authorization请求头
+func (a *DigestAuth) authorize(req *http.Request, c *digestChallenge, nc uint32) error {
+	algorithm := strings.ToUpper(c.algorithm)
+	newHash := md5.New
+	if strings.HasPrefix(algorithm, "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		return hashHex(newHash(), []byte(s))
+	}
+
+	cnonce := helper.RandomString(16)
+	ncValue := fmt.Sprintf("%08x", nc)
+	uri := req.URL.RequestURI()
+
+	ha1 := h(a.Username + ":" + c.realm + ":" + a.Password)
+	if strings.HasSuffix(algorithm, "-SESS") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+
+	ha2 := h(req.Method + ":" + uri)
+	if c.qop == "auth-int" {
+		body, err := requestBody(req)
+		if err != nil {
+			return err
+		}
+		ha2 = h(req.Method + ":" + uri + ":" + hashHex(newHash(), body))
+	}
+
+	var response string
+	if c.qop == "" {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + ncValue + ":" + cnonce + ":" + c.qop + ":" + ha2)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
+		quoteEscape(a.Username), quoteEscape(c.realm), quoteEscape(c.nonce), quoteEscape(uri), response)
+	if c.algorithm != "" {
+		fmt.Fprintf(&b, ", algorithm=%s", c.algorithm)
+	}
+	if c.opaque != "" {
+		fmt.Fprintf(&b, `, opaque="%s"`, quoteEscape(c.opaque))
+	}
+	if c.qop != "" {
+		fmt.Fprintf(&b, `, qop=%s, nc=%s, cnonce="%s"`, c.qop, ncValue, cnonce)
+	}
+
+	req.Header.Set("Authorization", b.String())
+	return nil
+}
+
+// selectedDigest 是选中的Digest质询及其stale标记
+type selectedDigest struct {
+	digestChallenge
+	stale string
+}
+
+// selectDigestChallenge 从质询中选出支持的Digest质询，SHA-256优先于MD5
+func selectDigestChallenge(challenges []authChallenge) *selectedDigest {
+	var best *selectedDigest
+	bestRank := 0
+
+	for _, ch := range challenges {
+		if !strings.EqualFold(ch.Scheme, "Digest") {
+			continue
+		}
+
+		algorithm := ch.Params["algorithm"]
+		rank := 0
+		switch strings.ToUpper(algorithm) {
+		case "", "MD5", "MD5-SESS":
+			rank = 1
+		case "SHA-256", "SHA-256-SESS":
+			rank = 2
+		}
+		if rank <= bestRank {
+			continue
+		}
+
+		// 优先使用qop=auth，只支持auth-int时对请求体计算摘要
+		qop := ""
+		if offered := ch.Params["qop"]; offered != "" {
+			for _, q := range strings.Split(offered, ",") {
+				switch q = strings.TrimSpace(q); q {
+				case "auth":
+					qop = q
+				case "auth-int":
+					if qop == "" {
+						qop = q
+					}
+				}
+			}
+			if qop == "" {
+				continue
+			}
+		}
+
+		best = &selectedDigest{
+			digestChallenge: digestChallenge{
+				realm:     ch.Params["realm"],
+				nonce:     ch.Params["nonce"],
+				opaque:    ch.Params["opaque"],
+				algorithm: algorithm,
+				qop:       qop,
+			},
+			stale: ch.Params["stale"],
+		}
+		bestRank = rank
+	}
+
+	return best
+}
+
+// hashHex 计算数据的摘要并返回十六进制字符串
+func hashHex(h hash.Hash, data []byte) string {
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// quoteEscape 转义引号字符串中的反斜杠和双引号
+func quoteEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// requestBody 通过GetBody读取请求体，不消费原始请求体
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("请求体无法重读")
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// authChallenge 是WWW-Authenticate中的一个质询
+type authChallenge struct {
+	Scheme string
+	// Token 是方案后的token68数据，如NTLM的质询消息
+	Token  string
+	Params map[string]string
+}
+
+// parseChallenges 解析WWW-Authenticate（或Authorization）头中的质询
+// 一个头可以包含多个以逗号分隔的质询，参数值可以是带引号的字符串
+func parseChallenges(values []string) []authChallenge {
+	var list []authChallenge
+
+	for _, v := range values {
+		p := &authParser{s: v}
+		for {
+			p.skip(" \t,")
+			if p.done() {
+				break
+			}
+
+			name := p.token()
+			if name == "" {
+				p.i++
+				continue
+			}
+
+			p.skip(" \t")
+			if p.peek() == '=' {
+				p.i++
+				p.skip(" \t")
+				value := p.value()
+				if len(list) > 0 {
+					list[len(list)-1].Params[strings.ToLower(name)] = value
+				}
+				continue
+			}
+
+			list = append(list, authChallenge{
+				Scheme: name,
+				Token:  p.token68(),
+				Params: make(map[string]string),
+			})
+		}
+	}
+
+	return list
+}
+
+// authParser 是认证头的简单词法分析器
+type authParser struct {
+	s string
+	i int
+}
+
+func (p *authParser) done() bool { return p.i >= len(p.s) }
+
+func (p *authParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+func (p *authParser) skip(chars string) {
+	for !p.done() && strings.IndexByte(chars, p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+// token 读取到空白、等号、逗号或引号为止
+func (p *authParser) token() string {
+	start := p.i
+	for !p.done() && strings.IndexByte(" \t=,\"", p.s[p.i]) < 0 {
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+// value 读取参数值，支持带反斜杠转义的引号字符串
+func (p *authParser) value() string {
+	if p.peek() != '"' {
+		start := p.i
+		for !p.done() && strings.IndexByte(" \t,", p.s[p.i]) < 0 {
+			p.i++
+		}
+		return p.s[start:p.i]
+	}
+
+	p.i++
+	var b strings.Builder
+	for !p.done() {
+		c := p.s[p.i]
+		p.i++
+		switch {
+		case c == '\\' && !p.done():
+			b.WriteByte(p.s[p.i])
+			p.i++
+		case c == '"':
+			return b.String()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// token68 读取方案后的token68数据，后面不是逗号或结尾时视为参数并回退
+func (p *authParser) token68() string {
+	start := p.i
+	for !p.done() && isToken68Char(p.s[p.i]) {
+		p.i++
+	}
+	for !p.done() && p.s[p.i] == '=' {
+		p.i++
+	}
+	end := p.i
+
+	p.skip(" \t")
+	if end > start && (p.done() || p.peek() == ',') {
+		return p.s[start:end]
+	}
+
+	p.i = start
+	return ""
+}
+
+func isToken68Char(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("-._~+/", c) >= 0
+}
+
+// decodeAuthToken 解码方案后的base64数据
+func decodeAuthToken(token string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(token)
+}
+
+// AuthEntry 是绑定到主机模式的认证方式
+type AuthEntry struct {
+	// Host 是主机模式，以点开头时匹配所有子域名
+	Host string
+	Auth Authenticator
+}
+
+// AuthStore 按主机管理认证方式，可在多个客户端之间共享
+type AuthStore struct {
+	mu      sync.RWMutex
+	entries []AuthEntry
+}
+
+// NewAuthStore 创建一个新的认证存储
+func NewAuthStore() *AuthStore {
+	return &AuthStore{}
+}
+
+// Set 为主机设置认证方式，替换同一主机已有的设置
+func (st *AuthStore) Set(host string, auth Authenticator) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for i, e := range st.entries {
+		if strings.EqualFold(e.Host, host) {
+			st.entries[i].Auth = auth
+			return
+		}
+	}
+	st.entries = append(st.entries, AuthEntry{Host: host, Auth: auth})
+}
+
+// Remove 删除主机的认证方式
+func (st *AuthStore) Remove(host string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for i, e := range st.entries {
+		if strings.EqualFold(e.Host, host) {
+			st.entries = append(st.entries[:i], st.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// List 返回所有认证设置
+func (st *AuthStore) List() []AuthEntry {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	list := make([]AuthEntry, len(st.entries))
+	copy(list, st.entries)
+	return list
+}
+
+// Match 查找适用于主机的认证方式，精确匹配优先于子域名匹配，多个子域名规则匹配时最长的优先
+func (st *AuthStore) Match(host string) Authenticator {
+	if st == nil {
+		return nil
+	}
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var (
+		best    Authenticator
+		bestLen int
+	)
+	for _, e := range st.entries {
+		if !matchHostPattern(e.Host, host) {
+			continue
+		}
+		if !strings.HasPrefix(e.Host, ".") {
+			return e.Auth
+		}
+		if len(e.Host) > bestLen {
+			best, bestLen = e.Auth, len(e.Host)
+		}
+	}
+	return best
+}
+
+// authTransport 为请求添加认证信息，并在收到401质询时应答后重发
+// 位于限速和流量记录之外，质询产生的每次请求都会被限速和记录
+type authTransport struct {
+	next  http.RoundTripper
+	store *AuthStore
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	auth := t.store.Match(req.URL.Hostname())
+	if auth == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	orig := req
+	ctx := req.Context()
+	// 连接级认证的握手固定在一条专用连接上，响应体关闭后释放
+	var pin *connPin
+	if pinsConnection(auth) {
+		pin = &connPin{}
+		ctx = withConnPin(ctx, pin)
+	}
+	req = req.Clone(ctx)
+	if err := auth.Apply(req); err != nil {
+		return nil, fmt.Errorf("认证失败: %w", err)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	for round := 0; err == nil && resp.StatusCode == http.StatusUnauthorized && round < maxAuthRounds; round++ {
+		// 请求体无法重读时不能重发
+		if orig.GetBody == nil && orig.Body != nil && orig.Body != http.NoBody {
+			break
+		}
+
+		retry := req.Clone(req.Context())
+		ok, cerr := auth.Challenge(retry, resp)
+		if cerr != nil {
+			resp.Body.Close()
+			pin.close()
+			return nil, fmt.Errorf("认证失败: %w", cerr)
+		}
+		if !ok {
+			break
+		}
+		if err := rewindBody(orig, retry); err != nil {
+			break
+		}
+
+		// 读完质询响应体，使NTLM握手能够复用同一连接
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		req = retry
+		resp, err = t.next.RoundTrip(req)
+	}
+
+	if pin != nil {
+		if err != nil {
+			pin.close()
+		} else {
+			resp.Body = &pinnedBody{ReadCloser: resp.Body, pin: pin}
+		}
+	}
+	return resp, err
+}
+
+// pinsConnection 判断认证方式是否绑定在连接上，需要在同一连接上完成握手
+func pinsConnection(auth Authenticator) bool {
+	switch auth.(type) {
+	case NTLMAuth, *NTLMAuth:
+		return true
+	}
+	return false
+}
+
+// pinnedBody 在响应体关闭时释放握手专用的连接
+type pinnedBody struct {
+	io.ReadCloser
+	pin *connPin
+}
+
+// Close 实现io.Closer接口
+func (b *pinnedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.pin.close()
+	return err
+}
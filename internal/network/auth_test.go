@@ -0,0 +1,169 @@
+package network
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ntlmServer 模拟按连接认证的NTLM服务，AUTHENTICATE必须在收到质询的同一连接上发送
+func ntlmServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	challenge := make([]byte, 48)
+	copy(challenge, ntlmSignature)
+	binary.LittleEndian.PutUint32(challenge[8:], 2)
+	binary.LittleEndian.PutUint32(challenge[20:], ntlmNegotiateFlags)
+	copy(challenge[24:32], "12345678")
+	binary.LittleEndian.PutUint32(challenge[44:], 48)
+
+	var (
+		mu         sync.Mutex
+		challenged = map[string]bool{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "NTLM ")
+		msg, _ := base64.StdEncoding.DecodeString(token)
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch ntlmMessageType(msg) {
+		case 1:
+			challenged[r.RemoteAddr] = true
+			w.Header().Set("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(challenge))
+		case 3:
+			if challenged[r.RemoteAddr] {
+				delete(challenged, r.RemoteAddr)
+				w.Write([]byte("ok"))
+				return
+			}
+			w.Header().Set("WWW-Authenticate", "NTLM")
+		default:
+			w.Header().Set("WWW-Authenticate", "NTLM")
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func authClient(srv *httptest.Server, auth Authenticator) *Client {
+	u, _ := url.Parse(srv.URL)
+	store := NewAuthStore()
+	store.Set(u.Hostname(), auth)
+
+	config := DefaultHTTPClientConfig()
+	config.MaxRetries = 0
+	config.Auth = store
+	return NewHTTPClient(config)
+}
+
+func TestNTLMHandshakePinsConnection(t *testing.T) {
+	srv := ntlmServer(t)
+	client := authClient(srv, NTLMAuth{Domain: "CORP", Username: "alice", Password: "secret"})
+
+	// 并发握手共用一个传输层，未固定连接时AUTHENTICATE可能落在其他连接上
+	var wg sync.WaitGroup
+	errs := make(chan string, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(context.Background(), srv.URL, nil)
+			if err != nil {
+				errs <- err.Error()
+				return
+			}
+			if resp.StatusCode != http.StatusOK {
+				errs <- http.StatusText(resp.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("handshake failed: %s", err)
+	}
+}
+
+func TestDigestNonceCountOnlyWhenSent(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		ncs []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		challenges := parseChallenges([]string{r.Header.Get("Authorization")})
+		if len(challenges) == 0 || !strings.EqualFold(challenges[0].Scheme, "Digest") {
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", nonce="abc", qop="auth"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		ncs = append(ncs, challenges[0].Params["nc"])
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	auth := NewDigestAuth("admin", "secret")
+
+	// 尚未收到质询时不发送认证信息，也不消耗nonce计数
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		if err := auth.Apply(req); err != nil {
+			t.Fatal(err)
+		}
+		if h := req.Header.Get("Authorization"); h != "" {
+			t.Fatalf("Authorization = %q before any challenge", h)
+		}
+	}
+	if auth.nc != 0 {
+		t.Fatalf("nc = %d after applying without a challenge, want 0", auth.nc)
+	}
+
+	client := authClient(srv, auth)
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(context.Background(), srv.URL, nil)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+
+	if got := strings.Join(ncs, ","); got != "00000001,00000002,00000003" {
+		t.Errorf("nc sequence = %s", got)
+	}
+}
+
+func TestAuthStoreMatch(t *testing.T) {
+	store := NewAuthStore()
+	// 较短的子域名规则先添加，最长的规则仍应优先
+	for _, host := range []string{".example.com", ".intranet.example.com", "wiki.intranet.example.com"} {
+		store.Set(host, BasicAuth{Username: host})
+	}
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"wiki.intranet.example.com", "wiki.intranet.example.com"},
+		{"hr.intranet.example.com", ".intranet.example.com"},
+		{"intranet.example.com", ".intranet.example.com"},
+		{"www.example.com", ".example.com"},
+		{"notexample.com", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if auth, ok := store.Match(tt.host).(BasicAuth); ok {
+			got = auth.Username
+		}
+		if got != tt.want {
+			t.Errorf("Match(%s) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package network
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// replayableBody 描述一个可以在重试时重新读取的请求体
+type replayableBody struct {
+	reader        io.Reader
+	contentLength int64
+	// getBody 为空表示请求体无法重放
+	getBody func() (io.ReadCloser, error)
+}
+
+// bytesBody 基于内存数据创建可重放的请求体
+func bytesBody(b []byte) *replayableBody {
+	return &replayableBody{
+		reader:        bytes.NewReader(b),
+		contentLength: int64(len(b)),
+		getBody: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(b)), nil
+		},
+	}
+}
+
+// readerBody 基于io.Reader创建请求体
+// 可Seek的读取器在重放时回到起始位置；其余读取器最多缓存limit字节，
+// 超过限制时请求体按流式发送且无法重放
+func readerBody(r io.Reader, limit int64) (*replayableBody, error) {
+	if rs, ok := r.(io.ReadSeeker); ok {
+		start, err := rs.Seek(0, io.SeekCurrent)
+		if err == nil {
+			end, err := rs.Seek(0, io.SeekEnd)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := rs.Seek(start, io.SeekStart); err != nil {
+				return nil, err
+			}
+
+			return &replayableBody{
+				reader:        rs,
+				contentLength: end - start,
+				getBody: func() (io.ReadCloser, error) {
+					if _, err := rs.Seek(start, io.SeekStart); err != nil {
+						return nil, err
+					}
+					return io.NopCloser(rs), nil
+				},
+			}, nil
+		}
+	}
+
+	if limit <= 0 {
+		return &replayableBody{reader: r, contentLength: -1}, nil
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(buf)) <= limit {
+		return bytesBody(buf), nil
+	}
+
+	// 超过缓存上限，拼接已读取部分和剩余数据按流式发送
+	return &replayableBody{
+		reader:        io.MultiReader(bytes.NewReader(buf), r),
+		contentLength: -1,
+	}, nil
+}
+
+// apply 将请求体设置到请求上
+func (b *replayableBody) apply(req *http.Request) {
+	req.Body = io.NopCloser(b.reader)
+	req.GetBody = b.getBody
+	if b.contentLength >= 0 {
+		req.ContentLength = b.contentLength
+	}
+	if b.contentLength == 0 {
+		req.Body = http.NoBody
+	}
+}
+
+// rewindBody 从GetBody重新获取请求体并设置到请求副本上
+func rewindBody(orig, clone *http.Request) error {
+	if orig.GetBody == nil || orig.Body == nil || orig.Body == http.NoBody {
+		return nil
+	}
+
+	body, err := orig.GetBody()
+	if err != nil {
+		return err
+	}
+	clone.Body = body
+
+	return nil
+}
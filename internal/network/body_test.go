@@ -0,0 +1,157 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bodyRecorder 记录每次收到的请求体，前failures次返回503
+type bodyRecorder struct {
+	mu       sync.Mutex
+	failures int
+	bodies   []string
+}
+
+func (rec *bodyRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := io.ReadAll(r.Body)
+
+	rec.mu.Lock()
+	rec.bodies = append(rec.bodies, string(data))
+	n := len(rec.bodies)
+	rec.mu.Unlock()
+
+	if n <= rec.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func retryClient() *Client {
+	config := DefaultHTTPClientConfig()
+	config.MaxRetries = 3
+	config.RetryInterval = time.Millisecond
+	config.RetryOnStatus = []int{http.StatusServiceUnavailable}
+	config.RetryNonIdempotent = true
+	return NewHTTPClient(config)
+}
+
+// plainReader 隐藏底层读取器的Seek方法
+type plainReader struct {
+	io.Reader
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	const payload = "id=1' AND SLEEP(5)-- -&x=\x00\xff"
+
+	seeker := bytes.NewReader([]byte("skip" + payload))
+	if _, err := seeker.Seek(4, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		body interface{}
+	}{
+		{"bytes", []byte(payload)},
+		{"string", payload},
+		{"reader", plainReader{strings.NewReader(payload)}},
+		{"readseeker", seeker},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &bodyRecorder{failures: 2}
+			srv := httptest.NewServer(rec)
+			defer srv.Close()
+
+			resp, err := retryClient().Post(context.Background(), srv.URL, tt.body, nil)
+			if err != nil {
+				t.Fatalf("Post: %v", err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want 200", resp.StatusCode)
+			}
+
+			if len(rec.bodies) != 3 {
+				t.Fatalf("attempts = %d, want 3", len(rec.bodies))
+			}
+			for i, b := range rec.bodies {
+				if b != payload {
+					t.Errorf("attempt %d body = %q, want %q", i+1, b, payload)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryOversizedReaderNotRetried(t *testing.T) {
+	rec := &bodyRecorder{failures: 2}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	config := DefaultHTTPClientConfig()
+	config.MaxRetries = 3
+	config.RetryInterval = time.Millisecond
+	config.RetryOnStatus = []int{http.StatusServiceUnavailable}
+	config.RetryNonIdempotent = true
+	config.MaxReplayBodySize = 4
+
+	payload := "larger than the replay limit"
+	resp, err := NewHTTPClient(config).Post(context.Background(), srv.URL, plainReader{strings.NewReader(payload)}, nil)
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", resp.StatusCode)
+	}
+	if len(rec.bodies) != 1 || rec.bodies[0] != payload {
+		t.Errorf("bodies = %q, want a single complete body", rec.bodies)
+	}
+}
+
+func TestRedirectReplaysBody(t *testing.T) {
+	const payload = "user=admin&pass=secret"
+
+	tests := []struct {
+		name string
+		body func() interface{}
+	}{
+		{"bytes", func() interface{} { return []byte(payload) }},
+		{"string", func() interface{} { return payload }},
+		{"reader", func() interface{} { return plainReader{strings.NewReader(payload)} }},
+		{"readseeker", func() interface{} { return strings.NewReader(payload) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &bodyRecorder{}
+			mux := http.NewServeMux()
+			mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+				io.Copy(io.Discard, r.Body)
+				http.Redirect(w, r, "/new", http.StatusTemporaryRedirect)
+			})
+			mux.Handle("/new", rec)
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			resp, err := retryClient().Post(context.Background(), srv.URL+"/old", tt.body(), nil)
+			if err != nil {
+				t.Fatalf("Post: %v", err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want 200", resp.StatusCode)
+			}
+			if len(rec.bodies) != 1 || rec.bodies[0] != payload {
+				t.Errorf("redirected bodies = %q, want [%q]", rec.bodies, payload)
+			}
+		})
+	}
+}
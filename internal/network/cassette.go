@@ -0,0 +1,451 @@
+package network
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 录制回放相关的环境变量，在未设置shell选项时使用
+const (
+	CassetteEnv     = "LUNA_CASSETTE"
+	CassetteModeEnv = "LUNA_CASSETTE_MODE"
+)
+
+// ErrCassetteMiss 表示回放时磁带中没有与请求匹配的录制
+var ErrCassetteMiss = errors.New("磁带中没有匹配的录制")
+
+// CassetteMode 是录制回放模式
+type CassetteMode string
+
+const (
+	// CassetteRecord 正常发送请求，并将每次交换写入磁带文件
+	CassetteRecord CassetteMode = "record"
+	// CassetteReplay 不发送请求，从磁带文件中返回录制的响应
+	CassetteReplay CassetteMode = "replay"
+)
+
+// ParseCassetteMode 解析录制回放模式
+func ParseCassetteMode(value string) (CassetteMode, error) {
+	switch mode := CassetteMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case CassetteRecord, CassetteReplay:
+		return mode, nil
+	}
+	return "", fmt.Errorf("无效的录制模式: %s (可选 record、replay)", value)
+}
+
+// CassetteInteraction 是磁带中录制的一次HTTP交换
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest 是录制的请求
+type CassetteRequest struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Headers  http.Header `json:"headers,omitempty"`
+	Body     string      `json:"body,omitempty"`
+	Encoding string      `json:"encoding,omitempty"`
+}
+
+// CassetteResponse 是录制的响应，响应体保持服务器返回的原始编码
+type CassetteResponse struct {
+	StatusCode int         `json:"status"`
+	Status     string      `json:"statusText"`
+	Proto      string      `json:"proto"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Encoding   string      `json:"encoding,omitempty"`
+	// Truncated 表示响应体超过录制上限或未被读完，Body只包含已读取的部分
+	Truncated bool `json:"truncated,omitempty"`
+	// Duration 是录制时的耗时，回放时不等待
+	Duration time.Duration `json:"duration"`
+}
+
+// cassetteFile 是磁带文件的根对象
+type cassetteFile struct {
+	Interactions []*CassetteInteraction `json:"interactions"`
+}
+
+// DefaultCassetteBodyLimit 是客户端未限制响应体大小时每个录制的响应体上限
+const DefaultCassetteBodyLimit = 10 << 20
+
+// 录制文件的开头和结尾，每次追加交换后重写结尾，使文件始终是完整的JSON
+const (
+	cassetteHeader  = "{\n  \"interactions\": [\n"
+	cassetteTrailer = "\n  ]\n}\n"
+)
+
+// Cassette 是录制回放使用的磁带，可在多个客户端之间共享
+// 录制模式下每次交换完成后立即追加到文件，凭据类请求头和Cookie值会被替换；回放模式下按方法、URL和请求体匹配录制的交换，
+// 相同请求按录制顺序依次返回，用完后重复返回最后一次
+type Cassette struct {
+	Path string
+	Mode CassetteMode
+
+	mu           sync.Mutex
+	interactions []*CassetteInteraction
+	used         []bool
+	// file 和 end 是录制文件及其结尾的偏移量
+	file *os.File
+	end  int64
+}
+
+// OpenCassette 打开磁带
+// 录制模式会覆盖已有文件，回放模式要求文件存在
+func OpenCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{Path: path, Mode: mode}
+
+	switch mode {
+	case CassetteRecord:
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("写入磁带失败: %w", err)
+		}
+		c.file = f
+		c.end = int64(len(cassetteHeader))
+		if _, err := f.WriteString(cassetteHeader + strings.TrimPrefix(cassetteTrailer, "\n")); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("写入磁带失败: %w", err)
+		}
+	case CassetteReplay:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取磁带失败: %w", err)
+		}
+		var file cassetteFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("解析磁带 %s 失败: %w", path, err)
+		}
+		c.interactions = file.Interactions
+		c.used = make([]bool, len(file.Interactions))
+	default:
+		return nil, fmt.Errorf("无效的录制模式: %s", mode)
+	}
+
+	return c, nil
+}
+
+// CassetteFromEnv 根据 LUNA_CASSETTE 和 LUNA_CASSETTE_MODE 环境变量打开磁带
+// 未设置LUNA_CASSETTE时返回nil，未设置模式时默认为回放
+func CassetteFromEnv() (*Cassette, error) {
+	path := os.Getenv(CassetteEnv)
+	if path == "" {
+		return nil, nil
+	}
+
+	mode := CassetteReplay
+	if v := os.Getenv(CassetteModeEnv); v != "" {
+		m, err := ParseCassetteMode(v)
+		if err != nil {
+			return nil, err
+		}
+		mode = m
+	}
+
+	return OpenCassette(path, mode)
+}
+
+// Interactions 返回磁带中的所有交换
+func (c *Cassette) Interactions() []*CassetteInteraction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list := make([]*CassetteInteraction, len(c.interactions))
+	copy(list, c.interactions)
+	return list
+}
+
+// record 添加一次交换并追加到文件，只重写文件结尾，不重写已录制的交换
+func (c *Cassette) record(i *CassetteInteraction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return fmt.Errorf("磁带 %s 已关闭", c.Path)
+	}
+
+	data, err := json.MarshalIndent(i, "    ", "  ")
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if len(c.interactions) > 0 {
+		buf.WriteString(",\n")
+	}
+	buf.WriteString("    ")
+	buf.Write(data)
+	entry := int64(buf.Len())
+	buf.WriteString(cassetteTrailer)
+
+	if _, err := c.file.WriteAt(buf.Bytes(), c.end); err != nil {
+		return fmt.Errorf("写入磁带失败: %w", err)
+	}
+	c.end += entry
+	c.interactions = append(c.interactions, i)
+	return nil
+}
+
+// Close 关闭录制文件，已录制的交换都已写入，回放模式下不做任何处理
+func (c *Cassette) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+// match 查找与请求匹配的交换，优先返回尚未使用的
+func (c *Cassette) match(method, url string, body []byte) *CassetteInteraction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	last := -1
+	for i, it := range c.interactions {
+		if it.Request.Method != method || it.Request.URL != url {
+			continue
+		}
+		if b, err := decodeCassetteBody(it.Request.Body, it.Request.Encoding); err != nil || !bytes.Equal(b, body) {
+			continue
+		}
+		if !c.used[i] {
+			c.used[i] = true
+			return it
+		}
+		last = i
+	}
+
+	if last >= 0 {
+		return c.interactions[last]
+	}
+	return nil
+}
+
+// encodeCassetteBody 编码请求体或响应体，非UTF-8内容使用base64
+func encodeCassetteBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func decodeCassetteBody(body, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return []byte(body), nil
+}
+
+// cassetteRedacted 替换磁带中凭据的值
+const cassetteRedacted = "REDACTED"
+
+// credentialHeaderWords 是请求头名称中表示携带凭据的关键字
+var credentialHeaderWords = []string{"token", "secret", "session", "password", "api-key", "apikey", "auth"}
+
+// isCredentialHeader 判断请求头是否携带凭据，如Authorization、Cookie和各类会话令牌
+func isCredentialHeader(name string) bool {
+	name = strings.ToLower(name)
+	if name == "cookie" {
+		return true
+	}
+	for _, w := range credentialHeaderWords {
+		if strings.Contains(name, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactRequestHeaders 复制请求头并替换凭据的值，磁带文件不保存认证信息
+// 回放只按方法、URL和请求体匹配，替换不影响回放
+func redactRequestHeaders(h http.Header) http.Header {
+	h = h.Clone()
+	for name, values := range h {
+		if !isCredentialHeader(name) {
+			continue
+		}
+		for i := range values {
+			values[i] = cassetteRedacted
+		}
+	}
+	return h
+}
+
+// redactResponseHeaders 复制响应头并替换Set-Cookie中的Cookie值
+// 保留Cookie名称和属性，回放时依赖Cookie的流程仍能进行
+func redactResponseHeaders(h http.Header) http.Header {
+	h = h.Clone()
+	for i, v := range h["Set-Cookie"] {
+		name, rest, _ := strings.Cut(v, "=")
+		attrs := ""
+		if j := strings.IndexByte(rest, ';'); j >= 0 {
+			attrs = rest[j:]
+		}
+		h["Set-Cookie"][i] = name + "=" + cassetteRedacted + attrs
+	}
+	return h
+}
+
+// cassetteTransport 位于传输链最内层，录制或回放未经解码的原始交换
+type cassetteTransport struct {
+	next     http.RoundTripper
+	cassette *Cassette
+	// limit 是录制的响应体上限，为0时使用DefaultCassetteBodyLimit
+	limit int64
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if t.cassette.Mode == CassetteReplay {
+		return t.replay(req, body)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	it := &CassetteInteraction{
+		Request: CassetteRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: redactRequestHeaders(req.Header),
+		},
+		Response: CassetteResponse{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Proto:      resp.Proto,
+			Headers:    redactResponseHeaders(resp.Header),
+		},
+	}
+	it.Request.Body, it.Request.Encoding = encodeCassetteBody(body)
+
+	limit := t.limit
+	if limit <= 0 {
+		limit = DefaultCassetteBodyLimit
+	}
+	// 响应体在调用方读取时同步录制，流式请求不必先读入内存，超出上限的部分不录制
+	resp.Body = &cassetteBody{ReadCloser: resp.Body, cassette: t.cassette, it: it, start: start, limit: limit}
+	return resp, nil
+}
+
+// cassetteBody 在调用方读取响应体的同时录制，读完或关闭时写入磁带
+type cassetteBody struct {
+	io.ReadCloser
+	cassette *Cassette
+	it       *CassetteInteraction
+	start    time.Time
+	limit    int64
+
+	buf       bytes.Buffer
+	truncated bool
+	done      bool
+	err       error
+}
+
+// Read 实现io.Reader接口，读到结尾时写入磁带，写入失败时返回错误
+func (b *cassetteBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if remain := b.limit - int64(b.buf.Len()); remain < int64(n) {
+		b.buf.Write(p[:max(remain, 0)])
+		b.truncated = true
+	} else {
+		b.buf.Write(p[:n])
+	}
+
+	if err == io.EOF {
+		if rerr := b.finish(false); rerr != nil {
+			return n, rerr
+		}
+	}
+	return n, err
+}
+
+// Close 实现io.Closer接口，未读完就关闭时录制已读取的部分并标记截断
+func (b *cassetteBody) Close() error {
+	err := b.ReadCloser.Close()
+	if rerr := b.finish(true); rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+// finish 写入磁带，只执行一次
+func (b *cassetteBody) finish(early bool) error {
+	if b.done {
+		return b.err
+	}
+	b.done = true
+
+	resp := &b.it.Response
+	resp.Body, resp.Encoding = encodeCassetteBody(b.buf.Bytes())
+	resp.Truncated = b.truncated || early
+	resp.Duration = time.Since(b.start)
+	b.err = b.cassette.record(b.it)
+	return b.err
+}
+
+// replay 返回录制的响应，没有匹配的交换时返回错误
+func (t *cassetteTransport) replay(req *http.Request, body []byte) (*http.Response, error) {
+	it := t.cassette.match(req.Method, req.URL.String(), body)
+	if it == nil {
+		return nil, fmt.Errorf("%w: %s %s (请求体 %d 字节, 磁带 %s)",
+			ErrCassetteMiss, req.Method, req.URL, len(body), t.cassette.Path)
+	}
+
+	respBody, err := decodeCassetteBody(it.Response.Body, it.Response.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("磁带 %s 中的响应体无效: %w", t.cassette.Path, err)
+	}
+
+	proto := it.Response.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	major, minor, _ := http.ParseHTTPVersion(proto)
+
+	header := it.Response.Headers.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        it.Response.Status,
+		StatusCode:    it.Response.StatusCode,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
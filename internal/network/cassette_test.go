@@ -0,0 +1,226 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func cassetteClient(c *Cassette) *Client {
+	config := DefaultHTTPClientConfig()
+	config.MaxRetries = 0
+	config.Cassette = c
+	return NewHTTPClient(config)
+}
+
+// fetchAll 依次请求paths，返回每个响应的状态码和响应体
+func fetchAll(t *testing.T, client *Client, base string, paths []string) []string {
+	t.Helper()
+
+	headers := map[string]string{
+		"Authorization": "Bearer s3cr3t-token",
+		"Cookie":        "sid=s3cr3t-cookie",
+		"X-Auth-Token":  "s3cr3t-header",
+	}
+
+	var got []string
+	for _, p := range paths {
+		resp, err := client.Get(context.Background(), base+p, headers)
+		if err != nil {
+			t.Fatalf("GET %s: %v", p, err)
+		}
+		got = append(got, strconv.Itoa(resp.StatusCode)+" "+string(resp.Body))
+	}
+	return got
+}
+
+func TestCassetteRoundTrip(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s3cr3t-session", Path: "/"})
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte("hit " + strconv.Itoa(int(n))))
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	paths := []string{"/a", "/a", "/missing", "/a"}
+
+	rec, err := OpenCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := fetchAll(t, cassetteClient(rec), srv.URL, paths)
+	base := srv.URL
+	srv.Close()
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cr3t") {
+		t.Errorf("cassette contains credentials:\n%s", data)
+	}
+
+	play, err := OpenCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := cassetteClient(play)
+
+	// 相同请求按录制顺序返回，用完后重复最后一次
+	replayed := fetchAll(t, client, base, paths)
+	if strings.Join(replayed, "|") != strings.Join(recorded, "|") {
+		t.Errorf("replayed = %q, recorded = %q", replayed, recorded)
+	}
+	if got := fetchAll(t, client, base, []string{"/a"}); got[0] != recorded[3] {
+		t.Errorf("exhausted replay = %q, want last recording %q", got[0], recorded[3])
+	}
+
+	_, err = client.Get(context.Background(), base+"/never-recorded", nil)
+	if !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("unmatched request error = %v, want ErrCassetteMiss", err)
+	}
+}
+
+func TestRedactCassetteHeaders(t *testing.T) {
+	req := http.Header{
+		"Authorization":       {"NTLM TlRMTVNTUAAB"},
+		"Proxy-Authorization": {"Basic YTpi"},
+		"Cookie":              {"a=1; b=2"},
+		"X-Csrf-Token":        {"abc"},
+		"X-Session-Id":        {"42"},
+		"Accept":              {"*/*"},
+	}
+	got := redactRequestHeaders(req)
+	for name := range req {
+		want := cassetteRedacted
+		if name == "Accept" {
+			want = "*/*"
+		}
+		if got.Get(name) != want {
+			t.Errorf("%s = %q, want %q", name, got.Get(name), want)
+		}
+	}
+	if req.Get("Cookie") != "a=1; b=2" {
+		t.Error("original request headers modified")
+	}
+
+	resp := redactResponseHeaders(http.Header{
+		"Set-Cookie": {"sid=abc; Path=/; HttpOnly", "pref=1"},
+	})
+	if v := resp.Values("Set-Cookie"); v[0] != "sid=REDACTED; Path=/; HttpOnly" || v[1] != "pref=REDACTED" {
+		t.Errorf("Set-Cookie = %q", v)
+	}
+}
+
+func TestCassetteRecordBodyLimit(t *testing.T) {
+	big := strings.Repeat("x", 4096)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(big))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec, err := OpenCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Close()
+
+	config := DefaultHTTPClientConfig()
+	config.MaxRetries = 0
+	config.MaxBodySize = 1024
+	config.Cassette = rec
+	client := NewHTTPClient(config)
+
+	resp, err := client.Get(context.Background(), srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Truncated || len(resp.Body) != 1024 {
+		t.Fatalf("response = %d bytes, truncated %v", len(resp.Body), resp.Truncated)
+	}
+
+	// 流式请求在调用方读取时录制
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/stream", nil)
+	stream, err := client.Stream(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.Interactions()); n != 1 {
+		t.Fatalf("interactions before the stream is read = %d, want 1", n)
+	}
+	data, _ := io.ReadAll(stream.BodyReader)
+	stream.BodyReader.Close()
+	if string(data) != big {
+		t.Fatalf("stream body = %d bytes, want %d", len(data), len(big))
+	}
+
+	list := rec.Interactions()
+	if len(list) != 2 {
+		t.Fatalf("interactions = %d, want 2", len(list))
+	}
+	for i, it := range list {
+		if !it.Response.Truncated || len(it.Response.Body) != 1024 {
+			t.Errorf("interaction %d: body %d bytes, truncated %v", i, len(it.Response.Body), it.Response.Truncated)
+		}
+	}
+}
+
+func TestCassetteFileValidAfterEachRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec, err := OpenCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Close()
+
+	check := func(want int) {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var file cassetteFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			t.Fatalf("cassette with %d interactions is not valid JSON: %v\n%s", want, err, data)
+		}
+		if len(file.Interactions) != want {
+			t.Fatalf("interactions in file = %d, want %d", len(file.Interactions), want)
+		}
+	}
+
+	check(0)
+	for i := 1; i <= 3; i++ {
+		it := &CassetteInteraction{
+			Request:  CassetteRequest{Method: "GET", URL: "http://example.com/" + strconv.Itoa(i)},
+			Response: CassetteResponse{StatusCode: 200, Body: "body " + strconv.Itoa(i)},
+		}
+		if err := rec.record(it); err != nil {
+			t.Fatal(err)
+		}
+		check(i)
+	}
+
+	play, err := OpenCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it := play.match("GET", "http://example.com/2", nil); it == nil || it.Response.Body != "body 2" {
+		t.Errorf("match = %+v", it)
+	}
+}
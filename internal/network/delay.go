@@ -0,0 +1,193 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// minDelayJitter 是基线抖动的下限，避免基线过于稳定时微小的波动也被视为显著
+const minDelayJitter = 20 * time.Millisecond
+
+// minDelaySlope 是耗时对注入延迟回归斜率的下限
+const minDelaySlope = 0.8
+
+// DelayProbe 发送一次注入了指定延迟的请求，delay为0时发送不含延迟载荷的正常请求
+type DelayProbe func(ctx context.Context, delay time.Duration) (*HTTPResponse, error)
+
+// DelaySample 是一次请求注入的延迟和观测到的耗时
+type DelaySample struct {
+	Delay   time.Duration
+	Elapsed time.Duration
+}
+
+// DelayResult 是时间盲注检测的结果
+type DelayResult struct {
+	// Vulnerable 表示响应耗时随注入的延迟稳定增长，且置信度达到阈值
+	Vulnerable bool
+	// Confidence 是0到1之间的置信度
+	Confidence float64
+	// Mean 和 StdDev 是基线耗时的均值和标准差
+	Mean, StdDev time.Duration
+	// Samples 按发送顺序记录所有请求，包括基线和最后的对照请求
+	Samples []DelaySample
+	// Slope 是耗时对注入延迟的线性回归斜率，注入成功时接近1，按行执行的延迟函数会成倍增长
+	Slope float64
+	// Reason 说明判定依据
+	Reason string
+}
+
+// DelayDetector 通过统计检验判断响应耗时是否受注入的延迟控制，用于时间盲注和命令注入检测
+// 先测量多次基线耗时，再按递增的延迟依次发送载荷，每次耗时都必须显著高于基线且不低于注入的延迟，
+// 最后发送一次对照请求确认目标没有整体变慢，并要求耗时随延迟成比例增长；
+// 置信度综合单次观测相对基线的显著性和耗时与延迟的线性相关程度
+// 耗时优先使用响应的TTFB，不受响应体大小影响；HTTP客户端的超时必须大于最大延迟
+type DelayDetector struct {
+	// Baseline 是基线请求次数，至少为2
+	Baseline int
+	// Delays 是依次注入的延迟，小于基线抖动六倍的延迟无法区分，会被跳过
+	Delays []time.Duration
+	// Threshold 是判定存在注入所需的最低置信度
+	Threshold float64
+}
+
+// NewDelayDetector 创建使用默认参数的检测器：5次基线，延迟2s、4s、6s，置信度阈值0.95
+func NewDelayDetector() *DelayDetector {
+	return &DelayDetector{
+		Baseline:  5,
+		Delays:    []time.Duration{2 * time.Second, 4 * time.Second, 6 * time.Second},
+		Threshold: 0.95,
+	}
+}
+
+// Detect 执行检测，某个延迟未能复现时立即停止，不再发送后续请求
+func (d *DelayDetector) Detect(ctx context.Context, probe DelayProbe) (*DelayResult, error) {
+	if d.Baseline < 2 {
+		return nil, errors.New("基线请求次数至少为2")
+	}
+	if len(d.Delays) == 0 {
+		return nil, errors.New("未指定注入的延迟")
+	}
+
+	result := &DelayResult{}
+	measure := func(delay time.Duration) (time.Duration, error) {
+		resp, err := probe(ctx, delay)
+		if err != nil {
+			return 0, fmt.Errorf("延迟 %s 的请求失败: %w", delay, err)
+		}
+		elapsed := responseLatency(resp)
+		result.Samples = append(result.Samples, DelaySample{Delay: delay, Elapsed: elapsed})
+		return elapsed, nil
+	}
+
+	baseline := make([]float64, 0, d.Baseline)
+	for i := 0; i < d.Baseline; i++ {
+		elapsed, err := measure(0)
+		if err != nil {
+			return nil, err
+		}
+		baseline = append(baseline, float64(elapsed))
+	}
+	mean, stddev := meanStdDev(baseline)
+	result.Mean, result.StdDev = time.Duration(mean), time.Duration(stddev)
+	jitter := math.Max(stddev, float64(minDelayJitter))
+
+	// 单次观测的显著性：基线耗时达到该值的概率
+	pValue := 1.0
+	tested := 0
+	for _, delay := range d.Delays {
+		if float64(delay) < 6*jitter {
+			continue
+		}
+		tested++
+
+		elapsed, err := measure(delay)
+		if err != nil {
+			return nil, err
+		}
+		excess := float64(elapsed) - mean
+		if float64(elapsed) < float64(delay) || excess < float64(delay)-3*jitter {
+			result.Reason = fmt.Sprintf("注入 %s 延迟后耗时 %s，未达到预期", delay, elapsed)
+			return result, nil
+		}
+		pValue *= normalTail(excess / jitter)
+	}
+	if tested == 0 {
+		result.Reason = fmt.Sprintf("基线抖动 %s 过大，注入的延迟无法区分", time.Duration(jitter))
+		return result, nil
+	}
+
+	// 对照请求确认延迟来自载荷而不是目标整体变慢
+	control, err := measure(0)
+	if err != nil {
+		return nil, err
+	}
+	if float64(control) > mean+6*jitter {
+		result.Reason = fmt.Sprintf("对照请求耗时 %s，目标响应整体变慢", control)
+		return result, nil
+	}
+
+	slope, r2 := linearFit(result.Samples)
+	result.Slope = slope
+	if slope < minDelaySlope {
+		result.Reason = fmt.Sprintf("耗时增长斜率 %.2f 低于 %.1f，与注入的延迟不成比例", slope, minDelaySlope)
+		return result, nil
+	}
+	result.Confidence = (1 - pValue) * r2
+	result.Vulnerable = result.Confidence >= d.Threshold
+	result.Reason = fmt.Sprintf("%d 个延迟均复现，斜率 %.2f，R² %.3f", tested, slope, r2)
+
+	return result, nil
+}
+
+// responseLatency 返回用于比较的耗时，优先使用TTFB
+func responseLatency(resp *HTTPResponse) time.Duration {
+	if resp.Timing.TTFB > 0 {
+		return resp.Timing.TTFB
+	}
+	return resp.Duration
+}
+
+// meanStdDev 计算均值和样本标准差
+func meanStdDev(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)-1))
+}
+
+// normalTail 返回标准正态分布大于z的概率
+func normalTail(z float64) float64 {
+	return 0.5 * math.Erfc(z/math.Sqrt2)
+}
+
+// linearFit 对耗时和注入的延迟做最小二乘拟合，返回斜率和决定系数
+func linearFit(samples []DelaySample) (float64, float64) {
+	n := float64(len(samples))
+	var sx, sy, sxx, sxy, syy float64
+	for _, s := range samples {
+		x, y := float64(s.Delay), float64(s.Elapsed)
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+		syy += y * y
+	}
+
+	vx := sxx - sx*sx/n
+	vy := syy - sy*sy/n
+	cov := sxy - sx*sy/n
+	if vx == 0 || vy == 0 {
+		return 0, 0
+	}
+	return cov / vx, cov * cov / (vx * vy)
+}
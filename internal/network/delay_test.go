@@ -0,0 +1,126 @@
+package network
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProbe 返回不实际等待的探测函数，第n次请求的耗时由elapsed给出
+func fakeProbe(elapsed func(n int, delay time.Duration) time.Duration) DelayProbe {
+	n := 0
+	return func(ctx context.Context, delay time.Duration) (*HTTPResponse, error) {
+		d := elapsed(n, delay)
+		n++
+		return &HTTPResponse{Duration: d}, nil
+	}
+}
+
+// cycle 按请求序号循环返回基线耗时
+func cycle(values ...time.Duration) func(n int) time.Duration {
+	return func(n int) time.Duration {
+		return values[n%len(values)]
+	}
+}
+
+func TestDelayDetector(t *testing.T) {
+	const ms = time.Millisecond
+	stable := cycle(98*ms, 100*ms, 102*ms, 99*ms, 101*ms)
+
+	tests := []struct {
+		name      string
+		delays    []time.Duration
+		elapsed   func(n int, delay time.Duration) time.Duration
+		vuln      bool
+		samples   int
+		reason    string
+		slopeOver float64
+	}{
+		{
+			name: "vulnerable",
+			elapsed: func(n int, delay time.Duration) time.Duration {
+				return stable(n) + delay
+			},
+			vuln:      true,
+			samples:   9,
+			slopeOver: 0.95,
+		},
+		{
+			name: "not injectable",
+			elapsed: func(n int, delay time.Duration) time.Duration {
+				return stable(n)
+			},
+			samples: 6,
+			reason:  "未达到预期",
+		},
+		{
+			// 基线之后目标整体变慢，每个延迟看似都复现，对照请求将其排除
+			name: "uniformly slow",
+			elapsed: func(n int, delay time.Duration) time.Duration {
+				if n < 5 {
+					return stable(n)
+				}
+				return 7*time.Second + stable(n)
+			},
+			samples: 9,
+			reason:  "对照请求",
+		},
+		{
+			name: "high jitter",
+			elapsed: func(n int, delay time.Duration) time.Duration {
+				return cycle(100*ms, 2*time.Second, 50*ms, 3*time.Second, 200*ms)(n)
+			},
+			samples: 5,
+			reason:  "抖动",
+		},
+		{
+			// 每个延迟都勉强达到阈值，但耗时的增长明显慢于注入的延迟
+			name:   "non-proportional slope",
+			delays: []time.Duration{2 * time.Second, 2500 * ms, 3 * time.Second},
+			elapsed: func(n int, delay time.Duration) time.Duration {
+				base := cycle(600*ms, 800*ms, time.Second, 1200*ms, 1400*ms)(n)
+				if delay == 0 {
+					return base
+				}
+				return time.Second + delay - 900*ms
+			},
+			samples: 9,
+			reason:  "斜率",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDelayDetector()
+			if tt.delays != nil {
+				d.Delays = tt.delays
+			}
+
+			result, err := d.Detect(context.Background(), fakeProbe(tt.elapsed))
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+			if result.Vulnerable != tt.vuln {
+				t.Errorf("vulnerable = %v, want %v (%s)", result.Vulnerable, tt.vuln, result.Reason)
+			}
+			if len(result.Samples) != tt.samples {
+				t.Errorf("samples = %d, want %d", len(result.Samples), tt.samples)
+			}
+			if tt.reason != "" && !strings.Contains(result.Reason, tt.reason) {
+				t.Errorf("reason = %q, want mention of %q", result.Reason, tt.reason)
+			}
+			if tt.vuln && (result.Confidence < d.Threshold || result.Slope < tt.slopeOver) {
+				t.Errorf("confidence = %.3f, slope = %.2f", result.Confidence, result.Slope)
+			}
+		})
+	}
+}
+
+func TestDelayDetectorPrefersTTFB(t *testing.T) {
+	resp := &HTTPResponse{Duration: 5 * time.Second}
+	resp.Timing.TTFB = 2 * time.Second
+	if got := responseLatency(resp); got != 2*time.Second {
+		t.Errorf("latency = %s, want TTFB", got)
+	}
+}
@@ -0,0 +1,330 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Dialer 按客户端配置建立连接，供原始HTTP和套接字类请求使用
+// 与HTTP客户端共享代理、TLS、hosts映射和DNS配置，代理支持SOCKS5和HTTP CONNECT
+type Dialer struct {
+	config   HTTPClientConfig
+	dialer   *net.Dialer
+	resolver *hostResolver
+}
+
+// ErrUDPProxy 表示设置了代理时尝试建立UDP连接
+var ErrUDPProxy = errors.New("代理不支持UDP，可将目标加入no_proxy后直连")
+
+// NewDialer 创建一个新的连接器
+func NewDialer(config HTTPClientConfig) *Dialer {
+	d := &Dialer{
+		config: config,
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+	}
+	d.resolver = newHostResolver(config.Hosts, config.DNSServer, d.dialer.DialContext)
+
+	return d
+}
+
+// DialContext 建立到addr的连接，TCP连接会按配置经过代理
+// 配置了hosts映射或自定义DNS时，经过代理前先在本地解析，代理收到的是解析后的IP；
+// 否则主机名由代理解析。TLS的SNI仍使用原始主机名
+// 代理不转发UDP，设置了代理时UDP连接返回ErrUDPProxy，不在NoProxy中的主机不会被直连
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.config.Proxy == "" {
+		return d.dialDirect(ctx, network, addr)
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if bypassProxy(host, d.config.NoProxy) {
+		return d.dialDirect(ctx, network, addr)
+	}
+	if !isTCP(network) {
+		return nil, fmt.Errorf("%w: %s %s", ErrUDPProxy, network, addr)
+	}
+
+	proxy, err := ParseProxyURL(d.config.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	target, err := d.resolver.resolveAddr(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := d.dialer.DialContext(ctx, "tcp", proxy.Host)
+	if err != nil {
+		return nil, fmt.Errorf("连接代理失败: %w", err)
+	}
+
+	// 握手期间遵循上下文的截止时间
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	switch proxy.Scheme {
+	case "socks5", "socks5h":
+		err = socks5Connect(conn, proxy, target)
+	case "https":
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+		if err = tlsConn.HandshakeContext(ctx); err == nil {
+			conn = tlsConn
+			err = httpConnect(conn, proxy, target)
+		}
+	default:
+		err = httpConnect(conn, proxy, target)
+	}
+
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// dialDirect 不经代理直接连接，按hosts映射和自定义DNS服务器解析主机名
+func (d *Dialer) dialDirect(ctx context.Context, network, addr string) (net.Conn, error) {
+	resolved, err := d.resolver.resolveAddr(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return d.dialer.DialContext(ctx, network, resolved)
+}
+
+// DialTLSContext 建立TLS连接，使用客户端的TLS配置
+// serverName 为空时使用addr中的主机名作为SNI
+func (d *Dialer) DialTLSContext(ctx context.Context, network, addr, serverName string) (*tls.Conn, error) {
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConn, err := d.ClientTLS(ctx, conn, addr, serverName)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// ClientTLS 在已有连接上完成TLS握手
+func (d *Dialer) ClientTLS(ctx context.Context, conn net.Conn, addr, serverName string) (*tls.Conn, error) {
+	return d.clientTLS(ctx, conn, addr, serverName, nil)
+}
+
+// clientTLS 在已有连接上完成TLS握手，nextProtos 是ALPN协商的协议列表
+func (d *Dialer) clientTLS(ctx context.Context, conn net.Conn, addr, serverName string, nextProtos []string) (*tls.Conn, error) {
+	config, err := d.config.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+
+	if serverName != "" {
+		config.ServerName = serverName
+	}
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		config.ServerName = host
+	}
+	if nextProtos != nil {
+		config.NextProtos = nextProtos
+	}
+
+	tlsConn := tls.Client(conn, config)
+	done := traceTLS(ctx)
+	err = tlsConn.HandshakeContext(ctx)
+	done(tlsConn.ConnectionState(), err)
+	if err != nil {
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// isTCP 判断网络类型是否为TCP
+func isTCP(network string) bool {
+	return network == "tcp" || network == "tcp4" || network == "tcp6"
+}
+
+// httpConnect 通过HTTP CONNECT建立隧道
+func httpConnect(conn net.Conn, proxy *url.URL, addr string) error {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+
+	if err := req.Write(conn); err != nil {
+		return fmt.Errorf("发送CONNECT请求失败: %w", err)
+	}
+
+	// 逐字节读取响应头，避免缓冲区吞掉隧道中的后续数据
+	resp, err := http.ReadResponse(bufio.NewReaderSize(&byteReader{conn}, 1), req)
+	if err != nil {
+		return fmt.Errorf("读取CONNECT响应失败: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("代理拒绝CONNECT请求: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// byteReader 每次只读取一个字节
+type byteReader struct {
+	r io.Reader
+}
+
+func (b *byteReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return b.r.Read(p[:1])
+}
+
+// socks5Connect 通过SOCKS5代理建立到addr的连接 (RFC 1928, RFC 1929)
+func socks5Connect(conn net.Conn, proxy *url.URL, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return fmt.Errorf("无效的端口: %s", portStr)
+	}
+
+	// 协商认证方式：无认证(0x00)，有用户名时追加用户名密码认证(0x02)
+	methods := []byte{0x00}
+	if proxy.User != nil {
+		methods = []byte{0x02}
+	}
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("读取SOCKS5握手响应失败: %w", err)
+	}
+	if reply[0] != 0x05 {
+		return errors.New("代理不是SOCKS5服务")
+	}
+
+	switch reply[1] {
+	case 0x00:
+	case 0x02:
+		if proxy.User == nil {
+			return errors.New("SOCKS5代理要求认证")
+		}
+		username := proxy.User.Username()
+		password, _ := proxy.User.Password()
+		if len(username) > 255 || len(password) > 255 {
+			return errors.New("SOCKS5用户名或密码过长")
+		}
+		msg := []byte{0x01, byte(len(username))}
+		msg = append(msg, username...)
+		msg = append(msg, byte(len(password)))
+		msg = append(msg, password...)
+		if _, err := conn.Write(msg); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return fmt.Errorf("读取SOCKS5认证响应失败: %w", err)
+		}
+		if reply[1] != 0x00 {
+			return errors.New("SOCKS5认证失败")
+		}
+	default:
+		return errors.New("SOCKS5代理不支持可用的认证方式")
+	}
+
+	// 发送CONNECT请求，域名交由代理解析
+	req := []byte{0x05, 0x01, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, 0x01)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, 0x04)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return errors.New("SOCKS5目标域名过长")
+		}
+		req = append(req, 0x03, byte(len(host)))
+		req = append(req, host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return fmt.Errorf("读取SOCKS5连接响应失败: %w", err)
+	}
+	if head[1] != 0x00 {
+		return fmt.Errorf("SOCKS5代理连接目标失败，错误码: %d", head[1])
+	}
+
+	// 跳过绑定地址
+	var skip int
+	switch head[3] {
+	case 0x01:
+		skip = net.IPv4len + 2
+	case 0x04:
+		skip = net.IPv6len + 2
+	case 0x03:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		skip = int(l[0]) + 2
+	default:
+		return errors.New("SOCKS5响应地址类型无效")
+	}
+	if _, err := io.ReadFull(conn, make([]byte, skip)); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// acceptEncoding 是客户端默认声明支持的内容编码
+const acceptEncoding = "gzip, deflate, br"
+
+// contentEncodings 返回响应使用的内容编码，按应用顺序排列，忽略identity
+func contentEncodings(h http.Header) []string {
+	var encodings []string
+	for _, v := range h.Values("Content-Encoding") {
+		for _, enc := range strings.Split(v, ",") {
+			enc = strings.ToLower(strings.TrimSpace(enc))
+			if enc != "" && enc != "identity" {
+				encodings = append(encodings, enc)
+			}
+		}
+	}
+	return encodings
+}
+
+// newDecoder 为单个内容编码创建解码读取器
+func newDecoder(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		// 规范要求zlib封装，但不少服务器直接发送裸deflate数据
+		br := bufio.NewReader(r)
+		header, _ := br.Peek(2)
+		if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	case "br":
+		return io.NopCloser(brotli.NewReader(r)), nil
+	default:
+		return nil, fmt.Errorf("不支持的内容编码: %s", encoding)
+	}
+}
+
+// supportedEncodings 判断是否支持所有内容编码
+func supportedEncodings(encodings []string) bool {
+	for _, enc := range encodings {
+		switch enc {
+		case "gzip", "x-gzip", "deflate", "br":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// decodingReader 按Content-Encoding逐层解码响应体，关闭时同时关闭原始响应体
+// 解码器在首次读取时创建，数据损坏等错误在读取时返回
+type decodingReader struct {
+	body      io.ReadCloser
+	encodings []string
+	reader    io.Reader
+	closers   []io.Closer
+	err       error
+}
+
+// newDecodingReader 包装响应体，encodings需先经supportedEncodings检查
+func newDecodingReader(body io.ReadCloser, encodings []string) *decodingReader {
+	return &decodingReader{body: body, encodings: encodings}
+}
+
+func (d *decodingReader) init() error {
+	d.reader = d.body
+
+	// 编码按应用顺序列出，解码时逆序进行
+	for i := len(d.encodings) - 1; i >= 0; i-- {
+		dec, err := newDecoder(d.encodings[i], d.reader)
+		if err == io.EOF {
+			// 空响应体
+			return io.EOF
+		}
+		if err != nil {
+			return fmt.Errorf("解码响应体失败 (%s): %w", d.encodings[i], err)
+		}
+		d.reader = dec
+		d.closers = append(d.closers, dec)
+	}
+	return nil
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	if d.reader == nil && d.err == nil {
+		d.err = d.init()
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.reader.Read(p)
+}
+
+// Close 关闭解码器和原始响应体
+func (d *decodingReader) Close() error {
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		d.closers[i].Close()
+	}
+	return d.body.Close()
+}
+
+// decodeBody 解码内存中的响应体，失败时返回原始数据
+func decodeBody(h http.Header, body []byte) []byte {
+	encodings := contentEncodings(h)
+	if len(encodings) == 0 || len(body) == 0 || !supportedEncodings(encodings) {
+		return body
+	}
+
+	r := newDecodingReader(io.NopCloser(bytes.NewReader(body)), encodings)
+	defer r.Close()
+
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+// decodingTransport 声明支持的压缩格式并透明解码响应体
+// 响应头保持服务器返回的原样，Content-Encoding仍可用于判断
+type decodingTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (t *decodingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	encodings := contentEncodings(resp.Header)
+	// 无法识别的编码保留原始数据
+	if len(encodings) == 0 || !supportedEncodings(encodings) || req.Method == http.MethodHead || resp.Body == http.NoBody {
+		return resp, nil
+	}
+
+	resp.Body = newDecodingReader(resp.Body, encodings)
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
@@ -0,0 +1,243 @@
+package network
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compress 按encoding压缩data
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	default:
+		t.Fatalf("unknown encoding %s", encoding)
+	}
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestContentDecoding(t *testing.T) {
+	plain := []byte(strings.Repeat("luna decodes this body. ", 50))
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		want     []byte
+	}{
+		{"gzip", "gzip", compress(t, "gzip", plain), plain},
+		{"x-gzip", "x-gzip", compress(t, "gzip", plain), plain},
+		{"deflate", "deflate", compress(t, "deflate", plain), plain},
+		{"raw deflate", "deflate", compress(t, "raw-deflate", plain), plain},
+		{"br", "br", compress(t, "br", plain), plain},
+		{"stacked", "gzip, br", compress(t, "br", compress(t, "gzip", plain)), plain},
+		{"identity", "identity", plain, plain},
+		{"case insensitive", "GZIP", compress(t, "gzip", plain), plain},
+		{"unsupported kept raw", "compress", []byte("lzw data"), []byte("lzw data")},
+		{"empty", "gzip", nil, []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acceptEncoding := make(chan string, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				acceptEncoding <- r.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Encoding", tt.encoding)
+				w.Write(tt.body)
+			}))
+			defer srv.Close()
+
+			resp, err := NewHTTPClient(DefaultHTTPClientConfig()).Get(context.Background(), srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(resp.Body, tt.want) {
+				t.Errorf("body = %q, want %q", resp.Body, tt.want)
+			}
+			// 响应头保持原样
+			if got := resp.Headers.Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+			if got := <-acceptEncoding; got != "gzip, deflate, br" {
+				t.Errorf("Accept-Encoding = %q", got)
+			}
+		})
+	}
+}
+
+func TestContentDecodingErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Encoding", "gzip")
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write([]byte("definitely not gzip"))
+	}))
+	defer srv.Close()
+
+	config := DefaultHTTPClientConfig()
+	config.MaxRetries = 0
+	c := NewHTTPClient(config)
+
+	if _, err := c.Get(context.Background(), srv.URL, nil); err == nil || !strings.Contains(err.Error(), "解码响应体失败 (gzip)") {
+		t.Errorf("corrupt body error = %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodHead, srv.URL, nil)
+	if resp, err := c.Do(req); err != nil || len(resp.Body) != 0 {
+		t.Errorf("HEAD = %v, %v", resp, err)
+	}
+
+	// 内存中的数据无法解码时返回原始数据
+	h := http.Header{"Content-Encoding": {"gzip"}}
+	if got := decodeBody(h, []byte("raw")); string(got) != "raw" {
+		t.Errorf("decodeBody = %q", got)
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 100)
+
+	tests := []struct {
+		name      string
+		limit     int64
+		encoding  string
+		wantLen   int
+		truncated bool
+	}{
+		{"under limit", 200, "", 100, false},
+		{"exact limit", 100, "", 100, false},
+		{"over limit", 10, "", 10, true},
+		{"unlimited", 0, "", 100, false},
+		// 限制作用于解码后的数据
+		{"decoded size", 50, "gzip", 50, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+					w.Write(compress(t, tt.encoding, body))
+					return
+				}
+				w.Write(body)
+			}))
+			defer srv.Close()
+
+			config := DefaultHTTPClientConfig()
+			config.MaxBodySize = tt.limit
+			resp, err := NewHTTPClient(config).Get(context.Background(), srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Body) != tt.wantLen || resp.Truncated != tt.truncated {
+				t.Errorf("body %d bytes, truncated %v, want %d, %v", len(resp.Body), resp.Truncated, tt.wantLen, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestMaxBodySizeEndlessBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := bytes.Repeat([]byte("x"), 4096)
+		for r.Context().Err() == nil {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	config := DefaultHTTPClientConfig()
+	config.MaxBodySize = 64 << 10
+	config.Timeout = 5 * time.Second
+
+	// 达到上限后停止读取，不会等到超时
+	start := time.Now()
+	resp, err := NewHTTPClient(config).Get(context.Background(), srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Body) != 64<<10 || !resp.Truncated {
+		t.Errorf("body %d bytes, truncated %v", len(resp.Body), resp.Truncated)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("took %s", d)
+	}
+}
+
+func TestStream(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(strings.Repeat("first ", 100)))
+		gz.Flush()
+		w.(http.Flusher).Flush()
+
+		// 调用方拿到响应后才发送剩余数据
+		<-release
+		gz.Write([]byte(strings.Repeat("second ", 100)))
+		gz.Close()
+	}))
+	defer srv.Close()
+
+	config := DefaultHTTPClientConfig()
+	config.MaxBodySize = 10
+	config.Timeout = 100 * time.Millisecond
+	c := NewHTTPClient(config)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := c.Stream(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.BodyReader.Close()
+	if resp.Body != nil || resp.Truncated {
+		t.Errorf("stream response has body %q, truncated %v", resp.Body, resp.Truncated)
+	}
+
+	// 流式读取超过Timeout也不会中断，且不受MaxBodySize限制
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+
+	data, err := io.ReadAll(resp.BodyReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Repeat("first ", 100) + strings.Repeat("second ", 100); string(data) != want {
+		t.Errorf("streamed %d bytes, want %d decoded bytes", len(data), len(want))
+	}
+
+	// 非流式请求没有BodyReader
+	resp, err = c.Get(context.Background(), srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.BodyReader != nil || !resp.Truncated {
+		t.Errorf("buffered response: BodyReader %v, truncated %v", resp.BodyReader, resp.Truncated)
+	}
+}
@@ -0,0 +1,349 @@
+package network
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// HAR 1.2 格式定义，参见 http://www.softwareishard.com/blog/har-12-spec/
+
+// HAR 是HAR文件的根对象
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog 是HAR日志
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator 描述生成HAR的工具
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry 是一次HTTP交换
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+
+	// 以下划线开头的自定义字段，记录Luna的流量标记
+	LunaID     int    `json:"_lunaId"`
+	LunaScan   string `json:"_lunaScan,omitempty"`
+	LunaPlugin string `json:"_lunaPlugin,omitempty"`
+	LunaTarget string `json:"_lunaTarget,omitempty"`
+
+	// WebSocket会话使用与浏览器导出一致的自定义字段
+	ResourceType      string              `json:"_resourceType,omitempty"`
+	WebSocketMessages []HARWebSocketFrame `json:"_webSocketMessages,omitempty"`
+}
+
+// HARWebSocketFrame 是WebSocket会话中的一个帧
+type HARWebSocketFrame struct {
+	// Type 是 "send" 或 "receive"
+	Type string `json:"type"`
+	// Time 是Unix时间，单位为秒
+	Time   float64 `json:"time"`
+	Opcode int     `json:"opcode"`
+	Data   string  `json:"data"`
+}
+
+// HARRequest 是HAR中的请求
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse 是HAR中的响应
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue 是名称和值的组合
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARCookie 是HAR中的Cookie
+type HARCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData 是请求体
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent 是响应体
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARTimings 是各阶段耗时，单位为毫秒，-1表示不可用
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	// SSL 包含在Connect中
+	SSL float64 `json:"ssl"`
+}
+
+// NewHAR 将流量记录转换为HAR
+func NewHAR(entries []*TrafficEntry) *HAR {
+	har := &HAR{
+		Log: HARLog{
+			Version: "1.2",
+			Creator: HARCreator{Name: "Luna", Version: "1.0"},
+			Entries: make([]HAREntry, 0, len(entries)),
+		},
+	}
+
+	for _, e := range entries {
+		// HAR只能描述HTTP交换
+		if e.Socket {
+			continue
+		}
+		har.Log.Entries = append(har.Log.Entries, harEntry(e))
+	}
+
+	return har
+}
+
+// WriteHAR 将流量记录以HAR格式写入w
+func WriteHAR(w io.Writer, entries []*TrafficEntry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(NewHAR(entries))
+}
+
+// harTimings 将各阶段耗时转换为HAR格式，没有分阶段记录时全部计入等待时间
+func harTimings(e *TrafficEntry) HARTimings {
+	t := e.Timing
+	if t.TTFB == 0 {
+		return HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: millis(e.Duration)}
+	}
+
+	timings := HARTimings{
+		Blocked: -1,
+		DNS:     -1,
+		Connect: -1,
+		SSL:     -1,
+		Wait:    millis(t.TTFB - t.DNS - t.Connect - t.TLS),
+		Receive: millis(e.Duration - t.TTFB),
+	}
+	if t.DNS > 0 {
+		timings.DNS = millis(t.DNS)
+	}
+	// 复用连接时不包含建立连接的耗时
+	if t.Connect > 0 || t.TLS > 0 {
+		timings.Connect = millis(t.Connect + t.TLS)
+	}
+	if t.TLS > 0 {
+		timings.SSL = millis(t.TLS)
+	}
+	if timings.Wait < 0 {
+		timings.Wait = 0
+	}
+	if timings.Receive < 0 {
+		timings.Receive = 0
+	}
+	return timings
+}
+
+// millis 将时长转换为毫秒
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// harServerIP 返回对端地址中的IP
+func harServerIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// harEntry 转换单条记录
+func harEntry(e *TrafficEntry) HAREntry {
+	entry := HAREntry{
+		StartedDateTime: e.StartedAt.Format(time.RFC3339Nano),
+		Time:            millis(e.Duration),
+		Timings:         harTimings(e),
+		ServerIPAddress: harServerIP(e.RemoteAddr),
+		Comment:         e.Error,
+		LunaID:          e.ID,
+		LunaScan:        e.Tags.Scan,
+		LunaPlugin:      e.Tags.Plugin,
+		LunaTarget:      e.Tags.Target,
+	}
+
+	entry.Request = HARRequest{
+		Method:      e.Method,
+		URL:         e.URL,
+		HTTPVersion: protoOrDefault(e.Proto),
+		Cookies:     harRequestCookies(e.RequestHeaders),
+		Headers:     harHeaders(e.RequestHeaders),
+		QueryString: harQuery(e.URL),
+		HeadersSize: -1,
+		BodySize:    len(e.RequestBody),
+	}
+	if len(e.RequestBody) > 0 {
+		entry.Request.PostData = &HARPostData{
+			MimeType: e.RequestHeaders.Get("Content-Type"),
+			Text:     string(e.RequestBody),
+		}
+	}
+
+	entry.Response = HARResponse{
+		Status:      e.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(e.Status, strconv.Itoa(e.StatusCode))),
+		HTTPVersion: protoOrDefault(e.ResponseProto),
+		Cookies:     harResponseCookies(e.ResponseHeaders),
+		Headers:     harHeaders(e.ResponseHeaders),
+		RedirectURL: e.ResponseHeaders.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(e.ResponseBody),
+		Content:     harContent(e.ResponseHeaders, e.ResponseBody),
+	}
+
+	if e.WebSocket {
+		entry.ResourceType = "websocket"
+		for _, m := range e.Messages {
+			frame := HARWebSocketFrame{
+				Type:   "receive",
+				Time:   float64(m.Time.UnixNano()) / float64(time.Second),
+				Opcode: m.Opcode,
+				Data:   string(m.Data),
+			}
+			if m.Sent {
+				frame.Type = "send"
+			}
+			// 与浏览器一致，二进制帧以base64编码
+			if m.Opcode == WSBinary || !utf8.Valid(m.Data) {
+				frame.Data = base64.StdEncoding.EncodeToString(m.Data)
+			}
+			entry.WebSocketMessages = append(entry.WebSocketMessages, frame)
+		}
+	}
+
+	return entry
+}
+
+// protoOrDefault 返回协议版本，为空时使用HTTP/1.1
+func protoOrDefault(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+	return proto
+}
+
+// harHeaders 按名称排序转换请求头
+func harHeaders(h http.Header) []HARNameValue {
+	list := make([]HARNameValue, 0, len(h))
+	for name, values := range h {
+		for _, v := range values {
+			list = append(list, HARNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// harQuery 解析URL中的查询参数
+func harQuery(rawURL string) []HARNameValue {
+	list := []HARNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return list
+	}
+	for name, values := range u.Query() {
+		for _, v := range values {
+			list = append(list, HARNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// harRequestCookies 解析请求中的Cookie
+func harRequestCookies(h http.Header) []HARCookie {
+	req := &http.Request{Header: h}
+	list := []HARCookie{}
+	for _, c := range req.Cookies() {
+		list = append(list, HARCookie{Name: c.Name, Value: c.Value})
+	}
+	return list
+}
+
+// harResponseCookies 解析响应中的Set-Cookie
+func harResponseCookies(h http.Header) []HARCookie {
+	resp := &http.Response{Header: h}
+	list := []HARCookie{}
+	for _, c := range resp.Cookies() {
+		list = append(list, HARCookie{Name: c.Name, Value: c.Value})
+	}
+	return list
+}
+
+// harContent 转换响应体，非UTF-8内容使用base64编码
+func harContent(h http.Header, body []byte) HARContent {
+	content := HARContent{
+		Size:     len(body),
+		MimeType: h.Get("Content-Type"),
+	}
+	if content.MimeType == "" {
+		content.MimeType = "application/octet-stream"
+	}
+
+	if utf8.Valid(body) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+
+	return content
+}
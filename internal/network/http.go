@@ -1,12 +1,14 @@
 package network
 
 import (
-	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"strings"
 	"time"
@@ -19,14 +21,38 @@ type HTTPClient interface {
 	Put(ctx context.Context, url string, body interface{}, headers map[string]string) (*HTTPResponse, error)
 	Delete(ctx context.Context, url string, headers map[string]string) (*HTTPResponse, error)
 	Do(req *http.Request) (*HTTPResponse, error)
+	// Stream 发送请求但不读取响应体，调用方通过BodyReader读取并负责关闭
+	Stream(req *http.Request) (*HTTPResponse, error)
 }
 
 // HTTPResponse 封装HTTP响应
+// Body 已按Content-Encoding解码，Headers保持服务器返回的原样
 type HTTPResponse struct {
 	StatusCode int
-	Headers    http.Header
-	Body       []byte
-	Request    *http.Request
+	// Proto 是实际使用的协议，如 "HTTP/1.1" 或 "HTTP/2.0"
+	Proto   string
+	Headers http.Header
+	Body    []byte
+	Request *http.Request
+	// Truncated 表示响应体超过MaxBodySize，Body只包含前MaxBodySize字节；
+	// 原始客户端中表示读取达到ReadLimit时该响应尚未接收完整
+	Truncated bool
+	// BodyReader 是流式模式下未读取的响应体，其他模式下为nil
+	BodyReader io.ReadCloser
+	// Duration 是从发送请求到读完响应体的耗时，流式模式下为收到响应头的耗时
+	Duration time.Duration
+	// TLS 是HTTPS连接的状态，包含对端证书链，明文请求时为nil
+	TLS *tls.ConnectionState
+	// Raw 是原始客户端收到的完整响应字节，普通请求时为nil
+	Raw []byte
+	// FinalURL 是跟随重定向后最终请求的URL
+	FinalURL string
+	// RedirectChain 是按顺序经过的重定向，未发生重定向时为空
+	RedirectChain []RedirectHop
+	// RemoteAddr 是最终响应所用连接的对端地址（IP:端口），经过代理时为代理的地址
+	RemoteAddr string
+	// Timing 是最终请求DNS解析、建立连接、TLS握手、首字节和总耗时的分解
+	Timing Timing
 }
 
 // HTTPClientConfig 配置HTTP客户端
@@ -36,14 +62,67 @@ type HTTPClientConfig struct {
 	RetryInterval  time.Duration
 	BaseURL        string
 	DefaultHeaders map[string]string
+
+	// MaxRetryInterval 限制指数退避的最大等待时间，Retry-After超过该值时不再重试
+	MaxRetryInterval time.Duration
+	// RetryOnStatus 指定需要重试的响应状态码，为空时只对网络错误重试
+	RetryOnStatus []int
+	// RetryNonIdempotent 允许对POST等非幂等请求重试
+	RetryNonIdempotent bool
+	// MaxReplayBodySize 限制为重试而缓存的不可Seek请求体大小，超过时该请求不再重试
+	MaxReplayBodySize int64
+	// MaxBodySize 限制读入内存的响应体大小，超出部分被丢弃并标记Truncated，0表示不限制
+	MaxBodySize int64
+
+	// Proxy 代理地址，支持 http、https、socks5 和 socks5h 协议，为空时直连
+	Proxy string
+	// NoProxy 不经过代理的主机列表，支持域名、域名后缀、IP和CIDR
+	NoProxy []string
+
+	// TLS 配置证书校验、客户端证书、SNI、版本和密码套件
+	TLS TLSConfig
+
+	// Protocol 指定协议版本：自动协商、HTTP/1.1、HTTP/2 或 h2c
+	Protocol Protocol
+
+	// Hosts 将主机名固定解析到指定IP，类似hosts文件，Host请求头和SNI保持不变
+	// 主机名以点开头时匹配所有子域名，同样作用于原始HTTP和套接字连接
+	Hosts map[string]string
+	// DNSServer 是解析主机名使用的DNS服务器，如 "8.8.8.8" 或 "10.0.0.2:5353"，为空时使用系统解析
+	DNSServer string
+
+	// CookieJar 为客户端启用Cookie Jar，保存未匹配会话的主机返回的Cookie
+	CookieJar bool
+	// Jar 是CookieJar为true时使用的Cookie Jar，为空时为客户端新建一个；
+	// 在多个客户端之间共享可使Cookie在多次执行之间保留
+	Jar http.CookieJar
+	// Sessions 按主机管理认证会话，可在多个客户端之间共享
+	Sessions *SessionStore
+	// Auth 按主机配置Basic、Digest、NTLM等认证方式，收到401质询时自动应答，可在多个客户端之间共享
+	Auth *AuthStore
+
+	// Redirect 控制是否跟随重定向及最大跳转次数，可通过WithRedirectPolicy按请求覆盖
+	Redirect RedirectPolicy
+
+	// Traffic 记录经过客户端的每一次HTTP交换，为空时不记录
+	Traffic *TrafficLog
+
+	// RateLimiter 限制全局和每个主机的请求速率与并发数，可在多个客户端之间共享，为空时不限制
+	RateLimiter *RateLimiter
+
+	// Cassette 录制经过客户端的HTTP交换或从录制中回放，为空时正常发送
+	Cassette *Cassette
 }
 
 // DefaultHTTPClientConfig 返回默认的HTTP客户端配置
 func DefaultHTTPClientConfig() HTTPClientConfig {
 	return HTTPClientConfig{
-		Timeout:       30 * time.Second,
-		MaxRetries:    3,
-		RetryInterval: 1 * time.Second,
+		Timeout:           30 * time.Second,
+		MaxRetries:        3,
+		RetryInterval:     1 * time.Second,
+		MaxRetryInterval:  30 * time.Second,
+		MaxReplayBodySize: 10 << 20,
+		MaxBodySize:       10 << 20,
 		DefaultHeaders: map[string]string{
 			"User-Agent": "Luna/1.0",
 		},
@@ -54,18 +133,55 @@ func DefaultHTTPClientConfig() HTTPClientConfig {
 type Client struct {
 	client *http.Client
 	config HTTPClientConfig
+	// err 保存创建客户端时的配置错误，在发送请求时返回
+	err error
 }
 
 // NewHTTPClient 创建一个新的HTTP客户端
+// 配置错误（如证书文件无法读取）会在发送请求时返回
 func NewHTTPClient(config HTTPClientConfig) *Client {
+	transport, err := newTransport(config)
+	if config.Cassette != nil {
+		transport = &cassetteTransport{next: transport, cassette: config.Cassette, limit: config.MaxBodySize}
+	}
+
+	var rt http.RoundTripper = &decodingTransport{next: transport}
+	if config.Traffic != nil {
+		rt = &recordingTransport{next: rt, log: config.Traffic}
+	}
+	// 限速在记录之外进行，记录的耗时不包含排队等待的时间
+	if config.RateLimiter != nil {
+		rt = &limitedTransport{next: rt, limiter: config.RateLimiter}
+	}
+	// 认证在最外层，质询握手中的每次请求都单独限速和记录
+	if config.Auth != nil {
+		rt = &authTransport{next: rt, store: config.Auth}
+	}
+
 	client := &http.Client{
-		Timeout: config.Timeout,
+		Timeout:   config.Timeout,
+		Transport: rt,
 	}
 
-	return &Client{
+	c := &Client{
 		client: client,
 		config: config,
+		err:    err,
+	}
+	client.CheckRedirect = c.checkRedirect
+
+	if config.CookieJar || config.Sessions != nil {
+		jar := &sessionJar{store: config.Sessions}
+		if config.CookieJar {
+			jar.fallback = config.Jar
+			if jar.fallback == nil {
+				jar.fallback, _ = cookiejar.New(nil)
+			}
+		}
+		client.Jar = jar
 	}
+
+	return c
 }
 
 // Get 发送GET请求
@@ -109,24 +225,142 @@ func (c *Client) Delete(ctx context.Context, urlStr string, headers map[string]s
 }
 
 // Do 执行HTTP请求
+// 目标主机存在会话时，自动登录、携带会话请求头，并在会话失效时重新登录后重发一次；
+// 重新登录失败时返回登录错误
 func (c *Client) Do(req *http.Request) (*HTTPResponse, error) {
-	resp, err := c.client.Do(req)
+	return c.do(req, false)
+}
+
+// Stream 执行HTTP请求并以流的形式返回响应体，适用于大文件下载
+// 流式请求不重试，也不受Timeout和MaxBodySize限制，由请求的上下文控制超时
+func (c *Client) Stream(req *http.Request) (*HTTPResponse, error) {
+	return c.do(req, true)
+}
+
+func (c *Client) do(req *http.Request, stream bool) (*HTTPResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	sess := c.config.Sessions.Match(req.URL.Hostname())
+	if sess == nil {
+		return c.send(req, stream)
+	}
+
+	ctx := req.Context()
+	login := isSessionLogin(ctx)
+	if !login {
+		if err := sess.ensure(ctx, c); err != nil {
+			return nil, err
+		}
+	}
+
+	retry := req.Clone(ctx)
+	applySessionHeaders(req, sess)
+	resp, err := c.send(req, stream)
+	if err != nil || login || sess.Login == nil || !sess.isExpireStatus(resp.StatusCode) {
+		return resp, err
+	}
+
+	// 会话已失效，重新登录后重发请求
+	sess.Invalidate()
+	if err := sess.ensure(ctx, c); err != nil {
+		if resp.BodyReader != nil {
+			resp.BodyReader.Close()
+		}
+		return nil, fmt.Errorf("会话失效（状态码 %d）后重新登录失败: %w", resp.StatusCode, err)
+	}
+	if req.GetBody == nil && req.Body != nil && req.Body != http.NoBody {
+		return resp, nil
+	}
+	if err := rewindBody(req, retry); err != nil {
+		return resp, nil
+	}
+	applySessionHeaders(retry, sess)
+
+	if resp.BodyReader != nil {
+		resp.BodyReader.Close()
+	}
+	return c.send(retry, stream)
+}
+
+// applySessionHeaders 添加会话请求头，不覆盖请求中已设置的值
+func applySessionHeaders(req *http.Request, sess *Session) {
+	for k, v := range sess.Headers() {
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
+	}
+}
+
+// send 发送请求并读取响应，stream为true时不读取响应体
+func (c *Client) send(req *http.Request, stream bool) (*HTTPResponse, error) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+
+	client := c.client
+	if stream {
+		// 流式读取可能持续很久，不使用整体超时
+		streamClient := *c.client
+		streamClient.Timeout = 0
+		client = &streamClient
+	}
+
+	trace := &requestTrace{}
+	req = req.WithContext(trace.withTrace(req.Context()))
+
+	start := time.Now()
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+
+	r := &HTTPResponse{
+		StatusCode:    resp.StatusCode,
+		Proto:         resp.Proto,
+		Headers:       resp.Header,
+		Request:       req,
+		TLS:           resp.TLS,
+		FinalURL:      resp.Request.URL.String(),
+		RedirectChain: redirectChain(resp),
+		RemoteAddr:    trace.remoteAddr(),
+	}
+
+	if stream {
+		r.BodyReader = resp.Body
+		r.Duration = time.Since(start)
+		r.Timing = trace.timing(r.Duration)
+		return r, nil
+	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	r.Body, r.Truncated, err = readBody(resp.Body, c.config.MaxBodySize)
 	if err != nil {
 		return nil, err
 	}
+	r.Duration = time.Since(start)
+	r.Timing = trace.timing(r.Duration)
+
+	return r, nil
+}
+
+// readBody 读取最多limit字节的响应体，超出时返回截断标记，limit为0表示不限制
+// 截断后不再读取剩余数据，连接随响应体关闭而释放
+func readBody(r io.Reader, limit int64) ([]byte, bool, error) {
+	if limit <= 0 {
+		body, err := io.ReadAll(r)
+		return body, false, err
+	}
 
-	return &HTTPResponse{
-		StatusCode: resp.StatusCode,
-		Headers:    resp.Header,
-		Body:       body,
-		Request:    req,
-	}, nil
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(body)) > limit {
+		return body[:limit], true, nil
+	}
+	return body, false, nil
 }
 
 // newRequest 创建一个新的HTTP请求
@@ -136,41 +370,58 @@ func (c *Client) newRequest(ctx context.Context, method, urlStr string, body int
 		urlStr = fmt.Sprintf("%s/%s", strings.TrimRight(c.config.BaseURL, "/"), strings.TrimLeft(urlStr, "/"))
 	}
 
-	var bodyReader io.Reader
+	// contentType 是请求体类型决定的默认Content-Type，可被headers覆盖
+	var rb *replayableBody
+	var contentType string
 	if body != nil {
 		switch v := body.(type) {
+		case RequestBody:
+			b, ct, err := v.Encode()
+			if err != nil {
+				return nil, err
+			}
+			rb = bytesBody(b)
+			contentType = ct
 		case string:
-			bodyReader = strings.NewReader(v)
+			rb = bytesBody([]byte(v))
 		case []byte:
-			bodyReader = bytes.NewReader(v)
+			rb = bytesBody(v)
 		case io.Reader:
-			bodyReader = v
+			var err error
+			rb, err = readerBody(v, c.config.MaxReplayBodySize)
+			if err != nil {
+				return nil, err
+			}
 		default:
 			b, err := json.Marshal(body)
 			if err != nil {
 				return nil, err
 			}
-			bodyReader = bytes.NewReader(b)
+			rb = bytesBody(b)
 			// 如果没有指定Content-Type，则默认为JSON
-			if headers == nil {
-				headers = make(map[string]string)
-			}
-			if _, ok := headers["Content-Type"]; !ok {
-				headers["Content-Type"] = "application/json"
-			}
+			contentType = "application/json"
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, urlStr, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	if rb != nil {
+		rb.apply(req)
+	}
+
 	// 添加默认请求头
 	for k, v := range c.config.DefaultHeaders {
 		req.Header.Set(k, v)
 	}
 
+	// 请求体的Content-Type优先于默认请求头，不修改调用方传入的headers
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
 	// 添加自定义请求头
 	for k, v := range headers {
 		req.Header.Set(k, v)
@@ -179,38 +430,6 @@ func (c *Client) newRequest(ctx context.Context, method, urlStr string, body int
 	return req, nil
 }
 
-// doWithRetry 执行HTTP请求并支持重试
-func (c *Client) doWithRetry(req *http.Request) (*HTTPResponse, error) {
-	var (
-		resp *HTTPResponse
-		err  error
-		try  int
-	)
-
-	for try = 0; try <= c.config.MaxRetries; try++ {
-		// 如果不是第一次尝试，则等待重试间隔
-		if try > 0 {
-			time.Sleep(c.config.RetryInterval)
-		}
-
-		// 创建请求的副本，因为原始请求的Body可能已经被消费
-		reqCopy := req.Clone(req.Context())
-		resp, err = c.Do(reqCopy)
-
-		// 如果请求成功或者是非临时性错误，则不再重试
-		if err == nil || !isTemporaryError(err) {
-			break
-		}
-	}
-
-	return resp, err
-}
-
-// isTemporaryError 判断错误是否为临时性错误
-func isTemporaryError(err error) bool {
-	return true
-}
-
 // ParseJSON 解析响应体为JSON
 func (r *HTTPResponse) ParseJSON(v interface{}) error {
 	return json.Unmarshal(r.Body, v)
@@ -221,6 +440,14 @@ func (r *HTTPResponse) String() string {
 	return string(r.Body)
 }
 
+// PeerCertificates 返回对端的证书链，明文请求时返回nil
+func (r *HTTPResponse) PeerCertificates() []*x509.Certificate {
+	if r.TLS == nil {
+		return nil
+	}
+	return r.TLS.PeerCertificates
+}
+
 // IsSuccess 判断响应是否成功
 func (r *HTTPResponse) IsSuccess() bool {
 	return r.StatusCode >= 200 && r.StatusCode < 300
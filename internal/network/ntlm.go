@@ -0,0 +1,243 @@
+package network
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// NTLM协商标志
+const (
+	ntlmNegotiateUnicode        = 0x00000001
+	ntlmRequestTarget           = 0x00000004
+	ntlmNegotiateNTLM           = 0x00000200
+	ntlmNegotiateAlwaysSign     = 0x00008000
+	ntlmNegotiateExtendedSecure = 0x00080000
+	ntlmNegotiateTargetInfo     = 0x00800000
+	ntlmNegotiate128            = 0x20000000
+	ntlmNegotiate56             = 0x80000000
+
+	ntlmNegotiateFlags = ntlmNegotiateUnicode | ntlmRequestTarget | ntlmNegotiateNTLM |
+		ntlmNegotiateAlwaysSign | ntlmNegotiateExtendedSecure | ntlmNegotiateTargetInfo |
+		ntlmNegotiate128 | ntlmNegotiate56
+)
+
+// ntlmAvTimestamp 是目标信息中服务器时间戳的AvId
+const ntlmAvTimestamp = 7
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// NTLMAuth 是NTLMv2认证
+// 握手的三条消息必须在同一条保持活动的连接上完成，authTransport为每次握手使用
+// 专用的单连接HTTP/1.1传输层；protocol 为 h2 或 h2c 时无法使用
+type NTLMAuth struct {
+	Domain      string
+	Username    string
+	Password    string
+	Workstation string
+}
+
+// Apply 实现Authenticator接口，发送NEGOTIATE消息开始握手
+func (a NTLMAuth) Apply(req *http.Request) error {
+	if req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "NTLM "+base64.StdEncoding.EncodeToString(ntlmNegotiateMessage()))
+	}
+	return nil
+}
+
+// Challenge 实现Authenticator接口，根据服务器的CHALLENGE消息计算AUTHENTICATE消息
+func (a NTLMAuth) Challenge(retry *http.Request, resp *http.Response) (bool, error) {
+	// 只应答NEGOTIATE之后的质询，AUTHENTICATE被拒绝说明凭据无效
+	prev := parseChallenges([]string{retry.Header.Get("Authorization")})
+	if len(prev) == 0 || !strings.EqualFold(prev[0].Scheme, "NTLM") {
+		return false, nil
+	}
+	if msg, err := decodeAuthToken(prev[0].Token); err != nil || ntlmMessageType(msg) != 1 {
+		return false, nil
+	}
+
+	var token string
+	for _, ch := range parseChallenges(resp.Header.Values("WWW-Authenticate")) {
+		if strings.EqualFold(ch.Scheme, "NTLM") && ch.Token != "" {
+			token = ch.Token
+			break
+		}
+	}
+	if token == "" {
+		return false, nil
+	}
+
+	data, err := decodeAuthToken(token)
+	if err != nil {
+		return false, fmt.Errorf("无效的NTLM质询: %w", err)
+	}
+	challenge, err := parseNTLMChallenge(data)
+	if err != nil {
+		return false, err
+	}
+
+	domain, user := a.Domain, a.Username
+	if i := strings.IndexByte(user, '\\'); i >= 0 && domain == "" {
+		domain, user = user[:i], user[i+1:]
+	}
+
+	clientChallenge := make([]byte, 8)
+	if _, err := rand.Read(clientChallenge); err != nil {
+		return false, err
+	}
+
+	msg := ntlmAuthenticateMessage(challenge, domain, user, a.Password, a.Workstation, clientChallenge, time.Now())
+	retry.Header.Set("Authorization", "NTLM "+base64.StdEncoding.EncodeToString(msg))
+	return true, nil
+}
+
+// ntlmChallenge 是服务器CHALLENGE消息中的字段
+type ntlmChallenge struct {
+	flags           uint32
+	serverChallenge []byte
+	targetInfo      []byte
+}
+
+// ntlmMessageType 返回NTLM消息的类型，无效消息返回0
+func ntlmMessageType(msg []byte) uint32 {
+	if len(msg) < 12 || !bytes.Equal(msg[:8], ntlmSignature) {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(msg[8:])
+}
+
+// ntlmNegotiateMessage 生成NEGOTIATE消息，不携带域和工作站
+func ntlmNegotiateMessage() []byte {
+	msg := make([]byte, 32)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], ntlmNegotiateFlags)
+	return msg
+}
+
+// parseNTLMChallenge 解析服务器的CHALLENGE消息
+func parseNTLMChallenge(msg []byte) (*ntlmChallenge, error) {
+	if ntlmMessageType(msg) != 2 || len(msg) < 32 {
+		return nil, fmt.Errorf("无效的NTLM质询消息")
+	}
+
+	c := &ntlmChallenge{
+		flags:           binary.LittleEndian.Uint32(msg[20:]),
+		serverChallenge: msg[24:32],
+	}
+
+	if len(msg) >= 48 {
+		n := int(binary.LittleEndian.Uint16(msg[40:]))
+		off := int(binary.LittleEndian.Uint32(msg[44:]))
+		if off+n > len(msg) {
+			return nil, fmt.Errorf("无效的NTLM目标信息")
+		}
+		c.targetInfo = msg[off : off+n]
+	}
+
+	return c, nil
+}
+
+// ntlmAuthenticateMessage 按NTLMv2计算应答并生成AUTHENTICATE消息
+func ntlmAuthenticateMessage(c *ntlmChallenge, domain, user, password, workstation string, clientChallenge []byte, now time.Time) []byte {
+	h := md4.New()
+	h.Write(utf16le(password))
+	ntHash := h.Sum(nil)
+	ntowf := hmacMD5(ntHash, utf16le(strings.ToUpper(user)+domain))
+
+	// 服务器提供时间戳时使用服务器时间，此时LMv2应答应为全零
+	timestamp, serverTime := ntlmTimestamp(c.targetInfo)
+	if !serverTime {
+		timestamp = make([]byte, 8)
+		ft := uint64(now.UnixNano()/100) + 116444736000000000
+		binary.LittleEndian.PutUint64(timestamp, ft)
+	}
+
+	var blob bytes.Buffer
+	blob.Write([]byte{1, 1, 0, 0, 0, 0, 0, 0})
+	blob.Write(timestamp)
+	blob.Write(clientChallenge)
+	blob.Write([]byte{0, 0, 0, 0})
+	blob.Write(c.targetInfo)
+	blob.Write([]byte{0, 0, 0, 0})
+
+	proof := hmacMD5(ntowf, append(append([]byte{}, c.serverChallenge...), blob.Bytes()...))
+	ntResponse := append(proof, blob.Bytes()...)
+
+	lmResponse := make([]byte, 24)
+	if !serverTime {
+		lm := hmacMD5(ntowf, append(append([]byte{}, c.serverChallenge...), clientChallenge...))
+		lmResponse = append(lm, clientChallenge...)
+	}
+
+	fields := [][]byte{
+		lmResponse,
+		ntResponse,
+		utf16le(domain),
+		utf16le(user),
+		utf16le(workstation),
+		nil, // 会话密钥
+	}
+
+	const headerSize = 64
+	msg := make([]byte, headerSize)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+
+	offset := headerSize
+	for i, field := range fields {
+		pos := 12 + i*8
+		binary.LittleEndian.PutUint16(msg[pos:], uint16(len(field)))
+		binary.LittleEndian.PutUint16(msg[pos+2:], uint16(len(field)))
+		binary.LittleEndian.PutUint32(msg[pos+4:], uint32(offset))
+		msg = append(msg, field...)
+		offset += len(field)
+	}
+
+	flags := c.flags&ntlmNegotiateFlags | ntlmNegotiateUnicode
+	binary.LittleEndian.PutUint32(msg[60:], flags)
+
+	return msg
+}
+
+// ntlmTimestamp 在目标信息中查找服务器时间戳
+func ntlmTimestamp(info []byte) ([]byte, bool) {
+	for len(info) >= 4 {
+		id := binary.LittleEndian.Uint16(info)
+		n := int(binary.LittleEndian.Uint16(info[2:]))
+		if id == 0 || len(info) < 4+n {
+			break
+		}
+		if id == ntlmAvTimestamp && n == 8 {
+			return info[4:12], true
+		}
+		info = info[4+n:]
+	}
+	return nil, false
+}
+
+func hmacMD5(key, data []byte) []byte {
+	m := hmac.New(md5.New, key)
+	m.Write(data)
+	return m.Sum(nil)
+}
+
+// utf16le 将字符串编码为UTF-16LE
+func utf16le(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(b[i*2:], u)
+	}
+	return b
+}
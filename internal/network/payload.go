@@ -0,0 +1,172 @@
+package network
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/seaung/Luna/pkg/helper"
+)
+
+// RequestBody 是能够自行编码并给出Content-Type的请求体
+// 作为Post、Put的body参数传入时，未显式指定Content-Type则使用编码返回的值
+type RequestBody interface {
+	Encode() (data []byte, contentType string, err error)
+}
+
+// FormField 是表单中的一个字段
+type FormField struct {
+	Name  string
+	Value string
+}
+
+// FormBody 是保持字段顺序的 application/x-www-form-urlencoded 请求体
+type FormBody struct {
+	Fields []FormField
+}
+
+// NewForm 根据交替出现的名称和值创建表单，如 NewForm("user", "admin", "pass", "123")
+func NewForm(pairs ...string) *FormBody {
+	f := &FormBody{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		f.Add(pairs[i], pairs[i+1])
+	}
+	return f
+}
+
+// Add 追加一个字段，同名字段可以重复出现
+func (f *FormBody) Add(name, value string) *FormBody {
+	f.Fields = append(f.Fields, FormField{Name: name, Value: value})
+	return f
+}
+
+// Encode 按添加顺序编码字段
+func (f *FormBody) Encode() ([]byte, string, error) {
+	var b strings.Builder
+	for i, field := range f.Fields {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(url.QueryEscape(field.Name))
+		b.WriteByte('=')
+		b.WriteString(url.QueryEscape(field.Value))
+	}
+	return []byte(b.String()), "application/x-www-form-urlencoded", nil
+}
+
+// MultipartPart 是multipart表单中的一个部分
+type MultipartPart struct {
+	Name string
+	// Filename 不为空时作为文件上传，原样写入Content-Disposition，不做转义
+	Filename string
+	// ContentType 为空时普通字段不写该头，文件使用 application/octet-stream
+	ContentType string
+	// Headers 是额外的部分头
+	Headers map[string]string
+	Data    []byte
+}
+
+// MultipartBody 是 multipart/form-data 请求体
+// 各部分按添加顺序原样写出，便于构造文件名、类型绕过等上传PoC
+type MultipartBody struct {
+	// Boundary 为空时自动生成
+	Boundary string
+	Parts    []MultipartPart
+}
+
+// NewMultipart 创建multipart表单
+func NewMultipart() *MultipartBody {
+	return &MultipartBody{}
+}
+
+// AddField 追加一个普通字段
+func (m *MultipartBody) AddField(name, value string) *MultipartBody {
+	m.Parts = append(m.Parts, MultipartPart{Name: name, Data: []byte(value)})
+	return m
+}
+
+// AddFile 追加一个文件，contentType为空时使用 application/octet-stream
+func (m *MultipartBody) AddFile(name, filename, contentType string, data []byte) *MultipartBody {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	m.Parts = append(m.Parts, MultipartPart{Name: name, Filename: filename, ContentType: contentType, Data: data})
+	return m
+}
+
+// Encode 编码所有部分
+func (m *MultipartBody) Encode() ([]byte, string, error) {
+	if m.Boundary == "" {
+		m.Boundary = "----LunaFormBoundary" + helper.RandomString(16)
+	}
+
+	var buf bytes.Buffer
+	for _, p := range m.Parts {
+		fmt.Fprintf(&buf, "--%s\r\n", m.Boundary)
+		fmt.Fprintf(&buf, `Content-Disposition: form-data; name="%s"`, p.Name)
+		if p.Filename != "" {
+			fmt.Fprintf(&buf, `; filename="%s"`, p.Filename)
+		}
+		buf.WriteString("\r\n")
+		if p.ContentType != "" {
+			fmt.Fprintf(&buf, "Content-Type: %s\r\n", p.ContentType)
+		}
+		names := make([]string, 0, len(p.Headers))
+		for k := range p.Headers {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			fmt.Fprintf(&buf, "%s: %s\r\n", k, p.Headers[k])
+		}
+		buf.WriteString("\r\n")
+		buf.Write(p.Data)
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", m.Boundary)
+
+	return buf.Bytes(), "multipart/form-data; boundary=" + m.Boundary, nil
+}
+
+// XMLBody 是XML请求体
+type XMLBody struct {
+	// Value 为string或[]byte时原样发送，其余值使用encoding/xml编码并加上XML声明
+	Value interface{}
+	// ContentType 为空时使用 application/xml
+	ContentType string
+}
+
+// Encode 编码XML
+func (x *XMLBody) Encode() ([]byte, string, error) {
+	contentType := x.ContentType
+	if contentType == "" {
+		contentType = "application/xml"
+	}
+
+	switch v := x.Value.(type) {
+	case string:
+		return []byte(v), contentType, nil
+	case []byte:
+		return v, contentType, nil
+	}
+
+	data, err := xml.Marshal(x.Value)
+	if err != nil {
+		return nil, "", err
+	}
+	return append([]byte(xml.Header), data...), contentType, nil
+}
+
+// RawBody 是带显式Content-Type的原始请求体
+type RawBody struct {
+	ContentType string
+	Data        []byte
+}
+
+// Encode 原样返回数据
+func (r *RawBody) Encode() ([]byte, string, error) {
+	return r.Data, r.ContentType, nil
+}
@@ -0,0 +1,179 @@
+package network
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFormBody(t *testing.T) {
+	tests := []struct {
+		name string
+		form *FormBody
+		want string
+	}{
+		{"order kept", NewForm("z", "1", "a", "2", "m", "3"), "z=1&a=2&m=3"},
+		{"duplicates", NewForm("id", "1", "id", "2"), "id=1&id=2"},
+		{"escaped", NewForm("q", "a b&c=d", "名", "值"), "q=a+b%26c%3Dd&%E5%90%8D=%E5%80%BC"},
+		{"odd pair ignored", NewForm("user", "admin", "dangling"), "user=admin"},
+		{"chained add", NewForm().Add("b", "").Add("a", "x"), "b=&a=x"},
+		{"empty", NewForm(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, contentType, err := tt.form.Encode()
+			if err != nil || string(data) != tt.want || contentType != "application/x-www-form-urlencoded" {
+				t.Errorf("Encode = %q, %q, %v, want %q", data, contentType, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultipartBody(t *testing.T) {
+	m := &MultipartBody{Boundary: "XyZ"}
+	m.AddField("token", "abc").
+		AddFile("upload", "shell.php", "image/png", []byte("<?php ?>")).
+		AddFile("raw", "data.bin", "", []byte{0, 1})
+	m.Parts = append(m.Parts, MultipartPart{
+		Name:    "meta",
+		Headers: map[string]string{"X-B": "2", "X-A": "1"},
+		Data:    []byte("{}"),
+	})
+
+	data, contentType, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 各部分按添加顺序原样写出，额外的部分头按名称排序
+	want := "--XyZ\r\n" +
+		"Content-Disposition: form-data; name=\"token\"\r\n\r\nabc\r\n" +
+		"--XyZ\r\n" +
+		"Content-Disposition: form-data; name=\"upload\"; filename=\"shell.php\"\r\nContent-Type: image/png\r\n\r\n<?php ?>\r\n" +
+		"--XyZ\r\n" +
+		"Content-Disposition: form-data; name=\"raw\"; filename=\"data.bin\"\r\nContent-Type: application/octet-stream\r\n\r\n\x00\x01\r\n" +
+		"--XyZ\r\n" +
+		"Content-Disposition: form-data; name=\"meta\"\r\nX-A: 1\r\nX-B: 2\r\n\r\n{}\r\n" +
+		"--XyZ--\r\n"
+	if string(data) != want {
+		t.Errorf("Encode =\n%q\nwant\n%q", data, want)
+	}
+	if contentType != "multipart/form-data; boundary=XyZ" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+
+	// 自动生成的边界可被标准库解析
+	auto := NewMultipart().AddField("a", "1").AddFile("f", "x.txt", "text/plain", []byte("hello"))
+	data, contentType, err = auto.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || !strings.HasPrefix(params["boundary"], "----LunaFormBoundary") {
+		t.Fatalf("Content-Type = %q, %v", contentType, err)
+	}
+	form, err := multipart.NewReader(strings.NewReader(string(data)), params["boundary"]).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form.Value["a"][0] != "1" || form.File["f"][0].Filename != "x.txt" || form.File["f"][0].Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("parsed form = %+v %+v", form.Value, form.File)
+	}
+	// 再次编码使用相同的边界
+	if _, again, _ := auto.Encode(); again != contentType {
+		t.Errorf("boundary changed: %q, %q", contentType, again)
+	}
+}
+
+func TestXMLBody(t *testing.T) {
+	type user struct {
+		XMLName struct{} `xml:"user"`
+		Name    string   `xml:"name"`
+	}
+
+	tests := []struct {
+		name        string
+		body        *XMLBody
+		want        string
+		contentType string
+	}{
+		{"string", &XMLBody{Value: `<!DOCTYPE x [<!ENTITY e SYSTEM "file:///etc/passwd">]><x>&e;</x>`},
+			`<!DOCTYPE x [<!ENTITY e SYSTEM "file:///etc/passwd">]><x>&e;</x>`, "application/xml"},
+		{"bytes", &XMLBody{Value: []byte("<a/>"), ContentType: "text/xml"}, "<a/>", "text/xml"},
+		{"struct", &XMLBody{Value: user{Name: "a<b"}},
+			"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<user><name>a&lt;b</name></user>", "application/xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, contentType, err := tt.body.Encode()
+			if err != nil || string(data) != tt.want || contentType != tt.contentType {
+				t.Errorf("Encode = %q, %q, %v, want %q, %q", data, contentType, err, tt.want, tt.contentType)
+			}
+		})
+	}
+
+	if _, _, err := (&XMLBody{Value: make(chan int)}).Encode(); err == nil {
+		t.Error("unencodable value accepted")
+	}
+}
+
+func TestRequestBodyContentType(t *testing.T) {
+	type seen struct {
+		contentType string
+		body        string
+	}
+	requests := make(chan seen, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- seen{r.Header.Get("Content-Type"), string(body)}
+	}))
+	defer srv.Close()
+
+	config := DefaultHTTPClientConfig()
+	config.DefaultHeaders = map[string]string{"Content-Type": "text/plain"}
+	c := NewHTTPClient(config)
+
+	tests := []struct {
+		name        string
+		body        interface{}
+		headers     map[string]string
+		contentType string
+		data        string
+	}{
+		{"form", NewForm("b", "2", "a", "1"), nil, "application/x-www-form-urlencoded", "b=2&a=1"},
+		{"multipart", &MultipartBody{Boundary: "B"}, nil, "multipart/form-data; boundary=B", "--B--\r\n"},
+		{"xml", &XMLBody{Value: "<a/>"}, nil, "application/xml", "<a/>"},
+		{"raw", &RawBody{ContentType: "application/vnd.api+json", Data: []byte("{}")}, nil, "application/vnd.api+json", "{}"},
+		{"raw without type", &RawBody{Data: []byte("x")}, nil, "text/plain", "x"},
+		{"json", map[string]int{"a": 1}, nil, "application/json", `{"a":1}`},
+		{"string keeps default", "plain", nil, "text/plain", "plain"},
+		{"header overrides", NewForm("a", "1"), map[string]string{"content-type": "application/json"}, "application/json", "a=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Post(context.Background(), srv.URL, tt.body, tt.headers); err != nil {
+				t.Fatal(err)
+			}
+			got := <-requests
+			if got.contentType != tt.contentType || got.body != tt.data {
+				t.Errorf("server saw %q %q, want %q %q", got.contentType, got.body, tt.contentType, tt.data)
+			}
+		})
+	}
+
+	// 调用方的headers不被修改，复用于不同类型的请求体时各自使用正确的Content-Type
+	headers := map[string]string{"X-Test": "1"}
+	c.Post(context.Background(), srv.URL, NewForm("a", "1"), headers)
+	<-requests
+	c.Put(context.Background(), srv.URL, &MultipartBody{Boundary: "B"}, headers)
+	if got := <-requests; got.contentType != "multipart/form-data; boundary=B" {
+		t.Errorf("reused headers sent Content-Type %q", got.contentType)
+	}
+	if len(headers) != 1 {
+		t.Errorf("headers modified: %v", headers)
+	}
+}
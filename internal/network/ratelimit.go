@@ -0,0 +1,407 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateWindow 是统计实际请求速率的时间窗口
+const rateWindow = 10 * time.Second
+
+// RateLimit 描述速率和并发限制，字段为0表示不限制
+type RateLimit struct {
+	// RPS 每秒允许的请求数
+	RPS float64
+	// Burst 令牌桶容量，为0时取 max(1, RPS)
+	Burst int
+	// MaxConns 最大并发请求数
+	MaxConns int
+}
+
+// ParseRateLimit 解析 "<rps>[/<max_conns>]" 形式的限制，如 "5"、"5/2"、"0/4"
+func ParseRateLimit(value string) (RateLimit, error) {
+	rps, conns, hasConns := strings.Cut(strings.TrimSpace(value), "/")
+
+	var limit RateLimit
+	var err error
+	if limit.RPS, err = strconv.ParseFloat(rps, 64); err != nil || limit.RPS < 0 {
+		return RateLimit{}, fmt.Errorf("无效的速率: %s", rps)
+	}
+	if hasConns {
+		if limit.MaxConns, err = strconv.Atoi(conns); err != nil || limit.MaxConns < 0 {
+			return RateLimit{}, fmt.Errorf("无效的并发数: %s", conns)
+		}
+	}
+
+	return limit, nil
+}
+
+// String 返回限制的可读表示
+func (l RateLimit) String() string {
+	rps, conns := "不限", "不限"
+	if l.RPS > 0 {
+		rps = strconv.FormatFloat(l.RPS, 'f', -1, 64) + "/s"
+	}
+	if l.MaxConns > 0 {
+		conns = strconv.Itoa(l.MaxConns)
+	}
+	return fmt.Sprintf("速率 %s, 并发 %s", rps, conns)
+}
+
+// limiterState 是单个令牌桶和并发计数
+type limiterState struct {
+	mu     sync.Mutex
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+	// wake 在释放并发名额或修改限制时关闭，唤醒等待名额的请求
+	wake chan struct{}
+
+	inFlight int
+	total    int
+	recent   []time.Time
+}
+
+func newLimiterState(limit RateLimit) *limiterState {
+	st := &limiterState{}
+	st.setLimit(limit)
+	return st
+}
+
+// setLimit 更新限制，保留已有统计
+func (st *limiterState) setLimit(limit RateLimit) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if limit == st.limit && st.last != (time.Time{}) {
+		return
+	}
+
+	st.limit = limit
+	st.tokens = float64(st.burst())
+	st.last = time.Now()
+	// 并发上限按当前占用的名额计算，调大后等待中的请求立即重试，调小时已发出的请求不受影响
+	st.notify()
+}
+
+// notify 唤醒所有等待并发名额的请求，调用方需持有锁
+func (st *limiterState) notify() {
+	if st.wake != nil {
+		close(st.wake)
+	}
+	st.wake = make(chan struct{})
+}
+
+// burst 返回令牌桶容量，调用方需持有锁
+func (st *limiterState) burst() int {
+	if st.limit.Burst > 0 {
+		return st.limit.Burst
+	}
+	return int(math.Max(1, math.Ceil(st.limit.RPS)))
+}
+
+// wait 获取一个令牌，必要时等待，返回的函数用于退还令牌
+func (st *limiterState) wait(ctx context.Context) (func(), error) {
+	st.mu.Lock()
+	if st.limit.RPS <= 0 {
+		st.mu.Unlock()
+		return func() {}, nil
+	}
+
+	now := time.Now()
+	st.tokens = math.Min(float64(st.burst()), st.tokens+now.Sub(st.last).Seconds()*st.limit.RPS)
+	st.last = now
+
+	// 令牌不足时预留令牌并计算等待时间
+	st.tokens--
+	var delay time.Duration
+	if st.tokens < 0 {
+		delay = time.Duration(-st.tokens / st.limit.RPS * float64(time.Second))
+	}
+	st.mu.Unlock()
+
+	refund := func() {
+		st.mu.Lock()
+		st.tokens++
+		st.mu.Unlock()
+	}
+	if err := sleepContext(ctx, delay); err != nil {
+		refund()
+		return nil, err
+	}
+	return refund, nil
+}
+
+// acquireConn 获取并发名额，占用数达到上限时等待其他请求释放
+func (st *limiterState) acquireConn(ctx context.Context) (func(), error) {
+	st.mu.Lock()
+	for st.limit.MaxConns > 0 && st.inFlight >= st.limit.MaxConns {
+		wake := st.wake
+		st.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		st.mu.Lock()
+	}
+
+	st.inFlight++
+	st.total++
+	now := time.Now()
+	st.recent = append(st.recent, now)
+	st.trimRecent(now)
+	st.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			st.mu.Lock()
+			st.inFlight--
+			st.notify()
+			st.mu.Unlock()
+		})
+	}, nil
+}
+
+// trimRecent 丢弃统计窗口之外的请求时间，调用方需持有锁
+func (st *limiterState) trimRecent(now time.Time) {
+	i := 0
+	for i < len(st.recent) && now.Sub(st.recent[i]) > rateWindow {
+		i++
+	}
+	st.recent = st.recent[i:]
+}
+
+// LimiterStats 是限速器的当前状态
+type LimiterStats struct {
+	// Scope 为 "*" 表示全局，否则为主机名
+	Scope    string
+	Limit    RateLimit
+	InFlight int
+	Total    int
+	// CurrentRPS 是最近10秒的平均请求速率
+	CurrentRPS float64
+}
+
+func (st *limiterState) stats(scope string) LimiterStats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.trimRecent(time.Now())
+	return LimiterStats{
+		Scope:      scope,
+		Limit:      st.limit,
+		InFlight:   st.inFlight,
+		Total:      st.total,
+		CurrentRPS: float64(len(st.recent)) / rateWindow.Seconds(),
+	}
+}
+
+// RateLimiter 在全局和每个主机两个层面限制请求速率和并发数
+// 可在多个客户端之间共享，使所有插件共同遵守同一限制
+type RateLimiter struct {
+	mu          sync.Mutex
+	global      *limiterState
+	hostDefault RateLimit
+	hostRules   map[string]RateLimit
+	hosts       map[string]*limiterState
+}
+
+// NewRateLimiter 创建一个限速器
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		global:    newLimiterState(RateLimit{}),
+		hostRules: make(map[string]RateLimit),
+		hosts:     make(map[string]*limiterState),
+	}
+}
+
+// SetGlobal 设置所有请求共同遵守的限制
+func (l *RateLimiter) SetGlobal(limit RateLimit) {
+	l.global.setLimit(limit)
+}
+
+// SetHostDefault 设置每个主机各自遵守的默认限制
+func (l *RateLimiter) SetHostDefault(limit RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hostDefault = limit
+	for host, st := range l.hosts {
+		st.setLimit(l.hostLimit(host))
+	}
+}
+
+// SetHost 为指定主机设置限制，以点开头时匹配所有子域名
+func (l *RateLimiter) SetHost(pattern string, limit RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hostRules[strings.ToLower(pattern)] = limit
+	for host, st := range l.hosts {
+		st.setLimit(l.hostLimit(host))
+	}
+}
+
+// RemoveHost 删除指定主机的限制
+func (l *RateLimiter) RemoveHost(pattern string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pattern = strings.ToLower(pattern)
+	if _, ok := l.hostRules[pattern]; !ok {
+		return false
+	}
+	delete(l.hostRules, pattern)
+	for host, st := range l.hosts {
+		st.setLimit(l.hostLimit(host))
+	}
+	return true
+}
+
+// hostLimit 查找主机适用的限制，调用方需持有锁
+func (l *RateLimiter) hostLimit(host string) RateLimit {
+	if limit, ok := l.hostRules[host]; ok {
+		return limit
+	}
+
+	// 最长的后缀规则优先
+	best, bestLen := l.hostDefault, 0
+	for pattern, limit := range l.hostRules {
+		if strings.HasPrefix(pattern, ".") && (host == pattern[1:] || strings.HasSuffix(host, pattern)) && len(pattern) > bestLen {
+			best, bestLen = limit, len(pattern)
+		}
+	}
+	return best
+}
+
+// state 返回主机的限速状态
+func (l *RateLimiter) state(host string) *limiterState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	host = strings.ToLower(host)
+	st, ok := l.hosts[host]
+	if !ok {
+		st = newLimiterState(l.hostLimit(host))
+		l.hosts[host] = st
+	}
+	return st
+}
+
+// Acquire 等待直到允许向主机发送请求，返回的函数用于释放并发名额
+// 先占用并发名额再获取令牌，等待名额的请求不预留令牌，名额释放后也不会突破速率限制
+func (l *RateLimiter) Acquire(ctx context.Context, host string) (func(), error) {
+	hostState := l.state(host)
+
+	// 先获取主机名额再获取全局名额，等待繁忙主机的请求不占用全局名额
+	releaseHost, err := hostState.acquireConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	releaseGlobal, err := l.global.acquireConn(ctx)
+	if err != nil {
+		releaseHost()
+		return nil, err
+	}
+	release := func() {
+		releaseHost()
+		releaseGlobal()
+	}
+
+	refundHost, err := hostState.wait(ctx)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if _, err := l.global.wait(ctx); err != nil {
+		refundHost()
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// Stats 返回全局和各主机的限速状态
+func (l *RateLimiter) Stats() []LimiterStats {
+	stats := []LimiterStats{l.global.stats("*")}
+
+	l.mu.Lock()
+	hosts := make([]string, 0, len(l.hosts))
+	for host := range l.hosts {
+		hosts = append(hosts, host)
+	}
+	l.mu.Unlock()
+
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		stats = append(stats, l.state(host).stats(host))
+	}
+	return stats
+}
+
+// HostRules 返回为指定主机配置的限制
+func (l *RateLimiter) HostRules() map[string]RateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rules := make(map[string]RateLimit, len(l.hostRules))
+	for k, v := range l.hostRules {
+		rules[k] = v
+	}
+	return rules
+}
+
+// limitedTransport 在发送请求前等待限速器放行，响应体关闭后释放并发名额
+type limitedTransport struct {
+	next    http.RoundTripper
+	limiter *RateLimiter
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.Acquire(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releaseBody 在响应体读取完毕或关闭时释放并发名额
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.release()
+	}
+	return n, err
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
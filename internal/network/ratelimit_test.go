@@ -0,0 +1,197 @@
+package network
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+// tryAcquire 在timeout内尝试获取名额
+func tryAcquire(l *RateLimiter, host string, timeout time.Duration) (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return l.Acquire(ctx, host)
+}
+
+func TestBusyHostDoesNotStarveOthers(t *testing.T) {
+	l := NewRateLimiter()
+	l.SetGlobal(RateLimit{MaxConns: 2})
+	l.SetHostDefault(RateLimit{MaxConns: 1})
+
+	release, err := tryAcquire(l, "a.example.com", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	// 等待a.example.com的请求不应占用全局名额
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		go l.Acquire(ctx, "a.example.com")
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	other, err := tryAcquire(l, "b.example.com", 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("b.example.com starved: %v", err)
+	}
+	other()
+}
+
+func TestShrinkMaxConnsWhileInFlight(t *testing.T) {
+	l := NewRateLimiter()
+	l.SetGlobal(RateLimit{MaxConns: 4})
+
+	var releases []func()
+	for i := 0; i < 4; i++ {
+		r, err := tryAcquire(l, "example.com", time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, r)
+	}
+
+	l.SetGlobal(RateLimit{MaxConns: 2})
+
+	// 释放两个后仍有2个在途，等于新上限，不能再获取
+	releases[0]()
+	releases[1]()
+	if r, err := tryAcquire(l, "example.com", 50*time.Millisecond); err == nil {
+		r()
+		t.Fatal("acquired beyond the reduced cap")
+	}
+
+	releases[2]()
+	r, err := tryAcquire(l, "example.com", time.Second)
+	if err != nil {
+		t.Fatalf("acquire below the reduced cap: %v", err)
+	}
+	r()
+	releases[3]()
+
+	if got := l.Stats()[0].InFlight; got != 0 {
+		t.Errorf("in flight = %d, want 0", got)
+	}
+}
+
+func TestGrowMaxConnsWakesWaiters(t *testing.T) {
+	l := NewRateLimiter()
+	l.SetGlobal(RateLimit{MaxConns: 1})
+
+	release, err := tryAcquire(l, "example.com", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	done := make(chan error, 1)
+	go func() {
+		r, err := tryAcquire(l, "example.com", time.Second)
+		if err == nil {
+			r()
+		}
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	l.SetGlobal(RateLimit{MaxConns: 2})
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("waiter not admitted after raising the cap: %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("waiter still blocked after raising the cap")
+	}
+}
+
+func TestHostLimitLongestSuffix(t *testing.T) {
+	l := NewRateLimiter()
+	l.SetHost(".example.com", RateLimit{RPS: 10})
+	l.SetHost(".api.example.com", RateLimit{RPS: 1})
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if got := l.hostLimit("v1.api.example.com").RPS; got != 1 {
+		t.Errorf("rps = %v, want 1 from the longer suffix", got)
+	}
+	if got := l.hostLimit("www.example.com").RPS; got != 10 {
+		t.Errorf("rps = %v, want 10", got)
+	}
+}
+
+func TestQueuedRequestsKeepRateAfterConnsFree(t *testing.T) {
+	l := NewRateLimiter()
+	l.SetGlobal(RateLimit{RPS: 10, Burst: 1, MaxConns: 3})
+
+	var holders []func()
+	for i := 0; i < 3; i++ {
+		r, err := tryAcquire(l, "example.com", 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		holders = append(holders, r)
+	}
+
+	// 等待名额的请求不应提前消耗令牌，名额同时释放后仍按速率依次放行
+	times := make(chan time.Time, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			r, err := tryAcquire(l, "example.com", 3*time.Second)
+			if err != nil {
+				times <- time.Time{}
+				return
+			}
+			times <- time.Now()
+			r()
+		}()
+	}
+	time.Sleep(600 * time.Millisecond)
+	for _, r := range holders {
+		r()
+	}
+
+	var got []time.Time
+	for i := 0; i < 3; i++ {
+		ts := <-times
+		if ts.IsZero() {
+			t.Fatal("queued request failed")
+		}
+		got = append(got, ts)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Before(got[j]) })
+	for i := 1; i < len(got); i++ {
+		if gap := got[i].Sub(got[i-1]); gap < 80*time.Millisecond {
+			t.Errorf("requests %d and %d sent %s apart, want about 100ms at 10 rps", i-1, i, gap)
+		}
+	}
+}
+
+func TestFailedAcquireRefundsTokens(t *testing.T) {
+	l := NewRateLimiter()
+	l.SetHostDefault(RateLimit{RPS: 1})
+	l.SetGlobal(RateLimit{RPS: 1})
+
+	// 耗尽全局令牌，下一次获取在等待全局令牌时超时
+	l.global.mu.Lock()
+	l.global.tokens = -5
+	l.global.mu.Unlock()
+
+	if _, err := tryAcquire(l, "example.com", 20*time.Millisecond); err == nil {
+		t.Fatal("acquire should time out waiting for the global token")
+	}
+
+	st := l.state("example.com")
+	st.mu.Lock()
+	tokens := st.tokens
+	st.mu.Unlock()
+	if tokens < 0.99 {
+		t.Errorf("host tokens = %.2f after failed acquire, want the reserved token refunded", tokens)
+	}
+	if got := st.stats("example.com").InFlight; got != 0 {
+		t.Errorf("host in flight = %d, want 0", got)
+	}
+}
@@ -0,0 +1,538 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRawReadLimit 是原始请求默认读取的最大响应字节数
+const defaultRawReadLimit = 10 << 20
+
+// RawRequest 描述一个按字节原样发送的HTTP请求
+type RawRequest struct {
+	// Addr 目标地址，格式为 host:port
+	Addr string
+	// TLS 是否使用TLS连接
+	TLS bool
+	// ServerName 覆盖TLS握手使用的SNI
+	ServerName string
+	// Data 原样写入连接的数据，可以包含多个流水线请求
+	Data []byte
+	// Timeout 整个交换的超时时间，为0时使用客户端配置的超时
+	Timeout time.Duration
+	// ReadLimit 读取响应的最大字节数，为0时使用默认值
+	ReadLimit int64
+	// ExpectResponses 收到指定数量的完整响应后立即返回，为0时读取到连接关闭或超时
+	ExpectResponses int
+	// IdleTimeout 已收到完整响应后，连接空闲超过该时间即返回，为0时不启用
+	IdleTimeout time.Duration
+}
+
+// NewRawRequest 根据目标URL创建原始请求，自动推断地址和是否使用TLS
+func NewRawRequest(target string, data []byte) (*RawRequest, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+
+	useTLS := u.Scheme == "https"
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if useTLS {
+			port = "443"
+		}
+	}
+
+	return &RawRequest{
+		Addr: net.JoinHostPort(u.Hostname(), port),
+		TLS:  useTLS,
+		Data: data,
+	}, nil
+}
+
+// RawClient 通过TCP/TLS连接原样发送HTTP请求，用于构造畸形请求和请求走私类PoC
+type RawClient struct {
+	config HTTPClientConfig
+	dialer *Dialer
+	tags   Tags
+}
+
+// NewRawClient 创建一个原始HTTP客户端，与HTTP客户端共享代理和TLS配置
+func NewRawClient(config HTTPClientConfig) *RawClient {
+	return &RawClient{
+		config: config,
+		dialer: NewDialer(config),
+	}
+}
+
+// WithTags 返回使用指定流量标记的客户端副本
+func (c *RawClient) WithTags(tags Tags) *RawClient {
+	cp := *c
+	cp.tags = tags
+	return &cp
+}
+
+// Send 发送原始请求并宽松地解析返回的所有响应
+// 即使读取出错，也会返回已解析出的响应
+func (c *RawClient) Send(ctx context.Context, req *RawRequest) (responses []*HTTPResponse, err error) {
+	var recv []byte
+	if entry := c.rawEntry("HTTP/1.1", req.Addr, req.TLS); entry != nil {
+		defer func() { c.recordRaw(entry, req.Data, recv, responses, err) }()
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = c.config.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if c.config.RateLimiter != nil {
+		host, _, _ := net.SplitHostPort(req.Addr)
+		release, err := c.config.RateLimiter.Acquire(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	// 建立连接的各阶段耗时由httptrace钩子记录
+	trace := &requestTrace{}
+	trace.begin()
+	ctx = trace.withTrace(ctx)
+	begin := time.Now()
+
+	var conn net.Conn
+	if req.TLS {
+		conn, err = c.dialer.DialTLSContext(ctx, "tcp", req.Addr, req.ServerName)
+	} else {
+		conn, err = c.dialer.DialContext(ctx, "tcp", req.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// 上下文取消时关闭连接以中断阻塞的读写
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	start := time.Now()
+	if _, err := conn.Write(req.Data); err != nil {
+		return nil, fmt.Errorf("发送原始请求失败: %w", err)
+	}
+
+	methods := requestMethods(req.Data)
+	data, truncated, err := c.readResponses(ctx, conn, req, methods, trace)
+	recv = data
+	elapsed := time.Since(start)
+	timing := trace.timing(time.Since(begin))
+	responses, complete := parseResponses(data, methods)
+	if err != nil && len(responses) == 0 {
+		return nil, err
+	}
+	// 达到读取上限时，最后一个未接收完整的响应被截断
+	if truncated && !complete && len(responses) > 0 {
+		responses[len(responses)-1].Truncated = true
+	}
+
+	// 流水线中的响应无法单独计时，均记为整个交换的耗时
+	for _, resp := range responses {
+		resp.Duration = elapsed
+		resp.RemoteAddr = conn.RemoteAddr().String()
+		resp.Timing = timing
+	}
+
+	return responses, nil
+}
+
+// rawEntry 创建原始交换的流量记录，未启用流量记录时返回nil
+func (c *RawClient) rawEntry(proto, addr string, useTLS bool) *TrafficEntry {
+	if c.config.Traffic == nil {
+		return nil
+	}
+
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	return &TrafficEntry{
+		Tags:      c.tags,
+		StartedAt: time.Now(),
+		Socket:    true,
+		Method:    "RAW",
+		URL:       scheme + "://" + addr,
+		Proto:     proto,
+	}
+}
+
+// recordRaw 填写收发的数据和结果后添加记录
+// 原始请求可能是畸形或流水线请求，无法按HTTP交换描述，因此与套接字会话一样记录收发的原始字节
+func (c *RawClient) recordRaw(e *TrafficEntry, sent, recv []byte, responses []*HTTPResponse, err error) {
+	log := c.config.Traffic
+	e.Duration = time.Since(e.StartedAt)
+
+	sentBuf := &limitedBuffer{limit: log.bodyLimit}
+	sentBuf.Write(sent)
+	recvBuf := &limitedBuffer{limit: log.bodyLimit}
+	recvBuf.Write(recv)
+	e.RequestBody = sentBuf.Bytes()
+	e.ResponseBody = recvBuf.Bytes()
+	e.Truncated = sentBuf.truncated || recvBuf.truncated
+
+	e.Status = fmt.Sprintf("发送 %d 字节, 接收 %d 字节, 解析出 %d 个响应", len(sent), len(recv), len(responses))
+	if len(responses) > 0 {
+		e.StatusCode = responses[0].StatusCode
+		e.RemoteAddr = responses[0].RemoteAddr
+		e.Timing = responses[0].Timing
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	log.Add(e)
+}
+
+// readResponses 读取响应数据，直到满足预期数量、连接关闭、空闲、超时或达到读取上限
+// 收到首字节时记录到trace；达到读取上限并丢弃了数据时truncated为true
+func (c *RawClient) readResponses(ctx context.Context, conn net.Conn, req *RawRequest, methods []string, trace *requestTrace) (data []byte, truncated bool, err error) {
+	limit := req.ReadLimit
+	if limit <= 0 {
+		limit = defaultRawReadLimit
+	}
+
+	var buf bytes.Buffer
+	chunk := make([]byte, 32*1024)
+
+	for {
+		if req.IdleTimeout > 0 && buf.Len() > 0 {
+			idle := time.Now().Add(req.IdleTimeout)
+			if deadline, ok := ctx.Deadline(); !ok || idle.Before(deadline) {
+				conn.SetReadDeadline(idle)
+			}
+		}
+
+		n, err := conn.Read(chunk)
+		if n > 0 {
+			trace.gotFirstByte()
+		}
+		if remain := limit - int64(buf.Len()); int64(n) > remain {
+			n = int(remain)
+			truncated = true
+		}
+		buf.Write(chunk[:n])
+
+		if req.ExpectResponses > 0 && countCompleteResponses(buf.Bytes(), methods) >= req.ExpectResponses {
+			return buf.Bytes(), false, nil
+		}
+		if truncated {
+			return buf.Bytes(), true, nil
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return buf.Bytes(), false, nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && buf.Len() > 0 {
+				return buf.Bytes(), false, nil
+			}
+			if ctx.Err() != nil {
+				return buf.Bytes(), false, ctx.Err()
+			}
+			return buf.Bytes(), false, err
+		}
+	}
+}
+
+// ParseRawResponses 宽松地解析原始响应数据，支持流水线中的多个响应
+// 兼容只使用\n的换行、缺少原因短语和格式错误的响应头
+// 响应体按Content-Encoding解码，Raw保留收到的原始字节
+func ParseRawResponses(data []byte) []*HTTPResponse {
+	responses, _ := parseResponses(data, nil)
+	return responses
+}
+
+// parseResponses 解析响应数据，methods 是流水线中各请求的方法，HEAD请求的响应没有响应体
+// 返回的complete表示最后一个响应是否完整
+func parseResponses(data []byte, methods []string) ([]*HTTPResponse, bool) {
+	var responses []*HTTPResponse
+	complete := true
+	index := 0
+
+	for len(data) > 0 {
+		start := bytes.Index(data, []byte("HTTP/"))
+		if start < 0 {
+			break
+		}
+		data = data[start:]
+
+		resp, n, ok := parseRawResponse(data, methodAt(methods, index))
+		if resp == nil {
+			complete = false
+			break
+		}
+		resp.Body = decodeBody(resp.Headers, resp.Body)
+		responses = append(responses, resp)
+		complete = ok
+		data = data[n:]
+		// 1xx是中间响应，之后还有同一请求的最终响应
+		if resp.StatusCode/100 != 1 {
+			index++
+		}
+	}
+
+	return responses, complete
+}
+
+// countCompleteResponses 统计数据中已完整接收的最终响应数量
+func countCompleteResponses(data []byte, methods []string) int {
+	count := 0
+	for len(data) > 0 {
+		start := bytes.Index(data, []byte("HTTP/"))
+		if start < 0 {
+			break
+		}
+		data = data[start:]
+
+		resp, n, complete := parseRawResponse(data, methodAt(methods, count))
+		if resp == nil || !complete {
+			break
+		}
+		if resp.StatusCode/100 != 1 {
+			count++
+		}
+		data = data[n:]
+	}
+	return count
+}
+
+// methodAt 返回第i个请求的方法，未知时为空
+func methodAt(methods []string, i int) string {
+	if i < len(methods) {
+		return methods[i]
+	}
+	return ""
+}
+
+// requestMethods 宽松地解析原始数据中流水线请求的方法，用于判断响应是否有响应体
+// 按Content-Length和分块编码跳过请求体，无法解析时返回已得到的部分
+func requestMethods(data []byte) []string {
+	var methods []string
+
+	for len(data) > 0 {
+		headerEnd, sepLen := findHeaderEnd(data)
+		if headerEnd < 0 {
+			break
+		}
+		lines := splitLines(data[:headerEnd])
+		// 跳过请求之间多余的空行
+		for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+			lines = lines[1:]
+		}
+		if len(lines) == 0 {
+			break
+		}
+		fields := strings.Fields(lines[0])
+		if len(fields) == 0 {
+			break
+		}
+		methods = append(methods, strings.ToUpper(fields[0]))
+
+		header := make(http.Header)
+		for _, line := range lines[1:] {
+			if k, v, ok := strings.Cut(line, ":"); ok {
+				header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+			}
+		}
+
+		rest := data[headerEnd+sepLen:]
+		switch {
+		case strings.Contains(strings.ToLower(header.Get("Transfer-Encoding")), "chunked"):
+			_, n, _ := decodeChunked(rest)
+			rest = rest[n:]
+		case header.Get("Content-Length") != "":
+			length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+			if err != nil || length < 0 || length > len(rest) {
+				return methods
+			}
+			rest = rest[length:]
+		}
+		data = rest
+	}
+
+	return methods
+}
+
+// parseRawResponse 解析单个响应，返回响应、消耗的字节数以及响应是否完整
+// method 是对应请求的方法，HEAD请求的响应忽略Content-Length，不读取响应体
+func parseRawResponse(data []byte, method string) (*HTTPResponse, int, bool) {
+	headerEnd, sepLen := findHeaderEnd(data)
+	if headerEnd < 0 {
+		return nil, 0, false
+	}
+
+	lines := splitLines(data[:headerEnd])
+	if len(lines) == 0 {
+		return nil, 0, false
+	}
+
+	resp := &HTTPResponse{
+		Headers: make(http.Header),
+	}
+
+	// 状态行: HTTP/1.1 200 OK
+	fields := strings.Fields(lines[0])
+	if len(fields) >= 1 {
+		resp.Proto = fields[0]
+	}
+	if len(fields) >= 2 {
+		resp.StatusCode, _ = strconv.Atoi(fields[1])
+	}
+
+	for _, line := range lines[1:] {
+		i := strings.IndexByte(line, ':')
+		if i <= 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:i])
+		resp.Headers.Add(key, strings.TrimSpace(line[i+1:]))
+	}
+
+	bodyStart := headerEnd + sepLen
+	rest := data[bodyStart:]
+	consumed := bodyStart
+	complete := true
+
+	switch {
+	case method == http.MethodHead || resp.StatusCode/100 == 1 || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified:
+		// 没有响应体
+	case strings.Contains(strings.ToLower(resp.Headers.Get("Transfer-Encoding")), "chunked"):
+		body, n, ok := decodeChunked(rest)
+		resp.Body = body
+		consumed += n
+		complete = ok
+	case resp.Headers.Get("Content-Length") != "":
+		length, err := strconv.Atoi(strings.TrimSpace(resp.Headers.Get("Content-Length")))
+		if err != nil || length < 0 {
+			resp.Body = rest
+			consumed += len(rest)
+			complete = false
+			break
+		}
+		if length > len(rest) {
+			length = len(rest)
+			complete = false
+		}
+		resp.Body = rest[:length]
+		consumed += length
+	default:
+		// 没有长度信息时读取到数据结尾
+		resp.Body = rest
+		consumed += len(rest)
+		complete = false
+	}
+
+	resp.Raw = data[:consumed]
+	return resp, consumed, complete
+}
+
+// findHeaderEnd 查找响应头结束位置，兼容\r\n\r\n和\n\n
+func findHeaderEnd(data []byte) (int, int) {
+	crlf := bytes.Index(data, []byte("\r\n\r\n"))
+	lf := bytes.Index(data, []byte("\n\n"))
+
+	switch {
+	case crlf < 0 && lf < 0:
+		return -1, 0
+	case crlf < 0:
+		return lf, 2
+	case lf < 0 || crlf+1 <= lf:
+		return crlf, 4
+	default:
+		return lf, 2
+	}
+}
+
+// splitLines 按行拆分，兼容\r\n和\n
+func splitLines(data []byte) []string {
+	raw := strings.Split(string(data), "\n")
+	lines := make([]string, 0, len(raw))
+	for _, line := range raw {
+		lines = append(lines, strings.TrimRight(line, "\r"))
+	}
+	return lines
+}
+
+// decodeChunked 宽松地解码分块传输编码，返回解码结果、消耗的字节数和是否读到结束块
+func decodeChunked(data []byte) ([]byte, int, bool) {
+	var body []byte
+	pos := 0
+
+	for pos < len(data) {
+		lineEnd := bytes.IndexByte(data[pos:], '\n')
+		if lineEnd < 0 {
+			return body, len(data), false
+		}
+
+		sizeLine := strings.TrimSpace(string(data[pos : pos+lineEnd]))
+		if i := strings.IndexByte(sizeLine, ';'); i >= 0 {
+			sizeLine = sizeLine[:i]
+		}
+		size, err := strconv.ParseInt(sizeLine, 16, 64)
+		if err != nil || size < 0 {
+			return body, len(data), false
+		}
+		pos += lineEnd + 1
+
+		if size == 0 {
+			// 跳过trailer直到空行
+			for pos < len(data) {
+				end := bytes.IndexByte(data[pos:], '\n')
+				if end < 0 {
+					return body, len(data), false
+				}
+				line := strings.TrimSpace(string(data[pos : pos+end]))
+				pos += end + 1
+				if line == "" {
+					return body, pos, true
+				}
+			}
+			return body, pos, false
+		}
+
+		if int64(len(data)-pos) < size {
+			body = append(body, data[pos:]...)
+			return body, len(data), false
+		}
+		body = append(body, data[pos:pos+int(size)]...)
+		pos += int(size)
+
+		// 跳过块数据后的换行
+		if pos < len(data) && data[pos] == '\r' {
+			pos++
+		}
+		if pos < len(data) && data[pos] == '\n' {
+			pos++
+		}
+	}
+
+	return body, pos, false
+}
@@ -0,0 +1,451 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// HTTP/2帧类型
+const (
+	H2FrameData         uint8 = 0x0
+	H2FrameHeaders      uint8 = 0x1
+	H2FramePriority     uint8 = 0x2
+	H2FrameRSTStream    uint8 = 0x3
+	H2FrameSettings     uint8 = 0x4
+	H2FramePushPromise  uint8 = 0x5
+	H2FramePing         uint8 = 0x6
+	H2FrameGoAway       uint8 = 0x7
+	H2FrameWindowUpdate uint8 = 0x8
+	H2FrameContinuation uint8 = 0x9
+)
+
+// HTTP/2帧标志
+const (
+	H2FlagEndStream  uint8 = 0x1
+	H2FlagAck        uint8 = 0x1
+	H2FlagEndHeaders uint8 = 0x4
+	H2FlagPadded     uint8 = 0x8
+	H2FlagPriority   uint8 = 0x20
+)
+
+// defaultH2IdleTimeout 是原始HTTP/2请求默认的空闲超时
+const defaultH2IdleTimeout = 2 * time.Second
+
+// H2Frame 是一个HTTP/2帧，发送时不做任何校验
+type H2Frame struct {
+	Type     uint8
+	Flags    uint8
+	StreamID uint32
+	Payload  []byte
+}
+
+// H2Header 是一个按顺序编码的头部字段，名称和值可以包含任意字符，
+// 用于构造伪头部注入、大写头部名等畸形请求
+type H2Header struct {
+	Name  string
+	Value string
+}
+
+// H2HeadersFrame 使用HPACK编码头部字段并创建带END_HEADERS标志的HEADERS帧
+func H2HeadersFrame(streamID uint32, headers []H2Header, endStream bool) H2Frame {
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	for _, h := range headers {
+		enc.WriteField(hpack.HeaderField{Name: h.Name, Value: h.Value})
+	}
+
+	flags := H2FlagEndHeaders
+	if endStream {
+		flags |= H2FlagEndStream
+	}
+	return H2Frame{Type: H2FrameHeaders, Flags: flags, StreamID: streamID, Payload: buf.Bytes()}
+}
+
+// H2DataFrame 创建DATA帧
+func H2DataFrame(streamID uint32, data []byte, endStream bool) H2Frame {
+	var flags uint8
+	if endStream {
+		flags = H2FlagEndStream
+	}
+	return H2Frame{Type: H2FrameData, Flags: flags, StreamID: streamID, Payload: data}
+}
+
+// RawH2Request 描述一个由原始HTTP/2帧组成的请求
+type RawH2Request struct {
+	// Addr 目标地址，格式为 host:port
+	Addr string
+	// TLS 是否使用TLS连接，为true时通过ALPN协商h2，否则使用h2c先验知识
+	TLS bool
+	// ServerName 覆盖TLS握手使用的SNI
+	ServerName string
+	// Frames 在连接前言和初始SETTINGS帧之后原样发送
+	Frames []H2Frame
+	// NoPreface 为true时不自动发送连接前言和初始SETTINGS帧
+	NoPreface bool
+	// Timeout 整个交换的超时时间，为0时使用客户端配置的超时
+	Timeout time.Duration
+	// IdleTimeout 连接空闲超过该时间即返回，为0时使用默认值
+	IdleTimeout time.Duration
+	// ReadLimit 读取帧（含帧头）的最大总字节数，为0时使用默认值
+	ReadLimit int64
+}
+
+// NewRawH2Request 根据目标URL创建原始HTTP/2请求
+func NewRawH2Request(target string, frames ...H2Frame) (*RawH2Request, error) {
+	raw, err := NewRawRequest(target, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &RawH2Request{Addr: raw.Addr, TLS: raw.TLS, Frames: frames}, nil
+}
+
+// H2Result 是原始HTTP/2请求的结果
+type H2Result struct {
+	// Frames 是按顺序收到的全部帧
+	Frames []H2Frame
+	// Responses 是按流ID整理的响应，头部块无法解码的流不包含在内
+	Responses map[uint32]*HTTPResponse
+	// Truncated 表示达到ReadLimit后停止读取，未结束的流的响应同样标记Truncated
+	Truncated bool
+}
+
+// h2Stream 是接收过程中的单个流
+type h2Stream struct {
+	resp   *HTTPResponse
+	closed bool
+}
+
+// SendH2 发送原始HTTP/2帧并收集响应
+// 收到的SETTINGS和PING会自动确认，所有发送过HEADERS的流结束、收到GOAWAY、
+// 连接关闭、空闲超时、整体超时或达到读取上限后返回，即使出错也会返回已收到的内容
+func (c *RawClient) SendH2(ctx context.Context, req *RawH2Request) (result *H2Result, err error) {
+	var sent []byte
+	if entry := c.rawEntry("h2", req.Addr, req.TLS); entry != nil {
+		defer func() { c.recordH2(entry, sent, result, err) }()
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = c.config.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if c.config.RateLimiter != nil {
+		host, _, _ := net.SplitHostPort(req.Addr)
+		release, err := c.config.RateLimiter.Acquire(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	trace := &requestTrace{}
+	trace.begin()
+	ctx = trace.withTrace(ctx)
+	begin := time.Now()
+
+	conn, err := c.dialer.DialContext(ctx, "tcp", req.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if req.TLS {
+		tlsConn, err := c.dialer.clientTLS(ctx, conn, req.Addr, req.ServerName, []string{http2.NextProtoTLS})
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if p := tlsConn.ConnectionState().NegotiatedProtocol; p != http2.NextProtoTLS {
+			tlsConn.Close()
+			return nil, fmt.Errorf("服务器 %s 不支持HTTP/2 (ALPN: %q)", req.Addr, p)
+		}
+		conn = tlsConn
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var out bytes.Buffer
+	if !req.NoPreface {
+		out.WriteString(http2.ClientPreface)
+		writeH2Frame(&out, h2Settings(map[http2.SettingID]uint32{
+			http2.SettingEnablePush:        0,
+			http2.SettingInitialWindowSize: 1 << 24,
+		}))
+		// 放大连接级流量控制窗口，避免大响应阻塞
+		writeH2Frame(&out, H2Frame{Type: H2FrameWindowUpdate, Payload: binary.BigEndian.AppendUint32(nil, 1<<24)})
+	}
+
+	streams := make(map[uint32]*h2Stream)
+	for _, f := range req.Frames {
+		writeH2Frame(&out, f)
+		if f.Type == H2FrameHeaders && f.StreamID != 0 && streams[f.StreamID] == nil {
+			streams[f.StreamID] = &h2Stream{}
+		}
+	}
+
+	sent = out.Bytes()
+	if _, err := conn.Write(sent); err != nil {
+		return nil, fmt.Errorf("发送HTTP/2帧失败: %w", err)
+	}
+
+	idle := req.IdleTimeout
+	if idle == 0 {
+		idle = defaultH2IdleTimeout
+	}
+
+	limit := req.ReadLimit
+	if limit <= 0 {
+		limit = defaultRawReadLimit
+	}
+
+	result = &H2Result{Responses: make(map[uint32]*HTTPResponse)}
+	err = readH2(conn, idle, limit, streams, result)
+	// 多路复用的流无法区分首字节，只记录建立连接的各阶段和整个交换的耗时
+	timing := trace.timing(time.Since(begin))
+
+	for id, st := range streams {
+		if st.resp != nil {
+			st.resp.Body = decodeBody(st.resp.Headers, st.resp.Body)
+			st.resp.RemoteAddr = conn.RemoteAddr().String()
+			st.resp.Timing = timing
+			st.resp.Truncated = result.Truncated && !st.closed
+			result.Responses[id] = st.resp
+		}
+	}
+
+	if err != nil && len(result.Frames) == 0 {
+		return nil, err
+	}
+	return result, nil
+}
+
+// recordH2 以收到的帧的原始编码记录HTTP/2交换，响应按流ID排序
+func (c *RawClient) recordH2(e *TrafficEntry, sent []byte, result *H2Result, err error) {
+	var (
+		recv      bytes.Buffer
+		responses []*HTTPResponse
+	)
+	if result != nil {
+		for _, f := range result.Frames {
+			writeH2Frame(&recv, f)
+		}
+		ids := make([]uint32, 0, len(result.Responses))
+		for id := range result.Responses {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, id := range ids {
+			responses = append(responses, result.Responses[id])
+		}
+	}
+
+	c.recordRaw(e, sent, recv.Bytes(), responses, err)
+}
+
+// readH2 读取帧直到所有流结束、连接结束或读取的字节数达到limit
+// 达到上限时设置result.Truncated，不返回错误
+func readH2(conn net.Conn, idle time.Duration, limit int64, streams map[uint32]*h2Stream, result *H2Result) error {
+	dec := hpack.NewDecoder(4096, nil)
+	var block []byte
+	var blockStream uint32
+	remain := limit
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(idle))
+		f, err := readH2Frame(conn, remain)
+		if err != nil {
+			if errors.Is(err, errH2ReadLimit) {
+				result.Truncated = true
+				return nil
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) || err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		remain -= int64(h2FrameHeaderLen + len(f.Payload))
+		result.Frames = append(result.Frames, f)
+
+		switch f.Type {
+		case H2FrameSettings:
+			if f.Flags&H2FlagAck == 0 {
+				conn.Write(encodeH2Frame(H2Frame{Type: H2FrameSettings, Flags: H2FlagAck}))
+			}
+		case H2FramePing:
+			if f.Flags&H2FlagAck == 0 {
+				conn.Write(encodeH2Frame(H2Frame{Type: H2FramePing, Flags: H2FlagAck, Payload: f.Payload}))
+			}
+		case H2FrameGoAway:
+			return nil
+		case H2FrameRSTStream:
+			if st := streams[f.StreamID]; st != nil {
+				st.closed = true
+			}
+		case H2FrameHeaders, H2FrameContinuation:
+			fragment := f.Payload
+			if f.Type == H2FrameHeaders {
+				fragment = h2HeaderFragment(f)
+				block, blockStream = nil, f.StreamID
+			}
+			block = append(block, fragment...)
+
+			if f.Flags&H2FlagEndHeaders != 0 {
+				fields, err := dec.DecodeFull(block)
+				st := streams[blockStream]
+				if err == nil && st != nil {
+					st.addHeaders(fields)
+				}
+				block = nil
+			}
+			if st := streams[f.StreamID]; st != nil && f.Type == H2FrameHeaders && f.Flags&H2FlagEndStream != 0 {
+				st.closed = true
+			}
+		case H2FrameData:
+			if st := streams[f.StreamID]; st != nil {
+				if st.resp != nil {
+					st.resp.Body = append(st.resp.Body, h2Unpad(f)...)
+				}
+				if f.Flags&H2FlagEndStream != 0 {
+					st.closed = true
+				}
+			}
+		}
+
+		// 等待CONTINUATION时不结束
+		if block == nil && allClosed(streams) {
+			return nil
+		}
+	}
+}
+
+// addHeaders 处理一个完整的头部块，第一个块为响应头，之后的为trailer
+func (st *h2Stream) addHeaders(fields []hpack.HeaderField) {
+	if st.resp == nil {
+		st.resp = &HTTPResponse{Proto: "HTTP/2.0", Headers: make(http.Header)}
+	}
+
+	for _, hf := range fields {
+		if hf.Name == ":status" {
+			code, _ := strconv.Atoi(hf.Value)
+			// 1xx信息响应之后还有最终响应
+			if st.resp.StatusCode == 0 || st.resp.StatusCode/100 == 1 {
+				st.resp.StatusCode = code
+			}
+			continue
+		}
+		st.resp.Headers.Add(hf.Name, hf.Value)
+	}
+}
+
+func allClosed(streams map[uint32]*h2Stream) bool {
+	if len(streams) == 0 {
+		return false
+	}
+	for _, st := range streams {
+		if !st.closed {
+			return false
+		}
+	}
+	return true
+}
+
+// h2HeaderFragment 去除HEADERS帧的填充和优先级字段
+func h2HeaderFragment(f H2Frame) []byte {
+	p := h2Unpad(f)
+	if f.Flags&H2FlagPriority != 0 {
+		if len(p) < 5 {
+			return nil
+		}
+		p = p[5:]
+	}
+	return p
+}
+
+// h2Unpad 去除带PADDED标志的帧的填充
+func h2Unpad(f H2Frame) []byte {
+	p := f.Payload
+	if f.Flags&H2FlagPadded == 0 {
+		return p
+	}
+	if len(p) < 1 || int(p[0]) > len(p)-1 {
+		return nil
+	}
+	return p[1 : len(p)-int(p[0])]
+}
+
+// h2Settings 创建SETTINGS帧
+func h2Settings(settings map[http2.SettingID]uint32) H2Frame {
+	var payload []byte
+	for _, id := range []http2.SettingID{
+		http2.SettingHeaderTableSize, http2.SettingEnablePush, http2.SettingMaxConcurrentStreams,
+		http2.SettingInitialWindowSize, http2.SettingMaxFrameSize, http2.SettingMaxHeaderListSize,
+	} {
+		if v, ok := settings[id]; ok {
+			payload = binary.BigEndian.AppendUint16(payload, uint16(id))
+			payload = binary.BigEndian.AppendUint32(payload, v)
+		}
+	}
+	return H2Frame{Type: H2FrameSettings, Payload: payload}
+}
+
+// encodeH2Frame 编码帧，长度字段取负载的实际长度
+func encodeH2Frame(f H2Frame) []byte {
+	var buf bytes.Buffer
+	writeH2Frame(&buf, f)
+	return buf.Bytes()
+}
+
+func writeH2Frame(buf *bytes.Buffer, f H2Frame) {
+	n := len(f.Payload)
+	buf.Write([]byte{byte(n >> 16), byte(n >> 8), byte(n), f.Type, f.Flags})
+	buf.Write(binary.BigEndian.AppendUint32(nil, f.StreamID))
+	buf.Write(f.Payload)
+}
+
+// h2FrameHeaderLen 是HTTP/2帧头的长度
+const h2FrameHeaderLen = 9
+
+// errH2ReadLimit 表示下一个帧会超过读取上限
+var errH2ReadLimit = errors.New("HTTP/2帧超过读取上限")
+
+// readH2Frame 读取一个帧，不做协议校验
+// 帧的总长度超过remain时不读取负载并返回 errH2ReadLimit
+func readH2Frame(r io.Reader, remain int64) (H2Frame, error) {
+	var hdr [h2FrameHeaderLen]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return H2Frame{}, err
+	}
+
+	n := int(hdr[0])<<16 | int(hdr[1])<<8 | int(hdr[2])
+	if int64(h2FrameHeaderLen+n) > remain {
+		return H2Frame{}, errH2ReadLimit
+	}
+	f := H2Frame{
+		Type:     hdr[3],
+		Flags:    hdr[4],
+		StreamID: binary.BigEndian.Uint32(hdr[5:]) & 0x7fffffff,
+		Payload:  make([]byte, n),
+	}
+	if _, err := io.ReadFull(r, f.Payload); err != nil {
+		return H2Frame{}, err
+	}
+	return f, nil
+}
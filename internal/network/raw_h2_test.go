@@ -0,0 +1,120 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// h2cServer 启动一个明文HTTP/2服务，返回 size 字节的响应体
+func h2cServer(t *testing.T, size int) string {
+	t.Helper()
+
+	body := bytes.Repeat([]byte("a"), size)
+	handler := h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}), &http2.Server{})
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func sendH2Get(t *testing.T, target string, limit int64) *H2Result {
+	t.Helper()
+
+	req, err := NewRawH2Request(target, H2HeadersFrame(1, []H2Header{
+		{Name: ":method", Value: "GET"},
+		{Name: ":scheme", Value: "http"},
+		{Name: ":authority", Value: strings.TrimPrefix(target, "http://")},
+		{Name: ":path", Value: "/"},
+	}, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.ReadLimit = limit
+	req.IdleTimeout = 500 * time.Millisecond
+
+	result, err := rawClient().SendH2(context.Background(), req)
+	if err != nil {
+		t.Fatalf("SendH2: %v", err)
+	}
+	return result
+}
+
+func TestSendH2Complete(t *testing.T) {
+	target := h2cServer(t, 100<<10)
+
+	result := sendH2Get(t, target, 0)
+	resp := result.Responses[1]
+	if resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("stream 1 response = %+v", resp)
+	}
+	if result.Truncated || resp.Truncated || len(resp.Body) != 100<<10 {
+		t.Errorf("body = %d bytes, truncated = %v", len(resp.Body), resp.Truncated)
+	}
+}
+
+func TestSendH2ReadLimit(t *testing.T) {
+	target := h2cServer(t, 100<<10)
+
+	const limit = 32 << 10
+	result := sendH2Get(t, target, limit)
+	if !result.Truncated {
+		t.Fatal("result not marked truncated")
+	}
+
+	var received int
+	for _, f := range result.Frames {
+		received += h2FrameHeaderLen + len(f.Payload)
+	}
+	if received > limit {
+		t.Errorf("received %d bytes of frames, limit %d", received, limit)
+	}
+
+	resp := result.Responses[1]
+	if resp == nil || !resp.Truncated {
+		t.Fatalf("stream 1 response not marked truncated: %+v", resp)
+	}
+	if len(resp.Body) >= 100<<10 {
+		t.Errorf("body = %d bytes, want cut below the limit", len(resp.Body))
+	}
+}
+
+func TestSendH2TrafficRecorded(t *testing.T) {
+	target := h2cServer(t, 10)
+
+	log := NewTrafficLog(0)
+	config := DefaultHTTPClientConfig()
+	config.Traffic = log
+	client := NewRawClient(config).WithTags(Tags{Plugin: "h2"})
+
+	req, _ := NewRawH2Request(target, H2HeadersFrame(1, []H2Header{
+		{Name: ":method", Value: "GET"},
+		{Name: ":scheme", Value: "http"},
+		{Name: ":authority", Value: strings.TrimPrefix(target, "http://")},
+		{Name: ":path", Value: "/"},
+	}, true))
+	req.IdleTimeout = 500 * time.Millisecond
+	if _, err := client.SendH2(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := log.List(TrafficFilter{Plugin: "h2"})
+	if len(entries) != 1 {
+		t.Fatalf("entries = %d, want 1", len(entries))
+	}
+	e := entries[0]
+	if !e.Socket || e.Proto != "h2" || e.StatusCode != http.StatusOK {
+		t.Errorf("entry = %+v", e)
+	}
+	if !bytes.HasPrefix(e.RequestBody, []byte(http2.ClientPreface)) || len(e.ResponseBody) == 0 {
+		t.Errorf("recorded %d bytes sent, %d received", len(e.RequestBody), len(e.ResponseBody))
+	}
+}
@@ -0,0 +1,165 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveRaw 启动一个TCP服务，读取请求后原样写回reply并关闭连接
+func serveRaw(t *testing.T, reply string) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+				io.Copy(io.Discard, bufio.NewReader(conn))
+				io.WriteString(conn, reply)
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func rawClient() *RawClient {
+	config := DefaultHTTPClientConfig()
+	config.Timeout = 2 * time.Second
+	return NewRawClient(config)
+}
+
+func TestRawHeadDoesNotConsumeNextResponse(t *testing.T) {
+	addr := serveRaw(t, "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\n"+
+		"HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello")
+
+	data := "HEAD / HTTP/1.1\r\nHost: x\r\n\r\n" +
+		"POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 3\r\n\r\nabc"
+	responses, err := rawClient().Send(context.Background(), &RawRequest{Addr: addr, Data: []byte(data)})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if len(responses) != 2 {
+		t.Fatalf("responses = %d, want 2", len(responses))
+	}
+	if len(responses[0].Body) != 0 {
+		t.Errorf("HEAD body = %q, want empty", responses[0].Body)
+	}
+	if string(responses[1].Body) != "hello" {
+		t.Errorf("second body = %q, want %q", responses[1].Body, "hello")
+	}
+}
+
+func TestRawInterimAndNoBodyResponses(t *testing.T) {
+	addr := serveRaw(t, "HTTP/1.1 100 Continue\r\n\r\n"+
+		"HTTP/1.1 204 No Content\r\nContent-Length: 5\r\n\r\n"+
+		"HTTP/1.1 304 Not Modified\r\nContent-Length: 5\r\n\r\n"+
+		"HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+
+	responses, err := rawClient().Send(context.Background(), &RawRequest{Addr: addr, Data: []byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n")})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	var codes []int
+	for _, r := range responses {
+		codes = append(codes, r.StatusCode)
+	}
+	if len(responses) != 4 || string(responses[3].Body) != "ok" {
+		t.Fatalf("codes = %v, last body = %q", codes, responses[len(responses)-1].Body)
+	}
+}
+
+func TestRawReadLimitMarksTruncated(t *testing.T) {
+	body := strings.Repeat("a", 100)
+	addr := serveRaw(t, "HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\n"+body)
+
+	responses, err := rawClient().Send(context.Background(), &RawRequest{
+		Addr:      addr,
+		Data:      []byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"),
+		ReadLimit: 60,
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(responses) != 1 || !responses[0].Truncated {
+		t.Fatalf("want one truncated response, got %d", len(responses))
+	}
+
+	responses, err = rawClient().Send(context.Background(), &RawRequest{
+		Addr: addr,
+		Data: []byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"),
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(responses) != 1 || responses[0].Truncated || string(responses[0].Body) != body {
+		t.Fatalf("complete response marked truncated or body cut")
+	}
+}
+
+func TestRequestMethods(t *testing.T) {
+	data := "GET /a HTTP/1.1\r\nHost: x\r\n\r\n" +
+		"POST /b HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n0\r\n\r\n" +
+		"head /c HTTP/1.1\r\nHost: x\r\n\r\n"
+
+	got := strings.Join(requestMethods([]byte(data)), ",")
+	if got != "GET,POST,HEAD" {
+		t.Errorf("methods = %s, want GET,POST,HEAD", got)
+	}
+}
+
+func TestRawTrafficRecorded(t *testing.T) {
+	reply := "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"
+	addr := serveRaw(t, reply)
+
+	log := NewTrafficLog(0)
+	config := DefaultHTTPClientConfig()
+	config.Timeout = 2 * time.Second
+	config.Traffic = log
+	tags := Tags{Scan: "s1", Plugin: "smuggle", Target: addr}
+	client := NewRawClient(config).WithTags(tags)
+
+	data := "GET / HTTP/1.1\r\nHost: x\r\nContent-Length: 0\r\nContent-Length: 5\r\n\r\n"
+	if _, err := client.Send(context.Background(), &RawRequest{Addr: addr, Data: []byte(data)}); err != nil {
+		t.Fatal(err)
+	}
+	// 连接失败同样记录
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := ln.Addr().String()
+	ln.Close()
+	if _, err := client.Send(context.Background(), &RawRequest{Addr: closed, Data: []byte(data)}); err == nil {
+		t.Fatal("Send to a closed port succeeded")
+	}
+
+	entries := log.List(TrafficFilter{Plugin: "smuggle"})
+	if len(entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(entries))
+	}
+	e := entries[0]
+	if !e.Socket || e.Tags != tags || e.URL != "http://"+addr || e.StatusCode != 200 || e.RemoteAddr != addr {
+		t.Errorf("entry = %+v", e)
+	}
+	if string(e.RequestBody) != data || string(e.ResponseBody) != reply {
+		t.Errorf("recorded bytes = %q / %q", e.RequestBody, e.ResponseBody)
+	}
+	if entries[1].Error == "" {
+		t.Errorf("failed exchange recorded without error: %+v", entries[1])
+	}
+}
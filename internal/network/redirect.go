@@ -0,0 +1,101 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxRedirects 是未指定时允许的最大跳转次数
+const defaultMaxRedirects = 10
+
+// RedirectMode 表示重定向处理方式
+type RedirectMode int
+
+const (
+	// RedirectFollow 自动跟随重定向
+	RedirectFollow RedirectMode = iota
+	// RedirectNone 不跟随重定向，直接返回3xx响应
+	RedirectNone
+)
+
+// RedirectPolicy 控制客户端的重定向行为
+type RedirectPolicy struct {
+	Mode RedirectMode
+	// MaxRedirects 最大跳转次数，超过后返回最后一个3xx响应，为0时使用默认值10
+	MaxRedirects int
+}
+
+// ParseRedirectPolicy 解析重定向策略: "follow"、"none" 或最大跳转次数
+func ParseRedirectPolicy(value string) (RedirectPolicy, error) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	switch v {
+	case "", "follow", "true", "yes":
+		return RedirectPolicy{Mode: RedirectFollow}, nil
+	case "none", "false", "no", "0":
+		return RedirectPolicy{Mode: RedirectNone}, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return RedirectPolicy{}, fmt.Errorf("无效的重定向策略: %s", value)
+	}
+	return RedirectPolicy{Mode: RedirectFollow, MaxRedirects: n}, nil
+}
+
+// RedirectHop 表示重定向链中的一跳
+type RedirectHop struct {
+	URL        string
+	StatusCode int
+	Location   string
+}
+
+type redirectKey struct{}
+
+// WithRedirectPolicy 为单个请求指定重定向策略，覆盖客户端配置
+func WithRedirectPolicy(ctx context.Context, policy RedirectPolicy) context.Context {
+	return context.WithValue(ctx, redirectKey{}, policy)
+}
+
+// checkRedirect 实现http.Client.CheckRedirect
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	policy := c.config.Redirect
+	if p, ok := req.Context().Value(redirectKey{}).(RedirectPolicy); ok {
+		policy = p
+	}
+
+	if policy.Mode == RedirectNone {
+		return http.ErrUseLastResponse
+	}
+
+	max := policy.MaxRedirects
+	if max <= 0 {
+		max = defaultMaxRedirects
+	}
+	if len(via) > max {
+		return http.ErrUseLastResponse
+	}
+
+	return nil
+}
+
+// redirectChain 从最终响应回溯完整的重定向链
+func redirectChain(resp *http.Response) []RedirectHop {
+	var chain []RedirectHop
+
+	for r := resp.Request; r != nil && r.Response != nil; r = r.Response.Request {
+		prev := r.Response
+		hop := RedirectHop{
+			StatusCode: prev.StatusCode,
+			Location:   prev.Header.Get("Location"),
+		}
+		if prev.Request != nil {
+			hop.URL = prev.Request.URL.String()
+		}
+		chain = append([]RedirectHop{hop}, chain...)
+	}
+
+	return chain
+}
@@ -0,0 +1,131 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseRedirectPolicy(t *testing.T) {
+	tests := []struct {
+		value string
+		want  RedirectPolicy
+		ok    bool
+	}{
+		{"", RedirectPolicy{Mode: RedirectFollow}, true},
+		{"follow", RedirectPolicy{Mode: RedirectFollow}, true},
+		{"Yes", RedirectPolicy{Mode: RedirectFollow}, true},
+		{"none", RedirectPolicy{Mode: RedirectNone}, true},
+		{"false", RedirectPolicy{Mode: RedirectNone}, true},
+		{"0", RedirectPolicy{Mode: RedirectNone}, true},
+		{"3", RedirectPolicy{Mode: RedirectFollow, MaxRedirects: 3}, true},
+		{" 5 ", RedirectPolicy{Mode: RedirectFollow, MaxRedirects: 5}, true},
+		{"-1", RedirectPolicy{}, false},
+		{"sometimes", RedirectPolicy{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseRedirectPolicy(tt.value)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseRedirectPolicy(%q) = %+v, %v, want %+v", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+// redirectServer 返回的服务器在 /hop/n 上跳转到 /hop/n-1，/hop/0 返回200
+func redirectServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if err != nil || n == 0 {
+			fmt.Fprint(w, "landed")
+			return
+		}
+		code := http.StatusFound
+		if n%2 == 0 {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), code)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	srv := redirectServer(t)
+
+	tests := []struct {
+		name     string
+		config   RedirectPolicy
+		request  *RedirectPolicy
+		path     string
+		status   int
+		hops     int
+		location string
+	}{
+		{"follow", RedirectPolicy{}, nil, "/hop/3", http.StatusOK, 3, ""},
+		{"none", RedirectPolicy{Mode: RedirectNone}, nil, "/hop/3", http.StatusFound, 0, "/hop/2"},
+		{"within limit", RedirectPolicy{MaxRedirects: 3}, nil, "/hop/3", http.StatusOK, 3, ""},
+		{"over limit", RedirectPolicy{MaxRedirects: 2}, nil, "/hop/3", http.StatusFound, 2, "/hop/0"},
+		{"default limit", RedirectPolicy{}, nil, "/hop/12", http.StatusMovedPermanently, 10, "/hop/1"},
+		{"request overrides none", RedirectPolicy{Mode: RedirectNone}, &RedirectPolicy{MaxRedirects: 1}, "/hop/3", http.StatusMovedPermanently, 1, "/hop/1"},
+		{"request disables follow", RedirectPolicy{}, &RedirectPolicy{Mode: RedirectNone}, "/hop/2", http.StatusMovedPermanently, 0, "/hop/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultHTTPClientConfig()
+			config.Redirect = tt.config
+
+			ctx := context.Background()
+			if tt.request != nil {
+				ctx = WithRedirectPolicy(ctx, *tt.request)
+			}
+			resp, err := NewHTTPClient(config).Get(ctx, srv.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status || len(resp.RedirectChain) != tt.hops {
+				t.Fatalf("status %d after %d hops, want %d after %d", resp.StatusCode, len(resp.RedirectChain), tt.status, tt.hops)
+			}
+			if got := resp.Headers.Get("Location"); got != tt.location {
+				t.Errorf("Location = %q, want %q", got, tt.location)
+			}
+		})
+	}
+}
+
+func TestRedirectChain(t *testing.T) {
+	srv := redirectServer(t)
+
+	resp, err := NewHTTPClient(DefaultHTTPClientConfig()).Get(context.Background(), srv.URL+"/hop/3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 链中按顺序记录每一跳的地址、状态码和Location
+	want := []RedirectHop{
+		{srv.URL + "/hop/3", http.StatusFound, "/hop/2"},
+		{srv.URL + "/hop/2", http.StatusMovedPermanently, "/hop/1"},
+		{srv.URL + "/hop/1", http.StatusFound, "/hop/0"},
+	}
+	if len(resp.RedirectChain) != len(want) {
+		t.Fatalf("chain = %+v", resp.RedirectChain)
+	}
+	for i, hop := range resp.RedirectChain {
+		if hop != want[i] {
+			t.Errorf("hop %d = %+v, want %+v", i, hop, want[i])
+		}
+	}
+	if string(resp.Body) != "landed" {
+		t.Errorf("body = %q", resp.Body)
+	}
+
+	direct, err := NewHTTPClient(DefaultHTTPClientConfig()).Get(context.Background(), srv.URL+"/hop/0", nil)
+	if err != nil || direct.RedirectChain != nil {
+		t.Errorf("chain without redirects = %+v, %v", direct.RedirectChain, err)
+	}
+}
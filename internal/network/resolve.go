@@ -0,0 +1,163 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// hostResolver 按hosts映射和自定义DNS服务器解析主机名
+// 两者都未配置时不做任何处理，交由系统解析
+type hostResolver struct {
+	hosts map[string]string
+	dns   *net.Resolver
+}
+
+// newHostResolver 创建解析器，server 为空时不使用自定义DNS服务器
+// dial 用于连接DNS服务器，UDP应答被截断时解析器会自动改用TCP
+func newHostResolver(hosts map[string]string, server string, dial func(ctx context.Context, network, addr string) (net.Conn, error)) *hostResolver {
+	r := &hostResolver{hosts: hosts}
+
+	if server != "" {
+		server = dnsServerAddr(server)
+		r.dns = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dial(ctx, network, server)
+			},
+		}
+	}
+
+	return r
+}
+
+// active 判断是否配置了hosts映射或自定义DNS服务器
+func (r *hostResolver) active() bool {
+	return len(r.hosts) > 0 || r.dns != nil
+}
+
+// ParseDNSServer 校验DNS服务器地址，未指定端口时使用53
+func ParseDNSServer(value string) (string, error) {
+	addr := dnsServerAddr(strings.TrimSpace(value))
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) == nil {
+		return "", fmt.Errorf("无效的DNS服务器: %s (格式 ip[:port])", value)
+	}
+	return addr, nil
+}
+
+// dnsServerAddr 为未指定端口的DNS服务器地址补上53端口
+func dnsServerAddr(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), "53")
+}
+
+// override 查找hosts映射中的IP，精确匹配优先于子域名匹配，多个子域名规则匹配时最长的优先
+func (r *hostResolver) override(host string) (string, bool) {
+	if ip, ok := r.hosts[strings.ToLower(host)]; ok {
+		return ip, true
+	}
+
+	best, bestLen := "", 0
+	for pattern, ip := range r.hosts {
+		if strings.HasPrefix(pattern, ".") && matchHostPattern(pattern, host) && len(pattern) > bestLen {
+			best, bestLen = ip, len(pattern)
+		}
+	}
+	return best, bestLen > 0
+}
+
+// resolveAddr 将addr中的主机名替换为IP，没有映射且未配置DNS服务器时原样返回
+func (r *hostResolver) resolveAddr(ctx context.Context, network, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return addr, nil
+	}
+
+	if ip, ok := r.override(host); ok {
+		return net.JoinHostPort(ip, port), nil
+	}
+	if r.dns == nil {
+		return addr, nil
+	}
+
+	addrs, err := r.dns.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+
+	// 按网络类型选择地址族，未指定时优先IPv4
+	want4 := !strings.HasSuffix(network, "6")
+	want6 := !strings.HasSuffix(network, "4")
+	var fallback net.IP
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			if want4 {
+				return net.JoinHostPort(a.IP.String(), port), nil
+			}
+		} else if want6 && fallback == nil {
+			fallback = a.IP
+		}
+	}
+	if fallback != nil {
+		return net.JoinHostPort(fallback.String(), port), nil
+	}
+
+	return "", &net.DNSError{Err: "没有可用的地址", Name: host, IsNotFound: true}
+}
+
+// ParseHosts 解析逗号分隔的 host=ip 列表，host 以点开头时匹配所有子域名
+func ParseHosts(value string) (map[string]string, error) {
+	hosts := make(map[string]string)
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		host, ip, ok := strings.Cut(entry, "=")
+		host, ip = strings.ToLower(strings.TrimSpace(host)), strings.TrimSpace(ip)
+		if !ok || host == "" {
+			return nil, fmt.Errorf("无效的hosts映射: %s (格式 host=ip)", entry)
+		}
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("无效的IP地址: %s", ip)
+		}
+		hosts[host] = ip
+	}
+
+	return hosts, nil
+}
+
+// LoadHostsFile 读取 /etc/hosts 格式的文件，每行为IP和一个或多个主机名，#之后为注释
+func LoadHostsFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hosts := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+			return nil, fmt.Errorf("%s:%d: 无效的hosts条目", path, line)
+		}
+		for _, host := range fields[1:] {
+			hosts[strings.ToLower(host)] = fields[0]
+		}
+	}
+
+	return hosts, scanner.Err()
+}
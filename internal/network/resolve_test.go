@@ -0,0 +1,234 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHostsOverrideLongestSuffix(t *testing.T) {
+	hosts, err := ParseHosts(".example.com=10.0.0.1, .api.example.com=10.0.0.2, www.api.example.com=10.0.0.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newHostResolver(hosts, "", nil)
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"www.api.example.com", "10.0.0.3"},
+		{"v1.api.example.com", "10.0.0.2"},
+		{"API.example.com", "10.0.0.2"},
+		{"www.example.com", "10.0.0.1"},
+		{"example.org", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			// 遍历map的顺序随机，多次查找确保结果稳定
+			for i := 0; i < 20; i++ {
+				ip, ok := r.override(tt.host)
+				if ok != (tt.want != "") || ip != tt.want {
+					t.Fatalf("override(%s) = %q, %v, want %q", tt.host, ip, ok, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// pipeConns 在两个连接之间双向转发，任意一方关闭后结束
+func pipeConns(client io.ReadWriteCloser, clientReader io.Reader, upstream net.Conn) {
+	go func() {
+		io.Copy(upstream, clientReader)
+		upstream.Close()
+	}()
+	io.Copy(client, upstream)
+	client.Close()
+}
+
+// connectProxy 启动一个HTTP CONNECT代理，把每个CONNECT的目标发送到返回的通道
+func connectProxy(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	targets := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				br := bufio.NewReader(conn)
+				req, err := http.ReadRequest(br)
+				if err != nil || req.Method != http.MethodConnect {
+					conn.Close()
+					return
+				}
+				targets <- req.Host
+				upstream, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+					conn.Close()
+					return
+				}
+				io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+				pipeConns(conn, br, upstream)
+			}()
+		}
+	}()
+
+	return ln.Addr().String(), targets
+}
+
+// socks5Proxy 启动一个不需要认证的SOCKS5代理，把每个CONNECT的目标发送到返回的通道
+func socks5Proxy(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	targets := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				head := make([]byte, 2)
+				io.ReadFull(conn, head)
+				io.ReadFull(conn, make([]byte, head[1]))
+				conn.Write([]byte{0x05, 0x00})
+
+				req := make([]byte, 4)
+				if _, err := io.ReadFull(conn, req); err != nil {
+					conn.Close()
+					return
+				}
+				var host string
+				switch req[3] {
+				case 0x01:
+					ip := make([]byte, 4)
+					io.ReadFull(conn, ip)
+					host = net.IP(ip).String()
+				case 0x03:
+					n := make([]byte, 1)
+					io.ReadFull(conn, n)
+					name := make([]byte, n[0])
+					io.ReadFull(conn, name)
+					host = string(name)
+				}
+				port := make([]byte, 2)
+				io.ReadFull(conn, port)
+				target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+				targets <- target
+
+				upstream, err := net.Dial("tcp", target)
+				if err != nil {
+					conn.Write([]byte{0x05, 0x04, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+					conn.Close()
+					return
+				}
+				conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+				pipeConns(conn, conn, upstream)
+			}()
+		}
+	}()
+
+	return ln.Addr().String(), targets
+}
+
+func TestHostsOverrideThroughProxy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Host)
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+
+	httpProxy, httpTargets := connectProxy(t)
+	socksProxy, socksTargets := socks5Proxy(t)
+
+	tests := []struct {
+		name    string
+		proxy   string
+		targets <-chan string
+	}{
+		{"connect", "http://" + httpProxy, httpTargets},
+		{"socks5", "socks5://" + socksProxy, socksTargets},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultHTTPClientConfig()
+			config.MaxRetries = 0
+			config.Proxy = tt.proxy
+			config.Hosts = map[string]string{"vhost.test": "127.0.0.1"}
+
+			resp, err := NewHTTPClient(config).Get(context.Background(), "http://vhost.test:"+port+"/", nil)
+			if err != nil {
+				t.Fatalf("GET through proxy: %v", err)
+			}
+			if got := string(resp.Body); got != "vhost.test:"+port {
+				t.Errorf("Host seen by server = %q, want the original host", got)
+			}
+			if got := <-tt.targets; got != "127.0.0.1:"+port {
+				t.Errorf("proxy asked to connect to %q, want the overridden IP", got)
+			}
+
+			// 原始连接使用同一Dialer
+			conn, err := NewDialer(config).DialContext(context.Background(), "tcp", "vhost.test:"+port)
+			if err != nil {
+				t.Fatalf("Dialer through proxy: %v", err)
+			}
+			conn.Close()
+			if got := <-tt.targets; got != "127.0.0.1:"+port {
+				t.Errorf("dialer asked proxy for %q, want the overridden IP", got)
+			}
+		})
+	}
+}
+
+func TestHostsOverrideThroughProxyKeepsSNI(t *testing.T) {
+	sni := make(chan string, 1)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		sni <- hello.ServerName
+		return nil, nil
+	}}
+	srv.StartTLS()
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "https://"))
+
+	proxy, targets := connectProxy(t)
+	config := DefaultHTTPClientConfig()
+	config.MaxRetries = 0
+	config.Proxy = "http://" + proxy
+	config.Hosts = map[string]string{"secure.test": "127.0.0.1"}
+	config.TLS.InsecureSkipVerify = true
+
+	if _, err := NewHTTPClient(config).Get(context.Background(), "https://secure.test:"+port+"/", nil); err != nil {
+		t.Fatalf("GET through proxy: %v", err)
+	}
+	if got := <-targets; got != "127.0.0.1:"+port {
+		t.Errorf("CONNECT target = %q, want the overridden IP", got)
+	}
+	if got := <-sni; got != "secure.test" {
+		t.Errorf("SNI = %q, want secure.test", got)
+	}
+}
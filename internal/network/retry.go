@@ -0,0 +1,229 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// DefaultRetryStatusCodes 是常见的可重试响应状态码
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// doWithRetry 执行HTTP请求并支持重试
+func (c *Client) doWithRetry(req *http.Request) (*HTTPResponse, error) {
+	var (
+		resp *HTTPResponse
+		err  error
+		wait time.Duration
+	)
+
+	ctx := req.Context()
+	retryable := c.canRetry(req)
+
+	for try := 0; ; try++ {
+		// 如果不是第一次尝试，则等待退避时间
+		if try > 0 {
+			if werr := sleepContext(ctx, wait); werr != nil {
+				if resp != nil {
+					return resp, nil
+				}
+				return nil, werr
+			}
+		}
+
+		// 创建请求的副本，并从GetBody重新获取已被上一次尝试消费的请求体
+		reqCopy := req.Clone(ctx)
+		if try > 0 {
+			if rerr := rewindBody(req, reqCopy); rerr != nil {
+				return resp, err
+			}
+		}
+		resp, err = c.Do(reqCopy)
+
+		if !retryable || try >= c.config.MaxRetries {
+			break
+		}
+
+		if err != nil {
+			// 上下文已取消或非临时性错误，不再重试
+			if ctx.Err() != nil || !isTemporaryError(err) {
+				break
+			}
+			wait = c.backoff(try)
+			continue
+		}
+
+		if !c.shouldRetryStatus(resp.StatusCode) {
+			break
+		}
+
+		wait = c.backoff(try)
+		if after, ok := parseRetryAfter(resp.Headers.Get("Retry-After")); ok {
+			// 服务端要求的等待时间超过上限时直接返回该响应
+			if c.config.MaxRetryInterval > 0 && after > c.config.MaxRetryInterval {
+				break
+			}
+			wait = after
+		}
+	}
+
+	return resp, err
+}
+
+// canRetry 判断请求是否允许重试
+func (c *Client) canRetry(req *http.Request) bool {
+	if c.config.MaxRetries <= 0 {
+		return false
+	}
+
+	// 请求体无法重放时重试会发送空请求体
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	return c.config.RetryNonIdempotent || isIdempotent(req)
+}
+
+// shouldRetryStatus 判断响应状态码是否需要重试
+func (c *Client) shouldRetryStatus(code int) bool {
+	for _, s := range c.config.RetryOnStatus {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff 计算第try次失败后的等待时间，使用带抖动的指数退避
+func (c *Client) backoff(try int) time.Duration {
+	base := c.config.RetryInterval
+	if base <= 0 {
+		return 0
+	}
+
+	d := base
+	for i := 0; i < try; i++ {
+		d *= 2
+		if c.config.MaxRetryInterval > 0 && d >= c.config.MaxRetryInterval {
+			d = c.config.MaxRetryInterval
+			break
+		}
+	}
+
+	// 等待时间在 [d/2, d) 之间随机分布，避免并发请求同时重试
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// sleepContext 等待指定时间，上下文取消时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter 解析Retry-After响应头，支持秒数和HTTP日期两种格式
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// isIdempotent 判断请求是否幂等
+// 带有Idempotency-Key或X-Idempotency-Key请求头的请求也视为幂等
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	_, ok := req.Header["Idempotency-Key"]
+	if !ok {
+		_, ok = req.Header["X-Idempotency-Key"]
+	}
+	return ok
+}
+
+// isTemporaryError 判断错误是否为临时性错误
+// 超时、连接重置、连接中断和意外EOF视为临时性错误；
+// DNS解析失败、证书错误、连接被拒绝和请求格式错误视为永久性错误
+func isTemporaryError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// 证书和TLS协议错误重试也不会成功
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostnameErr      x509.HostnameError
+		certInvalid      x509.CertificateInvalidError
+		recordHeaderErr  tls.RecordHeaderError
+		certVerifyErr    *tls.CertificateVerificationError
+	)
+	if errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &certInvalid) || errors.As(err, &recordHeaderErr) ||
+		errors.As(err, &certVerifyErr) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ETIMEDOUT) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
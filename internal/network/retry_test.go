@@ -0,0 +1,222 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"empty", "", 0, false},
+		{"seconds", "5", 5 * time.Second, true},
+		{"zero", "0", 0, true},
+		{"negative", "-1", 0, false},
+		{"fraction", "1.5", 0, false},
+		{"garbage", "soon", 0, false},
+		{"past date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	// HTTP日期精确到秒，等待时间略小于相差的时长
+	date := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	got, ok := parseRetryAfter(date)
+	if !ok || got < 28*time.Second || got > 30*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %s, %v, want about 30s", date, got, ok)
+	}
+}
+
+func TestIsTemporaryError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"canceled", context.Canceled, false},
+		{"wrapped canceled", fmt.Errorf("Get: %w", context.Canceled), false},
+		{"EOF", io.EOF, true},
+		{"unexpected EOF", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), true},
+		{"connection reset", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{"connection aborted", &net.OpError{Op: "read", Err: syscall.ECONNABORTED}, true},
+		{"broken pipe", &net.OpError{Op: "write", Err: syscall.EPIPE}, true},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, false},
+		{"deadline", &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, true},
+		{"dns not found", &net.DNSError{Err: "no such host", Name: "x.invalid", IsNotFound: true}, false},
+		{"dns timeout", &net.DNSError{Err: "i/o timeout", Name: "x.test", IsTimeout: true}, true},
+		{"dns temporary", &net.DNSError{Err: "server misbehaving", Name: "x.test", IsTemporary: true}, true},
+		{"unknown authority", fmt.Errorf("tls: %w", x509.UnknownAuthorityError{}), false},
+		{"hostname mismatch", x509.HostnameError{Host: "example.com", Certificate: &x509.Certificate{}}, false},
+		{"certificate verification", &tls.CertificateVerificationError{Err: errors.New("expired")}, false},
+		{"not TLS", tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, false},
+		{"other", errors.New("malformed HTTP response"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTemporaryError(tt.err); got != tt.want {
+				t.Errorf("isTemporaryError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRetryStatus(t *testing.T) {
+	config := DefaultHTTPClientConfig()
+	config.RetryOnStatus = DefaultRetryStatusCodes
+	c := NewHTTPClient(config)
+
+	for code, want := range map[int]bool{
+		http.StatusTooManyRequests:     true,
+		http.StatusBadGateway:          true,
+		http.StatusServiceUnavailable:  true,
+		http.StatusGatewayTimeout:      true,
+		http.StatusOK:                  false,
+		http.StatusNotFound:            false,
+		http.StatusInternalServerError: false,
+	} {
+		if got := c.shouldRetryStatus(code); got != want {
+			t.Errorf("shouldRetryStatus(%d) = %v, want %v", code, got, want)
+		}
+	}
+
+	// 未配置状态码时只对网络错误重试
+	if NewHTTPClient(DefaultHTTPClientConfig()).shouldRetryStatus(http.StatusServiceUnavailable) {
+		t.Error("503 retried without RetryOnStatus")
+	}
+}
+
+func TestBackoffBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		max      time.Duration
+		try      int
+		want     time.Duration
+	}{
+		{"first retry", 100 * time.Millisecond, time.Second, 0, 100 * time.Millisecond},
+		{"doubles", 100 * time.Millisecond, time.Second, 2, 400 * time.Millisecond},
+		{"capped", 100 * time.Millisecond, time.Second, 5, time.Second},
+		{"capped far out", 100 * time.Millisecond, time.Second, 60, time.Second},
+		{"no cap", 100 * time.Millisecond, 0, 4, 1600 * time.Millisecond},
+		{"disabled", 0, time.Second, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultHTTPClientConfig()
+			config.RetryInterval = tt.interval
+			config.MaxRetryInterval = tt.max
+			c := NewHTTPClient(config)
+
+			// 抖动后的等待时间落在 [d/2, d] 之间
+			for i := 0; i < 200; i++ {
+				if got := c.backoff(tt.try); got < tt.want/2 || got > tt.want {
+					t.Fatalf("backoff(%d) = %s, want within [%s, %s]", tt.try, got, tt.want/2, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCanRetry(t *testing.T) {
+	newReq := func(method string, body io.Reader, header string) *http.Request {
+		req, _ := http.NewRequest(method, "http://example.com/", body)
+		if header != "" {
+			req.Header.Set(header, "k1")
+		}
+		return req
+	}
+	unreplayable := newReq(http.MethodPut, nil, "")
+	unreplayable.Body = io.NopCloser(strings.NewReader("data"))
+
+	tests := []struct {
+		name          string
+		req           *http.Request
+		retries       int
+		nonIdempotent bool
+		want          bool
+	}{
+		{"GET", newReq(http.MethodGet, nil, ""), 3, false, true},
+		{"PUT with body", newReq(http.MethodPut, strings.NewReader("x"), ""), 3, false, true},
+		{"POST", newReq(http.MethodPost, strings.NewReader("x"), ""), 3, false, false},
+		{"POST with Idempotency-Key", newReq(http.MethodPost, strings.NewReader("x"), "Idempotency-Key"), 3, false, true},
+		{"POST with X-Idempotency-Key", newReq(http.MethodPost, strings.NewReader("x"), "X-Idempotency-Key"), 3, false, true},
+		{"POST allowed", newReq(http.MethodPost, strings.NewReader("x"), ""), 3, true, true},
+		{"retries disabled", newReq(http.MethodGet, nil, ""), 0, false, false},
+		{"body without GetBody", unreplayable, 3, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultHTTPClientConfig()
+			config.MaxRetries = tt.retries
+			config.RetryNonIdempotent = tt.nonIdempotent
+			if got := NewHTTPClient(config).canRetry(tt.req); got != tt.want {
+				t.Errorf("canRetry = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfterHonoredAndCapped(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		wantHits   int32
+		wantStatus int
+	}{
+		// 服务端要求立即重试，第三次成功
+		{"retry now", "0", 3, http.StatusOK},
+		// 超过MaxRetryInterval时直接返回503
+		{"over cap", "3600", 1, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&hits, 1) < 3 {
+					w.Header().Set("Retry-After", tt.retryAfter)
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte("ok"))
+			}))
+			defer srv.Close()
+
+			config := DefaultHTTPClientConfig()
+			config.RetryInterval = time.Hour
+			config.MaxRetryInterval = time.Minute
+			config.RetryOnStatus = DefaultRetryStatusCodes
+
+			resp, err := NewHTTPClient(config).Get(context.Background(), srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || atomic.LoadInt32(&hits) != tt.wantHits {
+				t.Errorf("status %d after %d requests, want %d after %d", resp.StatusCode, hits, tt.wantStatus, tt.wantHits)
+			}
+		})
+	}
+}
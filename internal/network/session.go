@@ -0,0 +1,368 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginRecipe 描述如何为会话完成登录
+// 登录请求通过同一个客户端发送，返回的Cookie会自动保存到会话的Cookie Jar中
+type LoginRecipe interface {
+	Login(ctx context.Context, c *Client, s *Session) error
+}
+
+// FormLogin 以表单方式提交登录请求
+type FormLogin struct {
+	URL    string
+	Fields url.Values
+}
+
+// Login 实现LoginRecipe接口
+func (f FormLogin) Login(ctx context.Context, c *Client, s *Session) error {
+	resp, err := c.Post(ctx, f.URL, f.Fields.Encode(), map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("表单登录失败: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// TokenLogin 请求令牌接口，并将返回的令牌写入会话请求头
+type TokenLogin struct {
+	URL string
+	// Body 是令牌请求的请求体，通常为JSON
+	Body interface{}
+	// TokenField 是响应JSON中令牌的路径，以点分隔，如 data.access_token
+	TokenField string
+	// Header 是携带令牌的请求头，默认为Authorization
+	Header string
+	// Prefix 是令牌前缀，Header为Authorization时默认为"Bearer "
+	Prefix string
+}
+
+// Login 实现LoginRecipe接口
+func (t TokenLogin) Login(ctx context.Context, c *Client, s *Session) error {
+	resp, err := c.Post(ctx, t.URL, t.Body, map[string]string{
+		"Content-Type": "application/json",
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("令牌登录失败: HTTP %d", resp.StatusCode)
+	}
+
+	var data interface{}
+	if err := resp.ParseJSON(&data); err != nil {
+		return fmt.Errorf("解析令牌响应失败: %w", err)
+	}
+
+	field := t.TokenField
+	if field == "" {
+		field = "access_token"
+	}
+	token, ok := lookupJSONField(data, field)
+	if !ok {
+		return fmt.Errorf("令牌响应中没有字段: %s", field)
+	}
+
+	header, prefix := t.Header, t.Prefix
+	if header == "" {
+		header = "Authorization"
+		if prefix == "" {
+			prefix = "Bearer "
+		}
+	}
+	s.SetHeader(header, prefix+token)
+
+	return nil
+}
+
+// lookupJSONField 按点分隔的路径查找JSON中的字符串字段
+func lookupJSONField(data interface{}, path string) (string, bool) {
+	for _, key := range strings.Split(path, ".") {
+		m, ok := data.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if data, ok = m[key]; !ok {
+			return "", false
+		}
+	}
+
+	switch v := data.(type) {
+	case string:
+		return v, true
+	case json.Number, float64, bool:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
+// StaticHeaders 为会话添加固定请求头，不需要登录请求
+type StaticHeaders map[string]string
+
+// Login 实现LoginRecipe接口
+func (h StaticHeaders) Login(ctx context.Context, c *Client, s *Session) error {
+	for k, v := range h {
+		s.SetHeader(k, v)
+	}
+	return nil
+}
+
+// Session 表示某个主机的认证会话
+type Session struct {
+	// Host 是会话适用的主机，以点开头时匹配所有子域名
+	Host string
+	// Login 是登录方式，为空时会话只保存Cookie
+	Login LoginRecipe
+	// TTL 是会话有效期，到期后在下一次请求前自动重新登录，为0时不过期
+	TTL time.Duration
+	// ExpireStatus 是表示会话失效的响应状态码，收到时重新登录并重发请求
+	ExpireStatus []int
+
+	Jar http.CookieJar
+
+	loginMu  sync.Mutex
+	mu       sync.Mutex
+	headers  map[string]string
+	loggedIn time.Time
+	valid    bool
+}
+
+// NewSession 创建一个带Cookie Jar的会话
+func NewSession(host string, login LoginRecipe) *Session {
+	jar, _ := cookiejar.New(nil)
+	return &Session{
+		Host:         host,
+		Login:        login,
+		ExpireStatus: []int{http.StatusUnauthorized},
+		Jar:          jar,
+		headers:      make(map[string]string),
+	}
+}
+
+// SetHeader 设置会话请求头，会话内的请求会自动携带
+func (s *Session) SetHeader(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.headers == nil {
+		s.headers = make(map[string]string)
+	}
+	s.headers[key] = value
+}
+
+// Headers 返回会话请求头的副本
+func (s *Session) Headers() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	headers := make(map[string]string, len(s.headers))
+	for k, v := range s.headers {
+		headers[k] = v
+	}
+	return headers
+}
+
+// Invalidate 将会话标记为失效，下一次请求前会重新登录
+func (s *Session) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.valid = false
+}
+
+// LoggedInAt 返回最近一次登录的时间
+func (s *Session) LoggedInAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.loggedIn
+}
+
+// Matches 判断会话是否适用于主机
+func (s *Session) Matches(host string) bool {
+	return matchHostPattern(s.Host, host)
+}
+
+// matchHostPattern 判断主机是否匹配模式，以点开头的模式匹配该域名及其所有子域名
+func matchHostPattern(pattern, host string) bool {
+	host = strings.ToLower(host)
+	pattern = strings.ToLower(pattern)
+
+	if strings.HasPrefix(pattern, ".") {
+		return host == pattern[1:] || strings.HasSuffix(host, pattern)
+	}
+	return host == pattern
+}
+
+// isExpireStatus 判断响应状态码是否表示会话失效
+func (s *Session) isExpireStatus(code int) bool {
+	for _, c := range s.ExpireStatus {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ensure 在会话未登录或已过期时执行登录
+func (s *Session) ensure(ctx context.Context, c *Client) error {
+	if s.Login == nil {
+		return nil
+	}
+
+	// 同一会话同时只执行一次登录，等待中的请求直接复用登录结果
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+
+	if s.isValid() {
+		return nil
+	}
+
+	if err := s.Login.Login(withSessionLogin(ctx), c, s); err != nil {
+		return fmt.Errorf("会话 %s 登录失败: %w", s.Host, err)
+	}
+
+	s.mu.Lock()
+	s.valid = true
+	s.loggedIn = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// isValid 判断会话是否已登录且未过期
+func (s *Session) isValid() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.valid && (s.TTL <= 0 || time.Since(s.loggedIn) < s.TTL)
+}
+
+// SessionStore 管理各主机的会话，可在多个客户端之间共享
+type SessionStore struct {
+	mu       sync.RWMutex
+	sessions []*Session
+}
+
+// NewSessionStore 创建一个新的会话存储
+func NewSessionStore() *SessionStore {
+	return &SessionStore{}
+}
+
+// Add 添加会话，同一主机的旧会话会被替换
+func (st *SessionStore) Add(s *Session) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for i, old := range st.sessions {
+		if strings.EqualFold(old.Host, s.Host) {
+			st.sessions[i] = s
+			return
+		}
+	}
+	st.sessions = append(st.sessions, s)
+}
+
+// Remove 删除指定主机的会话
+func (st *SessionStore) Remove(host string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for i, s := range st.sessions {
+		if strings.EqualFold(s.Host, host) {
+			st.sessions = append(st.sessions[:i], st.sessions[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// List 返回所有会话
+func (st *SessionStore) List() []*Session {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	list := make([]*Session, len(st.sessions))
+	copy(list, st.sessions)
+	return list
+}
+
+// Match 查找适用于主机的会话，精确匹配优先于子域名匹配，多个子域名会话匹配时最长的优先
+func (st *SessionStore) Match(host string) *Session {
+	if st == nil {
+		return nil
+	}
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var best *Session
+	for _, s := range st.sessions {
+		if !s.Matches(host) {
+			continue
+		}
+		if !strings.HasPrefix(s.Host, ".") {
+			return s
+		}
+		if best == nil || len(s.Host) > len(best.Host) {
+			best = s
+		}
+	}
+	return best
+}
+
+// sessionJar 按主机将Cookie路由到对应会话的Cookie Jar
+// 没有匹配的会话时使用客户端自身的Jar（可为空）
+type sessionJar struct {
+	store    *SessionStore
+	fallback http.CookieJar
+}
+
+func (j *sessionJar) jarFor(u *url.URL) http.CookieJar {
+	if s := j.store.Match(u.Hostname()); s != nil && s.Jar != nil {
+		return s.Jar
+	}
+	return j.fallback
+}
+
+// SetCookies 实现http.CookieJar接口
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if jar := j.jarFor(u); jar != nil {
+		jar.SetCookies(u, cookies)
+	}
+}
+
+// Cookies 实现http.CookieJar接口
+func (j *sessionJar) Cookies(u *url.URL) []*http.Cookie {
+	if jar := j.jarFor(u); jar != nil {
+		return jar.Cookies(u)
+	}
+	return nil
+}
+
+type sessionLoginKey struct{}
+
+// withSessionLogin 标记登录请求，避免登录请求再次触发登录
+func withSessionLogin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionLoginKey{}, true)
+}
+
+func isSessionLogin(ctx context.Context) bool {
+	v, _ := ctx.Value(sessionLoginKey{}).(bool)
+	return v
+}
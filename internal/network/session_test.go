@@ -0,0 +1,167 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// loginServer 模拟登录后下发令牌Cookie的站点，/data 只接受当前有效的令牌
+func loginServer(t *testing.T, logins *int32, allowLogins int32, valid *atomic.Value) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(logins, 1)
+		if n > allowLogins {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		token := "t" + strings.Repeat("x", int(n))
+		valid.Store(token)
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: token, Path: "/"})
+	})
+	mux.HandleFunc("/data", func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("sid")
+		if err != nil || c.Value != valid.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("secret"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func sessionClient(srv *httptest.Server) (*Client, *Session) {
+	u, _ := url.Parse(srv.URL)
+	sess := NewSession(u.Hostname(), FormLogin{URL: srv.URL + "/login", Fields: url.Values{"u": {"admin"}}})
+
+	store := NewSessionStore()
+	store.Add(sess)
+
+	config := DefaultHTTPClientConfig()
+	config.MaxRetries = 0
+	config.Sessions = store
+	return NewHTTPClient(config), sess
+}
+
+func TestSessionReloginOnExpiry(t *testing.T) {
+	var (
+		logins int32
+		valid  atomic.Value
+	)
+	valid.Store("")
+	srv := loginServer(t, &logins, 2, &valid)
+	client, _ := sessionClient(srv)
+	ctx := context.Background()
+
+	if resp, err := client.Get(ctx, srv.URL+"/data", nil); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("first request: %v", err)
+	}
+
+	// 服务端让会话失效，客户端应重新登录并重发
+	valid.Store("revoked")
+	resp, err := client.Get(ctx, srv.URL+"/data", nil)
+	if err != nil {
+		t.Fatalf("request after expiry: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != "secret" {
+		t.Errorf("status = %d, want 200 after relogin", resp.StatusCode)
+	}
+	if n := atomic.LoadInt32(&logins); n != 2 {
+		t.Errorf("logins = %d, want 2", n)
+	}
+}
+
+func TestSessionReloginFailureReturnsError(t *testing.T) {
+	var (
+		logins int32
+		valid  atomic.Value
+	)
+	valid.Store("")
+	srv := loginServer(t, &logins, 1, &valid)
+	client, _ := sessionClient(srv)
+	ctx := context.Background()
+
+	if _, err := client.Get(ctx, srv.URL+"/data", nil); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+
+	valid.Store("revoked")
+	resp, err := client.Get(ctx, srv.URL+"/data", nil)
+	if err == nil {
+		t.Fatalf("want relogin error, got status %d", resp.StatusCode)
+	}
+	if !strings.Contains(err.Error(), "登录失败") {
+		t.Errorf("error = %v, want wrapped login error", err)
+	}
+}
+
+func TestSharedCookieJarAcrossClients(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/set" {
+			http.SetCookie(w, &http.Cookie{Name: "pref", Value: "1", Path: "/"})
+			return
+		}
+		if _, err := r.Cookie("pref"); errors.Is(err, http.ErrNoCookie) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	jar, _ := cookiejar.New(nil)
+	config := DefaultHTTPClientConfig()
+	config.CookieJar = true
+	config.Jar = jar
+	ctx := context.Background()
+
+	if _, err := NewHTTPClient(config).Get(ctx, srv.URL+"/set", nil); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := NewHTTPClient(config).Get(ctx, srv.URL+"/check", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("second client status = %d, want cookie from shared jar", resp.StatusCode)
+	}
+}
+
+func TestSessionStoreMatch(t *testing.T) {
+	store := NewSessionStore()
+	// 较短的子域名规则先添加，最长的规则仍应优先
+	for _, host := range []string{".example.com", ".api.example.com", "login.api.example.com", ".other.com"} {
+		store.Add(NewSession(host, nil))
+	}
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"login.api.example.com", "login.api.example.com"},
+		{"LOGIN.API.EXAMPLE.COM", "login.api.example.com"},
+		{"v2.api.example.com", ".api.example.com"},
+		{"api.example.com", ".api.example.com"},
+		{"www.example.com", ".example.com"},
+		{"example.com", ".example.com"},
+		{"badexample.com", ""},
+		{"unrelated.org", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if s := store.Match(tt.host); s != nil {
+			got = s.Host
+		}
+		if got != tt.want {
+			t.Errorf("Match(%s) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DefaultSimilarityMargin 是判定响应不同时允许低于基线稳定度的幅度
+const DefaultSimilarityMargin = 0.01
+
+// similarityContext 是识别动态内容时参考的前置稳定词元数
+const similarityContext = 2
+
+// Similarity 返回两个响应体的相似度，0表示完全不同，1表示相同
+// 响应体按空白和常见分隔符切分为词元，按长度加权计算共有词元的占比，与词元顺序无关
+func Similarity(a, b *HTTPResponse) float64 {
+	return tokenRatio(countTokens(tokenize(a.Body), nil), countTokens(tokenize(b.Body), nil))
+}
+
+// SimilarityVerdict 是两个响应的比较结果
+type SimilarityVerdict struct {
+	// Ratio 是去除动态内容后的相似度
+	Ratio float64
+	// Different 表示两个响应存在有意义的差异
+	Different bool
+	// Reason 说明判定依据
+	Reason string
+}
+
+// ResponseBaseline 从多次相同请求的响应中学习页面的动态内容，用于布尔盲注等需要比较真假响应的检测
+// 在各次基线响应中出现次数不一致的词元视为动态内容，比较时忽略这些词元，
+// 以及紧跟在相同稳定上下文之后、基线中从未出现的新词元（如新的时间戳和CSRF令牌）
+type ResponseBaseline struct {
+	// Stability 是基线响应之间去除动态内容后的最低相似度，反映页面自身的波动
+	Stability float64
+	// Margin 是判定响应不同时允许低于Stability的幅度，默认为DefaultSimilarityMargin
+	Margin float64
+
+	reference *HTTPResponse
+	stable    map[string]bool
+	dynamic   map[string]bool
+	contexts  map[string]bool
+}
+
+// NewResponseBaseline 根据基线响应学习动态内容，至少需要两个响应才能识别动态内容
+// 三个及以上时逐个留出一个响应，用其余响应学习后与之比较，以估计未见过的动态值带来的波动
+func NewResponseBaseline(responses ...*HTTPResponse) (*ResponseBaseline, error) {
+	if len(responses) == 0 {
+		return nil, errors.New("至少需要一个基线响应")
+	}
+
+	b := learnBaseline(responses)
+	if len(responses) < 3 {
+		for i := range responses {
+			for j := i + 1; j < len(responses); j++ {
+				b.Stability = math.Min(b.Stability, b.Ratio(responses[i], responses[j]))
+			}
+		}
+		return b, nil
+	}
+
+	for i, held := range responses {
+		rest := make([]*HTTPResponse, 0, len(responses)-1)
+		rest = append(rest, responses[:i]...)
+		rest = append(rest, responses[i+1:]...)
+		loo := learnBaseline(rest)
+		b.Stability = math.Min(b.Stability, loo.Ratio(loo.reference, held))
+	}
+
+	return b, nil
+}
+
+// learnBaseline 统计基线响应中的稳定词元、动态词元和动态内容的上下文
+func learnBaseline(responses []*HTTPResponse) *ResponseBaseline {
+	b := &ResponseBaseline{
+		Stability: 1,
+		Margin:    DefaultSimilarityMargin,
+		reference: responses[0],
+		stable:    make(map[string]bool),
+		dynamic:   make(map[string]bool),
+		contexts:  make(map[string]bool),
+	}
+
+	sequences := make([][]string, len(responses))
+	counts := make([]map[string]int, len(responses))
+	for i, resp := range responses {
+		sequences[i] = tokenize(resp.Body)
+		counts[i] = countTokens(sequences[i], nil)
+	}
+
+	// 出现次数在所有基线中一致的词元是稳定的，其余是动态的
+	for i := range counts {
+		for token := range counts[i] {
+			if b.stable[token] || b.dynamic[token] {
+				continue
+			}
+			n := counts[0][token]
+			same := true
+			for _, c := range counts[1:] {
+				if c[token] != n {
+					same = false
+					break
+				}
+			}
+			if same {
+				b.stable[token] = true
+			} else {
+				b.dynamic[token] = true
+			}
+		}
+	}
+
+	// 记录动态词元之前的稳定上下文，比较时同一上下文之后的新词元同样视为动态
+	for _, seq := range sequences {
+		var ctx []string
+		for _, token := range seq {
+			if b.dynamic[token] {
+				b.contexts[contextKey(ctx)] = true
+				continue
+			}
+			ctx = pushContext(ctx, token)
+		}
+	}
+
+	return b
+}
+
+// Ratio 返回两个响应去除动态内容后的相似度
+func (b *ResponseBaseline) Ratio(x, y *HTTPResponse) float64 {
+	return tokenRatio(countTokens(tokenize(x.Body), b.ignored), countTokens(tokenize(y.Body), b.ignored))
+}
+
+// Threshold 返回判定响应相同所需的最低相似度
+func (b *ResponseBaseline) Threshold() float64 {
+	return b.Stability - b.Margin
+}
+
+// Compare 判断两个响应是否存在有意义的差异，状态码不同或相似度低于阈值时视为不同
+func (b *ResponseBaseline) Compare(x, y *HTTPResponse) SimilarityVerdict {
+	ratio := b.Ratio(x, y)
+	if x.StatusCode != y.StatusCode {
+		return SimilarityVerdict{
+			Ratio:     ratio,
+			Different: true,
+			Reason:    fmt.Sprintf("状态码不同: %d, %d", x.StatusCode, y.StatusCode),
+		}
+	}
+
+	threshold := b.Threshold()
+	if ratio < threshold {
+		return SimilarityVerdict{
+			Ratio:     ratio,
+			Different: true,
+			Reason:    fmt.Sprintf("相似度 %.3f 低于阈值 %.3f", ratio, threshold),
+		}
+	}
+	return SimilarityVerdict{
+		Ratio:  ratio,
+		Reason: fmt.Sprintf("相似度 %.3f 不低于阈值 %.3f", ratio, threshold),
+	}
+}
+
+// Differs 判断响应是否与基线存在有意义的差异
+func (b *ResponseBaseline) Differs(resp *HTTPResponse) bool {
+	return b.Compare(b.reference, resp).Different
+}
+
+// ignored 判断词元是否为动态内容，ctx 是该词元之前的稳定上下文
+func (b *ResponseBaseline) ignored(token string, ctx []string) bool {
+	if b.dynamic[token] {
+		return true
+	}
+	return !b.stable[token] && b.contexts[contextKey(ctx)]
+}
+
+// tokenize 按空白和HTML、JSON、URL中的常见分隔符切分响应体
+func tokenize(body []byte) []string {
+	var tokens []string
+	start := -1
+	for i, c := range body {
+		if isTokenSeparator(c) {
+			if start >= 0 {
+				tokens = append(tokens, string(body[start:i]))
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, string(body[start:]))
+	}
+	return tokens
+}
+
+// isTokenSeparator 判断字节是否为词元分隔符
+func isTokenSeparator(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', '<', '>', '"', '\'', '=', ':', ',', ';', '&', '{', '}', '[', ']', '(', ')':
+		return true
+	}
+	return false
+}
+
+// countTokens 统计词元出现次数，ignore 不为nil时跳过其判定为动态的词元
+// 上下文只由保留的词元组成
+func countTokens(tokens []string, ignore func(token string, ctx []string) bool) map[string]int {
+	counts := make(map[string]int, len(tokens))
+	var ctx []string
+	for _, token := range tokens {
+		if ignore != nil && ignore(token, ctx) {
+			continue
+		}
+		counts[token]++
+		ctx = pushContext(ctx, token)
+	}
+	return counts
+}
+
+// pushContext 将词元加入上下文，只保留最近的similarityContext个
+func pushContext(ctx []string, token string) []string {
+	ctx = append(ctx, token)
+	if len(ctx) > similarityContext {
+		ctx = ctx[len(ctx)-similarityContext:]
+	}
+	return ctx
+}
+
+// contextKey 将上下文转换为映射的键
+func contextKey(ctx []string) string {
+	return strings.Join(ctx, "\x00")
+}
+
+// tokenRatio 计算按词元长度加权的共有比例
+func tokenRatio(a, b map[string]int) float64 {
+	var total, common int
+	for token, n := range a {
+		total += n * len(token)
+		if m := b[token]; m > 0 {
+			common += min(n, m) * len(token)
+		}
+	}
+	for token, n := range b {
+		total += n * len(token)
+	}
+
+	if total == 0 {
+		return 1
+	}
+	return 2 * float64(common) / float64(total)
+}
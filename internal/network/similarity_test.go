@@ -0,0 +1,88 @@
+package network
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// productPage 生成带时间戳、CSRF令牌和请求ID等动态内容的商品列表页面
+func productPage(seq int, products ...string) *HTTPResponse {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<html><head><title>Shop</title><meta name="csrf-token" content="%x"></head><body>`, 0x5eed0000+seq*7919)
+	b.WriteString(`<nav><a href="/">Home</a> <a href="/cart">Cart</a> <a href="/account">Account</a></nav>`)
+	b.WriteString(`<form action="/search"><input type="hidden" name="_csrf" value="`)
+	fmt.Fprintf(&b, "tok%08d", seq*104729)
+	b.WriteString(`"><input name="q"></form><ul class="products">`)
+	for _, p := range products {
+		fmt.Fprintf(&b, `<li class="item"><h2>%s</h2><p>In stock, ships in 2 days</p></li>`, p)
+	}
+	fmt.Fprintf(&b, `</ul><footer>Generated at 2026-10-18T10:%02d:%02dZ request %d</footer></body></html>`,
+		seq%60, (seq*13)%60, 900000+seq)
+	return &HTTPResponse{StatusCode: 200, Body: []byte(b.String())}
+}
+
+var allProducts = []string{"Red Shirt", "Blue Jeans", "Green Hat", "Black Shoes", "White Socks"}
+
+func TestSimilarityIgnoresDynamicTokens(t *testing.T) {
+	baseline, err := NewResponseBaseline(productPage(1, allProducts...), productPage(2, allProducts...), productPage(3, allProducts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 新的时间戳、CSRF令牌和请求ID在基线中从未出现过
+	same := productPage(42, allProducts...)
+	if v := baseline.Compare(productPage(1, allProducts...), same); v.Different {
+		t.Errorf("page with only new dynamic values flagged: %s", v.Reason)
+	}
+	if baseline.Differs(same) {
+		t.Error("Differs reported a page with only new dynamic values")
+	}
+	if r := Similarity(productPage(1, allProducts...), same); r >= 1 {
+		t.Errorf("raw similarity = %.3f, want below 1 for differing dynamic values", r)
+	}
+}
+
+func TestSimilarityFlagsContentChange(t *testing.T) {
+	baseline, err := NewResponseBaseline(productPage(1, allProducts...), productPage(2, allProducts...), productPage(3, allProducts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		resp *HTTPResponse
+	}{
+		{"fewer results", productPage(42, allProducts[:1]...)},
+		{"no results", productPage(43)},
+		{"one item changed", productPage(44, "Red Shirt", "Blue Jeans", "Green Hat", "Black Shoes", "Purple Scarf")},
+		{"status changed", func() *HTTPResponse {
+			resp := productPage(45, allProducts...)
+			resp.StatusCode = 500
+			return resp
+		}()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !baseline.Differs(tt.resp) {
+				t.Errorf("content change not flagged: %s", baseline.Compare(productPage(1, allProducts...), tt.resp).Reason)
+			}
+		})
+	}
+}
+
+func TestSimilarityBounds(t *testing.T) {
+	a := &HTTPResponse{Body: []byte("alpha beta gamma")}
+	b := &HTTPResponse{Body: []byte("gamma beta alpha")}
+	c := &HTTPResponse{Body: []byte("delta epsilon")}
+
+	if r := Similarity(a, b); r != 1 {
+		t.Errorf("reordered tokens = %.3f, want 1", r)
+	}
+	if r := Similarity(a, c); r != 0 {
+		t.Errorf("disjoint tokens = %.3f, want 0", r)
+	}
+	if r := Similarity(&HTTPResponse{}, &HTTPResponse{}); r != 1 {
+		t.Errorf("empty bodies = %.3f, want 1", r)
+	}
+}
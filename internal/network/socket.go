@@ -0,0 +1,374 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// socketReadSize 是每次从连接读取的缓冲区大小，足以容纳一个UDP数据报
+const socketReadSize = 64 << 10
+
+// SocketClient 建立TCP/UDP连接，供Redis、FTP、SMTP等非HTTP协议的PoC使用
+// 与HTTP客户端共享代理、TLS配置、限速和流量记录；代理只转发TCP，设置了代理时UDP连接返回ErrUDPProxy
+type SocketClient struct {
+	config HTTPClientConfig
+	dialer *Dialer
+	tags   Tags
+	dump   io.Writer
+}
+
+// NewSocketClient 创建一个新的套接字客户端
+func NewSocketClient(config HTTPClientConfig) *SocketClient {
+	return &SocketClient{
+		config: config,
+		dialer: NewDialer(config),
+	}
+}
+
+// WithTags 返回使用指定流量标记的客户端副本
+func (c *SocketClient) WithTags(tags Tags) *SocketClient {
+	cp := *c
+	cp.tags = tags
+	return &cp
+}
+
+// WithDump 返回将收发数据以十六进制形式写入w的客户端副本
+func (c *SocketClient) WithDump(w io.Writer) *SocketClient {
+	cp := *c
+	cp.dump = w
+	return &cp
+}
+
+// Dial 建立TCP或UDP连接，TCP连接会按配置经过代理，UDP连接只能直连NoProxy中的主机
+func (c *SocketClient) Dial(ctx context.Context, network, addr string) (*Socket, error) {
+	return c.dial(ctx, network, addr, false, "")
+}
+
+// DialTLS 建立TLS连接，serverName为空时使用addr中的主机名作为SNI
+func (c *SocketClient) DialTLS(ctx context.Context, addr, serverName string) (*Socket, error) {
+	return c.dial(ctx, "tcp", addr, true, serverName)
+}
+
+func (c *SocketClient) dial(ctx context.Context, network, addr string, useTLS bool, serverName string) (*Socket, error) {
+	s := &Socket{
+		client:  c,
+		network: network,
+		addr:    addr,
+		tls:     useTLS,
+		Timeout: c.config.Timeout,
+		started: time.Now(),
+	}
+	if c.config.Traffic != nil {
+		s.sent = &limitedBuffer{limit: c.config.Traffic.bodyLimit}
+		s.recv = &limitedBuffer{limit: c.config.Traffic.bodyLimit}
+	}
+
+	if _, ok := ctx.Deadline(); !ok && s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	if c.config.RateLimiter != nil {
+		host, _, _ := net.SplitHostPort(addr)
+		release, err := c.config.RateLimiter.Acquire(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		s.release = release
+	}
+
+	var err error
+	if useTLS {
+		s.conn, err = c.dialer.DialTLSContext(ctx, network, addr, serverName)
+	} else {
+		s.conn, err = c.dialer.DialContext(ctx, network, addr)
+	}
+	if err != nil {
+		s.fail(err)
+		s.finish()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Banner 连接后读取服务端主动发送的数据，直到空闲wait时长或连接关闭
+func (c *SocketClient) Banner(ctx context.Context, network, addr string, wait time.Duration) ([]byte, error) {
+	s, err := c.Dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	return s.ReadIdle(wait)
+}
+
+// Socket 是一个TCP/UDP连接，提供按分隔符、长度和正则读取的辅助方法
+// Socket 不是并发安全的
+type Socket struct {
+	// Timeout 是每次读写操作的超时，为0时不限制
+	Timeout time.Duration
+
+	client  *SocketClient
+	conn    net.Conn
+	network string
+	addr    string
+	tls     bool
+	// buf 是已从连接读取但尚未返回给调用方的数据
+	buf  []byte
+	rbuf []byte
+
+	release    func()
+	started    time.Time
+	sent, recv *limitedBuffer
+	err        error
+	closed     bool
+}
+
+// Conn 返回底层连接
+func (s *Socket) Conn() net.Conn {
+	return s.conn
+}
+
+// RemoteAddr 返回对端地址
+func (s *Socket) RemoteAddr() string {
+	return s.conn.RemoteAddr().String()
+}
+
+// Send 发送数据
+func (s *Socket) Send(data []byte) error {
+	s.setDeadline(s.Timeout)
+	_, err := s.conn.Write(data)
+	if err != nil {
+		s.fail(err)
+		return err
+	}
+
+	s.dumpData(">", data)
+	if s.sent != nil {
+		s.sent.Write(data)
+	}
+	return nil
+}
+
+// SendString 发送字符串
+func (s *Socket) SendString(data string) error {
+	return s.Send([]byte(data))
+}
+
+// ReadN 读取恰好n个字节
+func (s *Socket) ReadN(n int) ([]byte, error) {
+	for len(s.buf) < n {
+		if err := s.fill(); err != nil {
+			return nil, err
+		}
+	}
+	return s.take(n), nil
+}
+
+// ReadUntil 读取到分隔符为止，返回的数据包含分隔符
+func (s *Socket) ReadUntil(delim []byte) ([]byte, error) {
+	if len(delim) == 0 {
+		return nil, fmt.Errorf("分隔符为空")
+	}
+
+	searched := 0
+	for {
+		if i := bytes.Index(s.buf[searched:], delim); i >= 0 {
+			return s.take(searched + i + len(delim)), nil
+		}
+		// 分隔符可能跨越两次读取的数据
+		if searched = len(s.buf) - len(delim) + 1; searched < 0 {
+			searched = 0
+		}
+		if err := s.fill(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ReadLine 读取一行，返回的字符串不含行尾的\r\n
+func (s *Socket) ReadLine() (string, error) {
+	line, err := s.ReadUntil([]byte("\n"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// ReadRegex 读取到数据匹配正则表达式为止，返回截至匹配结尾的数据
+func (s *Socket) ReadRegex(re *regexp.Regexp) ([]byte, error) {
+	for {
+		if loc := re.FindIndex(s.buf); loc != nil {
+			return s.take(loc[1]), nil
+		}
+		if err := s.fill(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ReadIdle 读取数据直到连接空闲idle时长或关闭，返回已收到的全部数据
+func (s *Socket) ReadIdle(idle time.Duration) ([]byte, error) {
+	for {
+		err := s.fillWithin(idle)
+		if err == nil {
+			continue
+		}
+
+		data := s.take(len(s.buf))
+		if errors.Is(err, os.ErrDeadlineExceeded) || err == io.EOF {
+			return data, nil
+		}
+		return data, err
+	}
+}
+
+// StartTLS 在当前连接上完成TLS握手，用于SMTP、FTP等协议的STARTTLS升级
+func (s *Socket) StartTLS(serverName string) error {
+	if len(s.buf) > 0 {
+		return fmt.Errorf("升级TLS前仍有 %d 字节未读取", len(s.buf))
+	}
+
+	ctx := context.Background()
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	tlsConn, err := s.client.dialer.ClientTLS(ctx, s.conn, s.addr, serverName)
+	if err != nil {
+		s.fail(err)
+		return err
+	}
+
+	s.conn = tlsConn
+	s.tls = true
+	return nil
+}
+
+// Close 关闭连接并记录本次会话的流量
+func (s *Socket) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	err := s.conn.Close()
+	s.finish()
+	return err
+}
+
+// fill 在超时时间内从连接读取一次数据
+func (s *Socket) fill() error {
+	return s.fillWithin(s.Timeout)
+}
+
+// fillWithin 在d时长内从连接读取一次数据追加到缓冲区，d为0时不限制
+func (s *Socket) fillWithin(d time.Duration) error {
+	if limit := s.client.config.MaxBodySize; limit > 0 && int64(len(s.buf)) >= limit {
+		return fmt.Errorf("未读取的数据超过上限 %d 字节", limit)
+	}
+
+	s.setDeadline(d)
+	if s.rbuf == nil {
+		s.rbuf = make([]byte, socketReadSize)
+	}
+	p := s.rbuf
+	n, err := s.conn.Read(p)
+	if n > 0 {
+		s.buf = append(s.buf, p[:n]...)
+		s.dumpData("<", p[:n])
+		if s.recv != nil {
+			s.recv.Write(p[:n])
+		}
+	}
+	if err != nil && n == 0 {
+		if !errors.Is(err, os.ErrDeadlineExceeded) && err != io.EOF {
+			s.fail(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// take 从缓冲区取出前n个字节
+func (s *Socket) take(n int) []byte {
+	data := make([]byte, n)
+	copy(data, s.buf[:n])
+	s.buf = s.buf[n:]
+	return data
+}
+
+func (s *Socket) setDeadline(d time.Duration) {
+	if d > 0 {
+		s.conn.SetDeadline(time.Now().Add(d))
+	} else {
+		s.conn.SetDeadline(time.Time{})
+	}
+}
+
+// fail 记录第一个错误
+func (s *Socket) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// dumpData 以十六进制形式输出收发的数据
+func (s *Socket) dumpData(dir string, data []byte) {
+	if s.client.dump == nil {
+		return
+	}
+	fmt.Fprintf(s.client.dump, "%s %s %d 字节\n%s", dir, s.addr, len(data), hex.Dump(data))
+}
+
+// finish 释放限速名额并添加流量记录
+func (s *Socket) finish() {
+	if s.release != nil {
+		s.release()
+		s.release = nil
+	}
+
+	log := s.client.config.Traffic
+	if log == nil {
+		return
+	}
+
+	scheme := s.network
+	if s.tls {
+		scheme = "tls"
+	}
+
+	e := &TrafficEntry{
+		Tags:      s.client.tags,
+		StartedAt: s.started,
+		Duration:  time.Since(s.started),
+		Socket:    true,
+		Method:    strings.ToUpper(s.network),
+		URL:       scheme + "://" + s.addr,
+		Proto:     s.network,
+	}
+	if s.sent != nil {
+		e.RequestBody = s.sent.Bytes()
+		e.ResponseBody = s.recv.Bytes()
+		e.Truncated = s.sent.truncated || s.recv.truncated
+		e.Status = fmt.Sprintf("发送 %d 字节, 接收 %d 字节", s.sent.Len(), s.recv.Len())
+	}
+	if s.err != nil {
+		e.Error = s.err.Error()
+	}
+
+	log.Add(e)
+}
@@ -0,0 +1,279 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tcpServer 在回环地址上接受一个连接并执行script
+func tcpServer(t *testing.T, script func(conn net.Conn)) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+		script(conn)
+	}()
+	return ln.Addr().String()
+}
+
+// writeChunks 分多次写入，每次之间稍作停顿，使客户端分开读到
+func writeChunks(conn net.Conn, chunks ...string) {
+	for _, c := range chunks {
+		conn.Write([]byte(c))
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func socketClient(config HTTPClientConfig) *SocketClient {
+	config.Timeout = time.Second
+	return NewSocketClient(config)
+}
+
+func dialSocket(t *testing.T, addr string, config HTTPClientConfig) *Socket {
+	t.Helper()
+
+	s, err := socketClient(config).Dial(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// testCertificate 返回httptest自带的自签名证书
+func testCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(nil)
+	srv.StartTLS()
+	defer srv.Close()
+	return srv.TLS.Certificates[0]
+}
+
+func TestSocketReadUntilSplitDelimiter(t *testing.T) {
+	addr := tcpServer(t, func(conn net.Conn) {
+		writeChunks(conn, "+OK hel", "lo\r", "\n-ERR", " next\r\n")
+	})
+	s := dialSocket(t, addr, DefaultHTTPClientConfig())
+
+	for _, want := range []string{"+OK hello\r\n", "-ERR next\r\n"} {
+		got, err := s.ReadUntil([]byte("\r\n"))
+		if err != nil || string(got) != want {
+			t.Fatalf("ReadUntil = %q, %v, want %q", got, err, want)
+		}
+	}
+	if _, err := s.ReadUntil(nil); err == nil {
+		t.Error("empty delimiter accepted")
+	}
+}
+
+func TestSocketReadN(t *testing.T) {
+	addr := tcpServer(t, func(conn net.Conn) {
+		writeChunks(conn, "\x00\x00", "\x00\x05abc", "de", "tail")
+	})
+	s := dialSocket(t, addr, DefaultHTTPClientConfig())
+
+	head, err := s.ReadN(4)
+	if err != nil || string(head) != "\x00\x00\x00\x05" {
+		t.Fatalf("ReadN(4) = %q, %v", head, err)
+	}
+	body, err := s.ReadN(5)
+	if err != nil || string(body) != "abcde" {
+		t.Fatalf("ReadN(5) = %q, %v", body, err)
+	}
+	if _, err := s.ReadN(10); err == nil {
+		t.Error("ReadN past the end of the stream succeeded")
+	}
+}
+
+func TestSocketReadRegex(t *testing.T) {
+	addr := tcpServer(t, func(conn net.Conn) {
+		writeChunks(conn, "220 mail.example.com ES", "MTP ready\r\n250 OK\r\n")
+	})
+	s := dialSocket(t, addr, DefaultHTTPClientConfig())
+
+	got, err := s.ReadRegex(regexp.MustCompile(`ESMTP[^\r\n]*\r\n`))
+	if err != nil || string(got) != "220 mail.example.com ESMTP ready\r\n" {
+		t.Fatalf("ReadRegex = %q, %v", got, err)
+	}
+	line, err := s.ReadLine()
+	if err != nil || line != "250 OK" {
+		t.Errorf("ReadLine after ReadRegex = %q, %v", line, err)
+	}
+}
+
+func TestSocketReadIdle(t *testing.T) {
+	addr := tcpServer(t, func(conn net.Conn) {
+		writeChunks(conn, "SSH-2.0-", "OpenSSH_9.6\r\n")
+		// 保持连接打开，ReadIdle 应在空闲后返回
+		time.Sleep(500 * time.Millisecond)
+	})
+	s := dialSocket(t, addr, DefaultHTTPClientConfig())
+
+	start := time.Now()
+	got, err := s.ReadIdle(100 * time.Millisecond)
+	if err != nil || string(got) != "SSH-2.0-OpenSSH_9.6\r\n" {
+		t.Fatalf("ReadIdle = %q, %v", got, err)
+	}
+	if d := time.Since(start); d > 400*time.Millisecond {
+		t.Errorf("ReadIdle returned after %s, want shortly after the idle period", d)
+	}
+}
+
+func TestSocketBanner(t *testing.T) {
+	addr := tcpServer(t, func(conn net.Conn) {
+		conn.Write([]byte("* OK IMAP4rev1 ready\r\n"))
+	})
+
+	got, err := socketClient(DefaultHTTPClientConfig()).Banner(context.Background(), "tcp", addr, 200*time.Millisecond)
+	if err != nil || string(got) != "* OK IMAP4rev1 ready\r\n" {
+		t.Errorf("Banner = %q, %v", got, err)
+	}
+}
+
+func TestSocketStartTLS(t *testing.T) {
+	cert := testCertificate(t)
+	addr := tcpServer(t, func(conn net.Conn) {
+		br := bufio.NewReader(conn)
+		conn.Write([]byte("220 ready\r\n"))
+		if line, _ := br.ReadString('\n'); line != "STARTTLS\r\n" {
+			return
+		}
+		conn.Write([]byte("220 go ahead\r\n"))
+
+		tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+		if err := tlsConn.Handshake(); err != nil {
+			return
+		}
+		line, _ := bufio.NewReader(tlsConn).ReadString('\n')
+		tlsConn.Write([]byte("250 secure " + line))
+	})
+
+	config := DefaultHTTPClientConfig()
+	config.TLS.InsecureSkipVerify = true
+	s := dialSocket(t, addr, config)
+
+	if line, err := s.ReadLine(); err != nil || line != "220 ready" {
+		t.Fatalf("greeting = %q, %v", line, err)
+	}
+	s.SendString("STARTTLS\r\n")
+	if line, err := s.ReadLine(); err != nil || line != "220 go ahead" {
+		t.Fatalf("STARTTLS reply = %q, %v", line, err)
+	}
+	if err := s.StartTLS("mail.example.com"); err != nil {
+		t.Fatalf("StartTLS: %v", err)
+	}
+	if _, ok := s.Conn().(*tls.Conn); !ok {
+		t.Fatalf("conn after StartTLS = %T", s.Conn())
+	}
+	s.SendString("EHLO\r\n")
+	if line, err := s.ReadLine(); err != nil || line != "250 secure EHLO" {
+		t.Errorf("reply over TLS = %q, %v", line, err)
+	}
+}
+
+func TestSocketStartTLSWithUnreadData(t *testing.T) {
+	addr := tcpServer(t, func(conn net.Conn) {
+		conn.Write([]byte("220 ready\r\n250 extra\r\n"))
+		time.Sleep(200 * time.Millisecond)
+	})
+	s := dialSocket(t, addr, DefaultHTTPClientConfig())
+
+	if _, err := s.ReadN(5); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.StartTLS(""); err == nil || !strings.Contains(err.Error(), "未读取") {
+		t.Errorf("StartTLS with buffered data = %v, want error", err)
+	}
+}
+
+func TestSocketMaxBodySize(t *testing.T) {
+	addr := tcpServer(t, func(conn net.Conn) {
+		conn.Write([]byte(strings.Repeat("A", 4096)))
+		time.Sleep(200 * time.Millisecond)
+	})
+	config := DefaultHTTPClientConfig()
+	config.MaxBodySize = 1024
+	s := dialSocket(t, addr, config)
+
+	// 数据中没有分隔符，缓冲超过上限后应停止读取
+	if _, err := s.ReadUntil([]byte("\n")); err == nil || !strings.Contains(err.Error(), "上限") {
+		t.Errorf("ReadUntil without delimiter = %v, want size limit error", err)
+	}
+}
+
+func TestSocketUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	go func() {
+		buf := make([]byte, 512)
+		n, from, err := pc.ReadFrom(buf)
+		if err == nil {
+			pc.WriteTo(append([]byte("echo "), buf[:n]...), from)
+		}
+	}()
+
+	log := NewTrafficLog(0)
+	config := DefaultHTTPClientConfig()
+	config.Traffic = log
+	s, err := socketClient(config).Dial(context.Background(), "udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SendString("ping")
+	got, err := s.ReadN(9)
+	s.Close()
+	if err != nil || string(got) != "echo ping" {
+		t.Fatalf("UDP reply = %q, %v", got, err)
+	}
+
+	entries := log.List(TrafficFilter{})
+	if len(entries) != 1 || !entries[0].Socket || entries[0].Method != "UDP" || string(entries[0].ResponseBody) != "echo ping" {
+		t.Errorf("traffic = %+v", entries)
+	}
+}
+
+func TestSocketUDPWithProxy(t *testing.T) {
+	config := DefaultHTTPClientConfig()
+	config.Proxy = "socks5://127.0.0.1:1"
+	config.NoProxy = []string{"10.0.0.0/8"}
+
+	_, err := socketClient(config).Dial(context.Background(), "udp", "192.0.2.1:53")
+	if !errors.Is(err, ErrUDPProxy) {
+		t.Errorf("UDP through proxy = %v, want ErrUDPProxy", err)
+	}
+
+	// NoProxy 中的主机仍然直连
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	config.NoProxy = []string{"127.0.0.1"}
+	s, err := socketClient(config).Dial(context.Background(), "udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("UDP to a no_proxy host: %v", err)
+	}
+	s.Close()
+}
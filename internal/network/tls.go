@@ -0,0 +1,134 @@
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSConfig 配置TLS连接
+type TLSConfig struct {
+	// InsecureSkipVerify 跳过证书校验，用于自签名或过期证书的目标
+	InsecureSkipVerify bool
+	// CAFile PEM格式的CA证书包，追加到系统根证书之后
+	CAFile string
+	// CertFile 和 KeyFile 是双向TLS使用的客户端证书和私钥
+	CertFile string
+	KeyFile  string
+	// ServerName 覆盖SNI和证书校验使用的主机名
+	ServerName string
+	// MinVersion 和 MaxVersion 限制TLS版本，取值如 "1.0"、"1.2"、"1.3"
+	MinVersion string
+	MaxVersion string
+	// CipherSuites 指定TLS 1.2及以下版本使用的密码套件名称
+	CipherSuites []string
+}
+
+// Build 根据配置创建tls.Config，未做任何配置时返回nil
+func (c TLSConfig) Build() (*tls.Config, error) {
+	if c.isZero() {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		ServerName:         c.ServerName,
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取CA证书失败: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA证书文件中没有有效的证书: %s", c.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, fmt.Errorf("客户端证书和私钥必须同时指定")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	var err error
+	if config.MinVersion, err = ParseTLSVersion(c.MinVersion); err != nil {
+		return nil, err
+	}
+	if config.MaxVersion, err = ParseTLSVersion(c.MaxVersion); err != nil {
+		return nil, err
+	}
+	if config.MinVersion != 0 && config.MaxVersion != 0 && config.MinVersion > config.MaxVersion {
+		return nil, fmt.Errorf("TLS最低版本 %s 高于最高版本 %s", c.MinVersion, c.MaxVersion)
+	}
+
+	if len(c.CipherSuites) > 0 {
+		if config.CipherSuites, err = ParseCipherSuites(c.CipherSuites); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// isZero 判断是否没有任何TLS配置
+func (c TLSConfig) isZero() bool {
+	return !c.InsecureSkipVerify && c.CAFile == "" && c.CertFile == "" && c.KeyFile == "" &&
+		c.ServerName == "" && c.MinVersion == "" && c.MaxVersion == "" && len(c.CipherSuites) == 0
+}
+
+// ParseTLSVersion 解析TLS版本名称，空字符串返回0表示使用默认值
+func ParseTLSVersion(name string) (uint16, error) {
+	v := strings.ToLower(strings.TrimSpace(name))
+	v = strings.TrimPrefix(strings.TrimPrefix(v, "tls"), "v")
+
+	switch v {
+	case "":
+		return 0, nil
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("未知的TLS版本: %s", name)
+	}
+}
+
+// ParseCipherSuites 将密码套件名称转换为ID，名称与tls.CipherSuiteName一致
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+	for _, cs := range tls.InsecureCipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("未知的密码套件: %s", name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,282 @@
+package network
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writePEM 将PEM块写入临时目录中的文件并返回路径
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// clientCertificate 生成自签名的客户端证书，返回证书和私钥文件的路径以及证书本身
+func clientCertificate(t *testing.T, cn string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ = x509.ParseCertificate(der)
+
+	return writePEM(t, "client.crt", "CERTIFICATE", der), writePEM(t, "client.key", "EC PRIVATE KEY", keyDER), cert
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		want uint16
+		ok   bool
+	}{
+		{"", 0, true},
+		{"1.0", tls.VersionTLS10, true},
+		{"1.1", tls.VersionTLS11, true},
+		{"1.2", tls.VersionTLS12, true},
+		{" TLS1.3 ", tls.VersionTLS13, true},
+		{"tlsv1.2", tls.VersionTLS12, true},
+		{"13", tls.VersionTLS13, true},
+		{"ssl3", 0, false},
+		{"1.4", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseTLSVersion(tt.name)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseTLSVersion(%q) = %x, %v, want %x", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	ids, err := ParseCipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", " tls_rsa_with_rc4_128_sha "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_RC4_128_SHA}
+	if len(ids) != 2 || ids[0] != want[0] || ids[1] != want[1] {
+		t.Errorf("ParseCipherSuites = %x, want %x", ids, want)
+	}
+
+	if _, err := ParseCipherSuites([]string{"TLS_NOPE"}); err == nil || !strings.Contains(err.Error(), "未知的密码套件") {
+		t.Errorf("unknown suite error = %v", err)
+	}
+}
+
+func TestTLSConfigBuild(t *testing.T) {
+	if config, err := (TLSConfig{}).Build(); config != nil || err != nil {
+		t.Errorf("zero config = %v, %v, want nil", config, err)
+	}
+
+	certFile, keyFile, _ := clientCertificate(t, "luna")
+	notPEM := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0o600)
+
+	tests := []struct {
+		name   string
+		config TLSConfig
+		err    string
+	}{
+		{"ca missing", TLSConfig{CAFile: "/nonexistent/ca.pem"}, "读取CA证书失败"},
+		{"ca without certificates", TLSConfig{CAFile: notPEM}, "没有有效的证书"},
+		{"cert without key", TLSConfig{CertFile: certFile}, "必须同时指定"},
+		{"key without cert", TLSConfig{KeyFile: keyFile}, "必须同时指定"},
+		{"mismatched pair", TLSConfig{CertFile: certFile, KeyFile: notPEM}, "加载客户端证书失败"},
+		{"bad min version", TLSConfig{MinVersion: "2.0"}, "未知的TLS版本"},
+		{"min above max", TLSConfig{MinVersion: "1.3", MaxVersion: "1.2"}, "高于最高版本"},
+		{"bad cipher", TLSConfig{CipherSuites: []string{"RC5"}}, "未知的密码套件"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.config.Build(); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Build error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+
+	config, err := TLSConfig{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ServerName:   "sni.test",
+		MinVersion:   "1.1",
+		MaxVersion:   "1.2",
+		CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+	}.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Certificates) != 1 || config.ServerName != "sni.test" ||
+		config.MinVersion != tls.VersionTLS11 || config.MaxVersion != tls.VersionTLS12 ||
+		len(config.CipherSuites) != 1 || config.InsecureSkipVerify {
+		t.Errorf("Build = %+v", config)
+	}
+}
+
+// tlsGet 使用给定的TLS配置请求url
+func tlsGet(url string, config TLSConfig) (*HTTPResponse, error) {
+	c := DefaultHTTPClientConfig()
+	c.MaxRetries = 0
+	c.TLS = config
+	return NewHTTPClient(c).Get(context.Background(), url, nil)
+}
+
+func TestTLSVerification(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// httptest的证书是自签名的，签发给example.com和127.0.0.1
+	caFile := writePEM(t, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+
+	tests := []struct {
+		name   string
+		config TLSConfig
+		err    string
+	}{
+		{"untrusted", TLSConfig{}, "certificate"},
+		{"skip verify", TLSConfig{InsecureSkipVerify: true}, ""},
+		{"custom ca", TLSConfig{CAFile: caFile}, ""},
+		{"server name in certificate", TLSConfig{CAFile: caFile, ServerName: "example.com"}, ""},
+		{"server name mismatch", TLSConfig{CAFile: caFile, ServerName: "wrong.test"}, "wrong.test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tlsGet(srv.URL, tt.config)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 响应中保存对端证书链
+			certs := resp.PeerCertificates()
+			if len(certs) == 0 || !certs[0].Equal(srv.Certificate()) {
+				t.Errorf("peer certificates = %d, want the server certificate", len(certs))
+			}
+		})
+	}
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+	if resp, err := tlsGet(plain.URL, TLSConfig{}); err != nil || resp.TLS != nil || resp.PeerCertificates() != nil {
+		t.Errorf("plain HTTP response has TLS state: %v", err)
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	certFile, keyFile, cert := clientCertificate(t, "luna-client")
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	resp, err := tlsGet(srv.URL, TLSConfig{InsecureSkipVerify: true, CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("mutual TLS: %v", err)
+	}
+	if string(resp.Body) != "luna-client" {
+		t.Errorf("server saw client %q", resp.Body)
+	}
+
+	if _, err := tlsGet(srv.URL, TLSConfig{InsecureSkipVerify: true}); err == nil {
+		t.Error("request without a client certificate succeeded")
+	}
+
+	// 原始连接使用同一客户端证书
+	config := DefaultHTTPClientConfig()
+	config.TLS = TLSConfig{InsecureSkipVerify: true, CertFile: certFile, KeyFile: keyFile}
+	conn, err := NewDialer(config).DialTLSContext(context.Background(), "tcp", srv.Listener.Addr().String(), "")
+	if err != nil {
+		t.Fatalf("DialTLSContext: %v", err)
+	}
+	conn.Close()
+}
+
+func TestTLSVersionsAndCiphers(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+
+	resp, err := tlsGet(srv.URL, TLSConfig{
+		InsecureSkipVerify: true,
+		MaxVersion:         "1.2",
+		CipherSuites:       []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TLS.Version != tls.VersionTLS12 || resp.TLS.CipherSuite != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+		t.Errorf("negotiated %s %s", tls.VersionName(resp.TLS.Version), tls.CipherSuiteName(resp.TLS.CipherSuite))
+	}
+
+	// 服务器最高只支持TLS 1.2
+	if _, err := tlsGet(srv.URL, TLSConfig{InsecureSkipVerify: true, MinVersion: "1.3"}); err == nil {
+		t.Error("TLS 1.3 minimum accepted by a TLS 1.2 server")
+	}
+
+	// SNI覆盖同样作用于原始连接
+	sni := make(chan string, 1)
+	srv2 := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv2.TLS = &tls.Config{GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		sni <- hello.ServerName
+		return nil, nil
+	}}
+	srv2.StartTLS()
+	defer srv2.Close()
+
+	config := DefaultHTTPClientConfig()
+	config.TLS = TLSConfig{InsecureSkipVerify: true, ServerName: "vhost.test"}
+	conn, err := NewDialer(config).DialTLSContext(context.Background(), "tcp", srv2.Listener.Addr().String(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if got := <-sni; got != "vhost.test" {
+		t.Errorf("SNI = %q, want vhost.test", got)
+	}
+}
@@ -0,0 +1,161 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing 是一次请求各阶段的耗时
+// 复用连接时DNS、Connect和TLS为0；经过代理时Connect是连接代理的耗时
+type Timing struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// TTFB 是从开始请求（包括建立连接）到收到响应首字节的耗时，无法区分首字节时为0
+	TTFB time.Duration
+	// Total 是从开始请求到读完响应体的耗时
+	Total time.Duration
+}
+
+// requestTrace 通过httptrace记录请求各阶段的时间点和所用连接
+// 跟随重定向或重试时每次获取连接都会重新开始，因此保留的是最后一次请求
+type requestTrace struct {
+	mu sync.Mutex
+
+	start               time.Time
+	dnsStart, dnsDone   time.Time
+	connStart, connDone time.Time
+	tlsStart, tlsDone   time.Time
+	firstByte           time.Time
+	remote              string
+}
+
+// withTrace 将本记录的钩子附加到上下文，与上下文中已有的钩子组合
+func (t *requestTrace) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, t.clientTrace())
+}
+
+// clientTrace 返回写入本记录的httptrace钩子
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.begin()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.stamp(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.stamp(&t.dnsDone)
+		},
+		// 同时尝试多个地址时取第一次开始和最后一次完成
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			if t.connStart.IsZero() {
+				t.connStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			t.stamp(&t.connDone)
+		},
+		TLSHandshakeStart: func() {
+			t.stamp(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.stamp(&t.tlsDone)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.remote = info.Conn.RemoteAddr().String()
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.stamp(&t.firstByte)
+		},
+	}
+}
+
+// begin 清空之前的记录并开始计时
+func (t *requestTrace) begin() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.start = time.Now()
+	t.dnsStart, t.dnsDone = time.Time{}, time.Time{}
+	t.connStart, t.connDone = time.Time{}, time.Time{}
+	t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+	t.firstByte = time.Time{}
+}
+
+// stamp 将时间点设置为当前时间
+func (t *requestTrace) stamp(p *time.Time) {
+	t.mu.Lock()
+	*p = time.Now()
+	t.mu.Unlock()
+}
+
+// gotFirstByte 记录收到首字节的时间，只记录第一次
+func (t *requestTrace) gotFirstByte() {
+	t.mu.Lock()
+	if t.firstByte.IsZero() {
+		t.firstByte = time.Now()
+	}
+	t.mu.Unlock()
+}
+
+// setRemote 设置连接的对端地址，用于不经过http.Transport的连接
+func (t *requestTrace) setRemote(addr string) {
+	t.mu.Lock()
+	t.remote = addr
+	t.mu.Unlock()
+}
+
+// remoteAddr 返回连接的对端地址
+func (t *requestTrace) remoteAddr() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.remote
+}
+
+// timing 根据记录的时间点计算各阶段耗时，total 由调用方给出
+func (t *requestTrace) timing(total time.Duration) Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return Timing{
+		DNS:     between(t.dnsStart, t.dnsDone),
+		Connect: between(t.connStart, t.connDone),
+		TLS:     between(t.tlsStart, t.tlsDone),
+		TTFB:    between(t.start, t.firstByte),
+		Total:   total,
+	}
+}
+
+// between 返回两个时间点的间隔，任一时间点缺失时返回0
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// traceTLS 在自行完成的TLS握手之前触发上下文中的httptrace钩子，返回握手完成后调用的函数
+func traceTLS(ctx context.Context) func(tls.ConnectionState, error) {
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace == nil {
+		return func(tls.ConnectionState, error) {}
+	}
+
+	if trace.TLSHandshakeStart != nil {
+		trace.TLSHandshakeStart()
+	}
+	return func(state tls.ConnectionState, err error) {
+		if trace.TLSHandshakeDone != nil {
+			trace.TLSHandshakeDone(state, err)
+		}
+	}
+}
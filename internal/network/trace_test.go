@@ -0,0 +1,166 @@
+package network
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowServer 返回的HTTPS服务器在发送响应头和响应体之前各等待delay
+func slowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(delay)
+		w.Write([]byte("done"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTimingPhases(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	srv := slowServer(t, delay)
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	traffic := NewTrafficLog(0)
+	config := DefaultHTTPClientConfig()
+	config.TLS.InsecureSkipVerify = true
+	config.Traffic = traffic
+	c := NewHTTPClient(config)
+
+	// 使用域名以触发DNS解析
+	resp, err := c.Get(context.Background(), "https://localhost:"+port+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := resp.Timing
+	if tm.DNS <= 0 || tm.Connect <= 0 || tm.TLS <= 0 {
+		t.Errorf("new connection timing = %+v, want DNS, Connect and TLS", tm)
+	}
+	if tm.TTFB < delay || tm.TTFB < tm.DNS+tm.Connect+tm.TLS {
+		t.Errorf("TTFB %s, want at least %s and the connection setup", tm.TTFB, delay)
+	}
+	if tm.Total < tm.TTFB+delay || tm.Total != resp.Duration {
+		t.Errorf("Total %s, TTFB %s, Duration %s", tm.Total, tm.TTFB, resp.Duration)
+	}
+	if host, _, _ := net.SplitHostPort(resp.RemoteAddr); net.ParseIP(host) == nil || !strings.HasSuffix(resp.RemoteAddr, ":"+port) {
+		t.Errorf("RemoteAddr = %q", resp.RemoteAddr)
+	}
+
+	// 复用连接时没有DNS、建立连接和TLS握手
+	resp, err = c.Get(context.Background(), "https://localhost:"+port+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm = resp.Timing
+	if tm.DNS != 0 || tm.Connect != 0 || tm.TLS != 0 || tm.TTFB < delay || tm.Total < tm.TTFB {
+		t.Errorf("reused connection timing = %+v", tm)
+	}
+
+	// 流量记录保存同样的耗时和对端地址
+	entries := traffic.List(TrafficFilter{})
+	if len(entries) != 2 {
+		t.Fatalf("entries = %d", len(entries))
+	}
+	first := entries[0]
+	if first.Timing.TLS <= 0 || first.Timing.TTFB < delay || first.RemoteAddr == "" {
+		t.Errorf("recorded timing = %+v, remote %q", first.Timing, first.RemoteAddr)
+	}
+	har := harTimings(first)
+	if har.DNS < 0 || har.Connect < 0 || har.SSL < 0 || har.Wait < 0 || har.Receive < 0 {
+		t.Errorf("HAR timings = %+v", har)
+	}
+}
+
+func TestTimingPlainHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	resp, err := NewHTTPClient(DefaultHTTPClientConfig()).Get(context.Background(), srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// IP地址不需要解析，明文请求没有TLS握手
+	if tm := resp.Timing; tm.DNS != 0 || tm.TLS != 0 || tm.Connect <= 0 || tm.TTFB <= 0 {
+		t.Errorf("timing = %+v", tm)
+	}
+	if resp.RemoteAddr != srv.Listener.Addr().String() {
+		t.Errorf("RemoteAddr = %q, want %q", resp.RemoteAddr, srv.Listener.Addr())
+	}
+}
+
+func TestTimingFollowsRedirect(t *testing.T) {
+	final := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+	}))
+	defer final.Close()
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, final.URL, http.StatusFound)
+	}))
+	defer first.Close()
+
+	resp, err := NewHTTPClient(DefaultHTTPClientConfig()).Get(context.Background(), first.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 耗时和对端地址属于最终请求
+	if resp.RemoteAddr != final.Listener.Addr().String() {
+		t.Errorf("RemoteAddr = %q, want the final server %q", resp.RemoteAddr, final.Listener.Addr())
+	}
+	if resp.Timing.TTFB < 30*time.Millisecond || resp.Timing.Connect <= 0 {
+		t.Errorf("timing = %+v", resp.Timing)
+	}
+}
+
+func TestRawTiming(t *testing.T) {
+	addr := tcpServer(t, func(conn net.Conn) {
+		conn.Read(make([]byte, 1024))
+		time.Sleep(30 * time.Millisecond)
+		writeChunks(conn, "HTTP/1.1 200 OK\r\nContent-Length: 4\r\n\r\n", "done")
+	})
+
+	responses, err := rawClient().Send(context.Background(), &RawRequest{Addr: addr, Data: []byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := responses[0].Timing
+	if tm.Connect <= 0 || tm.TTFB < 30*time.Millisecond || tm.Total < tm.TTFB+20*time.Millisecond {
+		t.Errorf("timing = %+v", tm)
+	}
+	if responses[0].RemoteAddr != addr {
+		t.Errorf("RemoteAddr = %q, want %q", responses[0].RemoteAddr, addr)
+	}
+}
+
+func TestBetween(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       time.Duration
+	}{
+		{"both", now, now.Add(time.Second), time.Second},
+		{"no start", time.Time{}, now, 0},
+		{"no end", now, time.Time{}, 0},
+		{"reversed", now, now.Add(-time.Second), 0},
+	}
+	for _, tt := range tests {
+		if got := between(tt.start, tt.end); got != tt.want {
+			t.Errorf("%s: between = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// 没有首字节时间时HAR全部计入等待
+	e := &TrafficEntry{Duration: 1500 * time.Millisecond}
+	if har := harTimings(e); har.Wait != 1500 || har.DNS != -1 || har.Connect != -1 || har.SSL != -1 {
+		t.Errorf("HAR timings without trace = %+v", har)
+	}
+}
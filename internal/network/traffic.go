@@ -0,0 +1,378 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultTrafficEntries 是流量记录默认保留的条数
+	defaultTrafficEntries = 1000
+	// defaultTrafficBodyLimit 是每条记录中请求体和响应体各自保留的最大字节数
+	defaultTrafficBodyLimit = 1 << 20
+)
+
+// Tags 标记流量所属的扫描、插件和目标
+type Tags struct {
+	Scan   string
+	Plugin string
+	Target string
+}
+
+type tagsKey struct{}
+
+// WithTags 将流量标记附加到上下文
+func WithTags(ctx context.Context, tags Tags) context.Context {
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// TagsFromContext 读取上下文中的流量标记
+func TagsFromContext(ctx context.Context) Tags {
+	tags, _ := ctx.Value(tagsKey{}).(Tags)
+	return tags
+}
+
+// TrafficEntry 是一次HTTP交换或套接字会话的记录
+type TrafficEntry struct {
+	ID        int
+	Tags      Tags
+	StartedAt time.Time
+	Duration  time.Duration
+	// Timing 是HTTP交换各阶段的耗时，回放磁带或WebSocket握手以外的套接字会话时为零值
+	Timing Timing
+	// RemoteAddr 是连接的对端地址（IP:端口）
+	RemoteAddr string
+
+	Method         string
+	URL            string
+	Proto          string
+	RequestHeaders http.Header
+	RequestBody    []byte
+
+	StatusCode      int
+	Status          string
+	ResponseProto   string
+	ResponseHeaders http.Header
+	ResponseBody    []byte
+	// Truncated 表示请求体或响应体超过记录上限被截断
+	Truncated bool
+	// Socket 表示记录的是TCP/UDP会话，RequestBody和ResponseBody分别是发送和收到的全部数据
+	Socket bool
+	// WebSocket 表示记录的是WebSocket会话，请求和响应字段记录握手，Messages记录收发的帧
+	WebSocket bool
+	Messages  []TrafficMessage
+
+	Error string
+}
+
+// TrafficMessage 是WebSocket会话中收发的一个帧
+type TrafficMessage struct {
+	Time time.Time
+	// Sent 为true表示由客户端发送
+	Sent   bool
+	Opcode int
+	Data   []byte
+}
+
+// TrafficFilter 用于筛选流量记录，空字段不参与筛选
+type TrafficFilter struct {
+	Scan   string
+	Plugin string
+	Target string
+}
+
+// Match 判断记录是否符合筛选条件
+func (f TrafficFilter) Match(e *TrafficEntry) bool {
+	return (f.Scan == "" || e.Tags.Scan == f.Scan) &&
+		(f.Plugin == "" || e.Tags.Plugin == f.Plugin) &&
+		(f.Target == "" || e.Tags.Target == f.Target)
+}
+
+// TrafficLog 在内存中保存最近的流量记录，超过保留上限时丢弃最旧的记录
+type TrafficLog struct {
+	mu         sync.Mutex
+	entries    []*TrafficEntry
+	nextID     int
+	maxEntries int
+	bodyLimit  int
+}
+
+// NewTrafficLog 创建流量记录，maxEntries 为0时使用默认值
+func NewTrafficLog(maxEntries int) *TrafficLog {
+	if maxEntries <= 0 {
+		maxEntries = defaultTrafficEntries
+	}
+	return &TrafficLog{
+		nextID:     1,
+		maxEntries: maxEntries,
+		bodyLimit:  defaultTrafficBodyLimit,
+	}
+}
+
+// SetLimit 修改保留的记录条数
+func (l *TrafficLog) SetLimit(maxEntries int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if maxEntries <= 0 {
+		maxEntries = defaultTrafficEntries
+	}
+	l.maxEntries = maxEntries
+	l.trim()
+}
+
+// Add 添加一条记录并分配ID
+func (l *TrafficLog) Add(e *TrafficEntry) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.ID = l.nextID
+	l.nextID++
+	l.entries = append(l.entries, e)
+	l.trim()
+
+	return e.ID
+}
+
+// trim 丢弃超过保留上限的旧记录，调用方需持有锁
+func (l *TrafficLog) trim() {
+	if over := len(l.entries) - l.maxEntries; over > 0 {
+		copy(l.entries, l.entries[over:])
+		for i := len(l.entries) - over; i < len(l.entries); i++ {
+			l.entries[i] = nil
+		}
+		l.entries = l.entries[:len(l.entries)-over]
+	}
+}
+
+// Get 根据ID获取记录
+func (l *TrafficLog) Get(id int) (*TrafficEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, e := range l.entries {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// List 返回符合筛选条件的记录
+func (l *TrafficLog) List(filter TrafficFilter) []*TrafficEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var list []*TrafficEntry
+	for _, e := range l.entries {
+		if filter.Match(e) {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// Clear 清空所有记录
+func (l *TrafficLog) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = nil
+}
+
+// recordingTransport 记录经过的每一次HTTP交换，包括重定向和重试产生的请求
+type recordingTransport struct {
+	next http.RoundTripper
+	log  *TrafficLog
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limit := t.log.bodyLimit
+	entry := &TrafficEntry{
+		Tags:           TagsFromContext(req.Context()),
+		StartedAt:      time.Now(),
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		Proto:          req.Proto,
+		RequestHeaders: req.Header.Clone(),
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		entry.RequestHeaders.Set("Host", req.Host)
+	}
+
+	// 优先通过GetBody读取请求体，避免消费原始请求体
+	var reqBody *limitedBuffer
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				b, truncated := readLimited(body, limit)
+				body.Close()
+				entry.RequestBody, entry.Truncated = b, truncated
+			}
+		} else {
+			reqBody = &limitedBuffer{limit: limit}
+			req = req.Clone(req.Context())
+			req.Body = &teeReadCloser{r: io.TeeReader(req.Body, reqBody), c: req.Body}
+		}
+	}
+
+	// 每次交换单独记录各阶段耗时，与上层请求的钩子组合
+	trace := &requestTrace{}
+	req = req.WithContext(trace.withTrace(req.Context()))
+
+	resp, err := t.next.RoundTrip(req)
+
+	if reqBody != nil {
+		entry.RequestBody, entry.Truncated = reqBody.Bytes(), reqBody.truncated
+	}
+
+	if err != nil {
+		entry.Duration = time.Since(entry.StartedAt)
+		entry.Timing = trace.timing(entry.Duration)
+		entry.Error = err.Error()
+		t.log.Add(entry)
+		return nil, err
+	}
+
+	entry.RemoteAddr = trace.remoteAddr()
+	entry.StatusCode = resp.StatusCode
+	entry.Status = resp.Status
+	entry.ResponseProto = resp.Proto
+	entry.ResponseHeaders = resp.Header.Clone()
+
+	respBody := &limitedBuffer{limit: limit}
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		buf:        respBody,
+		done: func(readErr error) {
+			entry.Duration = time.Since(entry.StartedAt)
+			entry.Timing = trace.timing(entry.Duration)
+			entry.ResponseBody = respBody.Bytes()
+			entry.Truncated = entry.Truncated || respBody.truncated
+			if readErr != nil && readErr != io.EOF {
+				entry.Error = readErr.Error()
+			}
+			t.log.Add(entry)
+		},
+	}
+
+	return resp, nil
+}
+
+// limitedBuffer 最多保存limit字节，超出部分丢弃并标记截断
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if remain := b.limit - b.Len(); remain < len(p) {
+		if remain < 0 {
+			remain = 0
+		}
+		p = p[:remain]
+		b.truncated = true
+	}
+	b.Buffer.Write(p)
+	return n, nil
+}
+
+// readLimited 读取最多limit字节
+func readLimited(r io.Reader, limit int) ([]byte, bool) {
+	buf := &limitedBuffer{limit: limit}
+	io.Copy(buf, r)
+	return buf.Bytes(), buf.truncated
+}
+
+// teeReadCloser 在读取请求体时复制一份用于记录
+type teeReadCloser struct {
+	r io.Reader
+	c io.Closer
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) { return t.r.Read(p) }
+func (t *teeReadCloser) Close() error               { return t.c.Close() }
+
+// recordingBody 在响应体读取完毕或关闭时完成记录
+type recordingBody struct {
+	io.ReadCloser
+	buf  *limitedBuffer
+	once sync.Once
+	done func(error)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err != nil {
+		b.once.Do(func() { b.done(err) })
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(nil) })
+	return err
+}
+
+// taggedClient 为所有请求附加流量标记
+type taggedClient struct {
+	client HTTPClient
+	tags   Tags
+}
+
+// Tagged 返回一个为所有请求附加流量标记的HTTP客户端
+func Tagged(client HTTPClient, tags Tags) HTTPClient {
+	if client == nil {
+		return nil
+	}
+	return &taggedClient{client: client, tags: tags}
+}
+
+func (c *taggedClient) Get(ctx context.Context, url string, headers map[string]string) (*HTTPResponse, error) {
+	return c.client.Get(WithTags(ctx, c.tags), url, headers)
+}
+
+func (c *taggedClient) Post(ctx context.Context, url string, body interface{}, headers map[string]string) (*HTTPResponse, error) {
+	return c.client.Post(WithTags(ctx, c.tags), url, body, headers)
+}
+
+func (c *taggedClient) Put(ctx context.Context, url string, body interface{}, headers map[string]string) (*HTTPResponse, error) {
+	return c.client.Put(WithTags(ctx, c.tags), url, body, headers)
+}
+
+func (c *taggedClient) Delete(ctx context.Context, url string, headers map[string]string) (*HTTPResponse, error) {
+	return c.client.Delete(WithTags(ctx, c.tags), url, headers)
+}
+
+func (c *taggedClient) Do(req *http.Request) (*HTTPResponse, error) {
+	return c.client.Do(req.WithContext(WithTags(req.Context(), c.tags)))
+}
+
+func (c *taggedClient) Stream(req *http.Request) (*HTTPResponse, error) {
+	return c.client.Stream(req.WithContext(WithTags(req.Context(), c.tags)))
+}
+
+// Summary 返回记录的单行摘要
+func (e *TrafficEntry) Summary() string {
+	status := e.Status
+	if e.Error != "" {
+		status = "ERR " + e.Error
+	}
+
+	u := e.URL
+	if len(u) > 80 {
+		u = u[:77] + "..."
+	}
+
+	return fmt.Sprintf("%s %s -> %s", e.Method, u, status)
+}
@@ -0,0 +1,189 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// Protocol 指定HTTP客户端使用的协议版本
+type Protocol string
+
+const (
+	// ProtocolAuto 通过ALPN协商，HTTPS优先使用HTTP/2，明文使用HTTP/1.1
+	ProtocolAuto Protocol = ""
+	// ProtocolHTTP1 强制使用HTTP/1.1
+	ProtocolHTTP1 Protocol = "http1"
+	// ProtocolHTTP2 强制通过TLS使用HTTP/2
+	ProtocolHTTP2 Protocol = "h2"
+	// ProtocolH2C 明文请求使用h2c（先验知识），HTTPS请求使用HTTP/2
+	ProtocolH2C Protocol = "h2c"
+)
+
+// ParseProtocol 解析协议名称，支持 auto、http1、h2 和 h2c
+func ParseProtocol(name string) (Protocol, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "auto":
+		return ProtocolAuto, nil
+	case "http1", "http/1.1", "h1":
+		return ProtocolHTTP1, nil
+	case "h2", "http2", "http/2":
+		return ProtocolHTTP2, nil
+	case "h2c":
+		return ProtocolH2C, nil
+	default:
+		return "", fmt.Errorf("未知的协议: %s", name)
+	}
+}
+
+// newTransport 根据配置创建HTTP传输层
+func newTransport(config HTTPClientConfig) (http.RoundTripper, error) {
+	tlsConfig, err := config.TLS.Build()
+
+	switch config.Protocol {
+	case ProtocolHTTP2:
+		return newHTTP2Transport(config, false), err
+	case ProtocolH2C:
+		return &h2cTransport{
+			h2:  newHTTP2Transport(config, false),
+			h2c: newHTTP2Transport(config, true),
+		}, err
+	}
+
+	// 代理由http.Transport处理，Dialer只负责按hosts映射和DNS配置直连；
+	// 配置了hosts映射或自定义DNS时改由Dialer建立代理隧道，先在本地解析再交给代理
+	dialer := NewDialer(config)
+	proxy, dial := proxyFunc(config.Proxy, config.NoProxy), dialer.dialDirect
+	if config.Proxy != "" && dialer.resolver.active() {
+		proxy, dial = nil, dialer.DialContext
+	}
+
+	transport := &http.Transport{
+		Proxy:             proxy,
+		DialContext:       dial,
+		TLSClientConfig:   tlsConfig,
+		ForceAttemptHTTP2: true,
+		// 由decodingTransport统一解码gzip、deflate和br
+		DisableCompression:    true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	if config.Protocol == ProtocolHTTP1 {
+		// 非空的TLSNextProto会禁用HTTP/2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &pinnableTransport{Transport: transport}, err
+}
+
+// connPinKey 是请求上下文中connPin的键
+type connPinKey struct{}
+
+// connPin 保存一次连接级握手（如NTLM）专用的传输层
+// 专用传输层每个主机只有一条HTTP/1.1连接，握手的所有请求都经由同一条连接
+type connPin struct {
+	mu        sync.Mutex
+	transport *http.Transport
+}
+
+// withConnPin 返回携带connPin的上下文
+func withConnPin(ctx context.Context, pin *connPin) context.Context {
+	return context.WithValue(ctx, connPinKey{}, pin)
+}
+
+// get 返回专用传输层，首次调用时从base复制
+func (p *connPin) get(base *http.Transport) *http.Transport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.transport == nil {
+		t := base.Clone()
+		t.MaxConnsPerHost = 1
+		t.MaxIdleConnsPerHost = 1
+		// 连接级认证不能用于HTTP/2
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		p.transport = t
+	}
+	return p.transport
+}
+
+// close 关闭专用传输层的空闲连接
+func (p *connPin) close() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.transport != nil {
+		p.transport.CloseIdleConnections()
+	}
+}
+
+// pinnableTransport 在请求上下文携带connPin时改用其专用传输层
+type pinnableTransport struct {
+	*http.Transport
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (t *pinnableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if pin, ok := req.Context().Value(connPinKey{}).(*connPin); ok {
+		return pin.get(t.Transport).RoundTrip(req)
+	}
+	return t.Transport.RoundTrip(req)
+}
+
+// newHTTP2Transport 创建只使用HTTP/2的传输层，连接经由Dialer建立以支持代理
+// cleartext为true时不进行TLS握手，直接以h2c先验知识方式通信
+func newHTTP2Transport(config HTTPClientConfig, cleartext bool) *http2.Transport {
+	dialer := NewDialer(config)
+
+	return &http2.Transport{
+		AllowHTTP:          cleartext,
+		DisableCompression: true,
+		ReadIdleTimeout:    30 * time.Second,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil || cleartext {
+				return conn, err
+			}
+
+			tlsConn, err := dialer.clientTLS(ctx, conn, addr, "", []string{http2.NextProtoTLS})
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			if p := tlsConn.ConnectionState().NegotiatedProtocol; p != http2.NextProtoTLS {
+				tlsConn.Close()
+				return nil, fmt.Errorf("服务器 %s 不支持HTTP/2 (ALPN: %q)", addr, p)
+			}
+			return tlsConn, nil
+		},
+	}
+}
+
+// h2cTransport 将明文请求交给h2c传输层，HTTPS请求交给HTTP/2传输层
+type h2cTransport struct {
+	h2  *http2.Transport
+	h2c *http2.Transport
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (t *h2cTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.h2c.RoundTrip(req)
+	}
+	return t.h2.RoundTrip(req)
+}
@@ -0,0 +1,631 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket操作码 (RFC 6455)
+const (
+	WSContinuation = 0x0
+	WSText         = 0x1
+	WSBinary       = 0x2
+	WSClose        = 0x8
+	WSPing         = 0x9
+	WSPong         = 0xA
+)
+
+// wsGUID 用于计算Sec-WebSocket-Accept
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsMaxMessageSize 是MaxBodySize为0（不限制）时单个帧和合并后消息的上限，
+// 避免对端声明的超大长度导致内存耗尽
+const wsMaxMessageSize = 64 << 20
+
+// WSMessage 是收到的一条WebSocket消息，分片消息会被合并
+type WSMessage struct {
+	// Type 是WSText、WSBinary或WSPong
+	Type int
+	Data []byte
+}
+
+// Text 以字符串形式返回消息内容
+func (m *WSMessage) Text() string {
+	return string(m.Data)
+}
+
+// WSCloseError 表示对端发送了关闭帧
+type WSCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WSCloseError) Error() string {
+	return fmt.Sprintf("WebSocket连接已关闭: %d %s", e.Code, e.Reason)
+}
+
+// WSHandshakeError 表示服务器没有接受WebSocket握手
+type WSHandshakeError struct {
+	// Response 是服务器返回的响应，可用于判断认证要求等
+	Response *HTTPResponse
+	Reason   string
+}
+
+func (e *WSHandshakeError) Error() string {
+	return fmt.Sprintf("WebSocket握手失败: %s (状态码 %d)", e.Reason, e.Response.StatusCode)
+}
+
+// WebSocketClient 建立WebSocket连接
+// 与HTTP客户端共享代理、TLS、hosts映射、默认请求头、限速和流量记录
+type WebSocketClient struct {
+	config HTTPClientConfig
+	dialer *Dialer
+	tags   Tags
+}
+
+// NewWebSocketClient 创建一个新的WebSocket客户端
+func NewWebSocketClient(config HTTPClientConfig) *WebSocketClient {
+	return &WebSocketClient{
+		config: config,
+		dialer: NewDialer(config),
+	}
+}
+
+// WithTags 返回使用指定流量标记的客户端副本
+func (c *WebSocketClient) WithTags(tags Tags) *WebSocketClient {
+	cp := *c
+	cp.tags = tags
+	return &cp
+}
+
+// Dial 连接到ws://或wss://地址并完成握手，headers 可设置Origin、Cookie、Sec-WebSocket-Protocol等
+func (c *WebSocketClient) Dial(ctx context.Context, rawURL string, headers map[string]string) (*WebSocket, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	useTLS := false
+	switch strings.ToLower(u.Scheme) {
+	case "ws", "http":
+	case "wss", "https":
+		useTLS = true
+	default:
+		return nil, fmt.Errorf("不支持的WebSocket协议: %s", u.Scheme)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if useTLS {
+			port = "443"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	ws := &WebSocket{
+		Timeout: c.config.Timeout,
+		client:  c,
+		started: time.Now(),
+	}
+	if c.config.Traffic != nil {
+		ws.recordLimit = c.config.Traffic.bodyLimit
+	}
+
+	if _, ok := ctx.Deadline(); !ok && c.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+
+	if c.config.RateLimiter != nil {
+		release, err := c.config.RateLimiter.Acquire(ctx, u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		ws.release = release
+	}
+
+	trace := &requestTrace{}
+	trace.begin()
+	ctx = trace.withTrace(ctx)
+
+	req := c.handshakeRequest(u, useTLS, headers)
+	ws.req = req
+	ws.url = req.URL.String()
+
+	if useTLS {
+		ws.conn, err = c.dialer.DialTLSContext(ctx, "tcp", addr, "")
+	} else {
+		ws.conn, err = c.dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		ws.fail(err)
+		ws.finish()
+		return nil, err
+	}
+
+	if err := ws.handshake(ctx, trace); err != nil {
+		ws.fail(err)
+		ws.conn.Close()
+		ws.finish()
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+// handshakeRequest 创建握手请求，使用默认请求头并由headers覆盖
+func (c *WebSocketClient) handshakeRequest(u *url.URL, useTLS bool, headers map[string]string) *http.Request {
+	target := *u
+	target.Scheme = "ws"
+	if useTLS {
+		target.Scheme = "wss"
+	}
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &target,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+
+	for k, v := range c.config.DefaultHeaders {
+		req.Header.Set(k, v)
+	}
+
+	key := make([]byte, 16)
+	rand.Read(key)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key))
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	for k, v := range headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	return req
+}
+
+// WebSocket 是一个已完成握手的WebSocket连接
+// 读取和发送可以在不同的goroutine中进行，但同一时间只能有一个goroutine读取
+type WebSocket struct {
+	// Timeout 是每次读写操作的超时，为0时不限制
+	Timeout time.Duration
+	// Handshake 是服务器的握手响应
+	Handshake *HTTPResponse
+
+	client *WebSocketClient
+	conn   net.Conn
+	br     *bufio.Reader
+	req    *http.Request
+	url    string
+
+	// msgType 和 partial 是尚未接收完的分片消息，读取到控制帧时保留
+	msgType int
+	partial []byte
+
+	// wmu 保护写操作，自动回复的pong与用户发送的消息不会交错
+	wmu       sync.Mutex
+	closeSent bool
+	// mu 保护以下记录状态
+	mu          sync.Mutex
+	messages    []TrafficMessage
+	recorded    int
+	recordLimit int
+	truncated   bool
+	err         error
+	closed      bool
+
+	release func()
+	started time.Time
+}
+
+// handshake 发送握手请求并校验响应，各阶段耗时记录在trace中
+func (ws *WebSocket) handshake(ctx context.Context, trace *requestTrace) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		ws.conn.SetDeadline(deadline)
+		defer ws.conn.SetDeadline(time.Time{})
+	}
+
+	// 请求行使用路径而不是绝对URI
+	wire := ws.req.Clone(ctx)
+	wire.URL = &url.URL{Path: ws.req.URL.Path, RawPath: ws.req.URL.RawPath, RawQuery: ws.req.URL.RawQuery}
+	if wire.URL.Path == "" {
+		wire.URL.Path = "/"
+	}
+	if err := wire.Write(ws.conn); err != nil {
+		return fmt.Errorf("发送WebSocket握手失败: %w", err)
+	}
+
+	ws.br = bufio.NewReader(ws.conn)
+	if _, err := ws.br.Peek(1); err == nil {
+		trace.gotFirstByte()
+	}
+	resp, err := http.ReadResponse(ws.br, ws.req)
+	if err != nil {
+		return fmt.Errorf("读取WebSocket握手响应失败: %w", err)
+	}
+
+	ws.Handshake = &HTTPResponse{
+		StatusCode: resp.StatusCode,
+		Proto:      resp.Proto,
+		Headers:    resp.Header,
+		Request:    ws.req,
+		FinalURL:   ws.url,
+		RemoteAddr: ws.conn.RemoteAddr().String(),
+		Duration:   time.Since(ws.started),
+	}
+	ws.Handshake.Timing = trace.timing(ws.Handshake.Duration)
+	if tlsConn, ok := ws.conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		ws.Handshake.TLS = &state
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		ws.Handshake.Body, ws.Handshake.Truncated, _ = readBody(resp.Body, ws.client.config.MaxBodySize)
+		resp.Body.Close()
+		ws.Handshake.Body = decodeBody(resp.Header, ws.Handshake.Body)
+		return &WSHandshakeError{Response: ws.Handshake, Reason: "服务器未切换协议"}
+	}
+
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		return &WSHandshakeError{Response: ws.Handshake, Reason: "响应缺少 Upgrade: websocket"}
+	}
+
+	sum := sha1.Sum([]byte(ws.req.Header.Get("Sec-WebSocket-Key") + wsGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		return &WSHandshakeError{Response: ws.Handshake, Reason: "Sec-WebSocket-Accept 不匹配"}
+	}
+
+	return nil
+}
+
+// RemoteAddr 返回对端地址
+func (ws *WebSocket) RemoteAddr() string {
+	return ws.conn.RemoteAddr().String()
+}
+
+// Conn 返回底层连接
+func (ws *WebSocket) Conn() net.Conn {
+	return ws.conn
+}
+
+// SendText 发送文本消息
+func (ws *WebSocket) SendText(text string) error {
+	return ws.SendFrame(WSText, []byte(text), true)
+}
+
+// SendBinary 发送二进制消息
+func (ws *WebSocket) SendBinary(data []byte) error {
+	return ws.SendFrame(WSBinary, data, true)
+}
+
+// Ping 发送ping帧，对端的pong会作为WSPong类型的消息由Read返回
+func (ws *WebSocket) Ping(data []byte) error {
+	return ws.SendFrame(WSPing, data, true)
+}
+
+// SendFrame 发送单个帧，可用于构造分片消息、保留操作码或超长控制帧等畸形数据
+func (ws *WebSocket) SendFrame(opcode int, data []byte, fin bool) error {
+	var frame []byte
+
+	b0 := byte(opcode & 0x0F)
+	if fin {
+		b0 |= 0x80
+	}
+	frame = append(frame, b0)
+
+	// 客户端发送的帧必须加掩码
+	switch n := len(data); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	mask := make([]byte, 4)
+	rand.Read(mask)
+	frame = append(frame, mask...)
+	start := len(frame)
+	frame = append(frame, data...)
+	for i := range data {
+		frame[start+i] ^= mask[i%4]
+	}
+
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+
+	if ws.Timeout > 0 {
+		ws.conn.SetWriteDeadline(time.Now().Add(ws.Timeout))
+	} else {
+		ws.conn.SetWriteDeadline(time.Time{})
+	}
+	if _, err := ws.conn.Write(frame); err != nil {
+		ws.fail(err)
+		return err
+	}
+	if opcode == WSClose {
+		ws.closeSent = true
+	}
+
+	ws.record(true, opcode, data)
+	return nil
+}
+
+// Read 读取下一条消息，分片消息会被合并
+// 收到ping时自动回复pong；分片之间收到的pong会先返回，未接收完的分片保留到下次读取；
+// 收到关闭帧时回复关闭帧并返回*WSCloseError
+func (ws *WebSocket) Read() (*WSMessage, error) {
+	for {
+		fin, opcode, data, err := ws.readFrame()
+		if err != nil {
+			if !errors.Is(err, os.ErrDeadlineExceeded) && err != io.EOF {
+				ws.fail(err)
+			}
+			return nil, err
+		}
+		ws.record(false, opcode, data)
+
+		if opcode >= WSClose && !fin {
+			return nil, ws.protocolError(fmt.Errorf("收到分片的控制帧 (操作码 0x%X)", opcode))
+		}
+
+		switch opcode {
+		case WSPing:
+			if err := ws.SendFrame(WSPong, data, true); err != nil {
+				return nil, err
+			}
+			continue
+		case WSPong:
+			return &WSMessage{Type: WSPong, Data: data}, nil
+		case WSClose:
+			closeErr := &WSCloseError{Code: 1005}
+			if len(data) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(data))
+				closeErr.Reason = string(data[2:])
+			}
+			ws.SendFrame(WSClose, data[:min(len(data), 2)], true)
+			return nil, closeErr
+		case WSContinuation:
+			if ws.msgType == 0 {
+				return nil, ws.protocolError(errors.New("收到意外的延续帧"))
+			}
+		default:
+			if ws.msgType != 0 {
+				return nil, ws.protocolError(fmt.Errorf("分片消息未结束时收到新的数据帧 (操作码 0x%X)", opcode))
+			}
+			ws.msgType = opcode
+		}
+
+		ws.partial = append(ws.partial, data...)
+		if limit := ws.messageLimit(); int64(len(ws.partial)) > limit {
+			return nil, ws.protocolError(fmt.Errorf("WebSocket消息超过上限 %d 字节", limit))
+		}
+		if fin {
+			msg := &WSMessage{Type: ws.msgType, Data: ws.partial}
+			ws.msgType, ws.partial = 0, nil
+			return msg, nil
+		}
+	}
+}
+
+// protocolError 丢弃未接收完的分片并记录错误
+func (ws *WebSocket) protocolError(err error) error {
+	ws.msgType, ws.partial = 0, nil
+	ws.fail(err)
+	return err
+}
+
+// messageLimit 返回单个帧和合并后消息的上限
+func (ws *WebSocket) messageLimit() int64 {
+	if limit := ws.client.config.MaxBodySize; limit > 0 {
+		return limit
+	}
+	return wsMaxMessageSize
+}
+
+// ReadText 读取下一条文本或二进制消息并以字符串形式返回，跳过pong
+func (ws *WebSocket) ReadText() (string, error) {
+	for {
+		msg, err := ws.Read()
+		if err != nil {
+			return "", err
+		}
+		if msg.Type != WSPong {
+			return msg.Text(), nil
+		}
+	}
+}
+
+// readFrame 读取一个帧并去除掩码
+func (ws *WebSocket) readFrame() (bool, int, []byte, error) {
+	if ws.Timeout > 0 {
+		ws.conn.SetReadDeadline(time.Now().Add(ws.Timeout))
+	} else {
+		ws.conn.SetReadDeadline(time.Time{})
+	}
+
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(ws.br, head); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := head[0]&0x80 != 0
+	opcode := int(head[0] & 0x0F)
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(ws.br, ext); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(ws.br, ext); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	// RFC 6455 规定64位长度的最高位必须为0
+	if length>>63 != 0 {
+		return false, 0, nil, fmt.Errorf("无效的WebSocket帧长度 %d", length)
+	}
+	if limit := ws.messageLimit(); length > uint64(limit) {
+		return false, 0, nil, fmt.Errorf("WebSocket帧长度 %d 超过上限 %d 字节", length, limit)
+	}
+
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(ws.br, mask); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(ws.br, data); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range data {
+			data[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, data, nil
+}
+
+// Close 发送关闭帧、关闭连接并记录本次会话的流量
+func (ws *WebSocket) Close() error {
+	ws.mu.Lock()
+	if ws.closed {
+		ws.mu.Unlock()
+		return nil
+	}
+	ws.closed = true
+	ws.mu.Unlock()
+
+	ws.wmu.Lock()
+	closeSent := ws.closeSent
+	ws.wmu.Unlock()
+	if !closeSent {
+		ws.SendFrame(WSClose, []byte{0x03, 0xE8}, true)
+	}
+	err := ws.conn.Close()
+	ws.finish()
+	return err
+}
+
+// record 记录收发的帧，内容总量超过记录上限时截断
+func (ws *WebSocket) record(sent bool, opcode int, data []byte) {
+	if ws.client.config.Traffic == nil {
+		return
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if remain := ws.recordLimit - ws.recorded; remain < len(data) {
+		if remain < 0 {
+			remain = 0
+		}
+		data = data[:remain]
+		ws.truncated = true
+	}
+	ws.recorded += len(data)
+
+	ws.messages = append(ws.messages, TrafficMessage{
+		Time:   time.Now(),
+		Sent:   sent,
+		Opcode: opcode,
+		Data:   append([]byte(nil), data...),
+	})
+}
+
+// fail 记录第一个错误
+func (ws *WebSocket) fail(err error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.err == nil {
+		ws.err = err
+	}
+}
+
+// finish 释放限速名额并添加流量记录
+func (ws *WebSocket) finish() {
+	if ws.release != nil {
+		ws.release()
+		ws.release = nil
+	}
+
+	log := ws.client.config.Traffic
+	if log == nil {
+		return
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	e := &TrafficEntry{
+		Tags:           ws.client.tags,
+		StartedAt:      ws.started,
+		Duration:       time.Since(ws.started),
+		WebSocket:      true,
+		Method:         http.MethodGet,
+		URL:            ws.url,
+		Proto:          "HTTP/1.1",
+		RequestHeaders: ws.req.Header.Clone(),
+		Messages:       ws.messages,
+		Truncated:      ws.truncated,
+	}
+	if ws.req.Host != "" {
+		e.RequestHeaders.Set("Host", ws.req.Host)
+	}
+	if h := ws.Handshake; h != nil {
+		e.StatusCode = h.StatusCode
+		e.Status = fmt.Sprintf("%d %s", h.StatusCode, http.StatusText(h.StatusCode))
+		e.ResponseProto = h.Proto
+		e.ResponseHeaders = h.Headers.Clone()
+		e.ResponseBody = h.Body
+		e.RemoteAddr = h.RemoteAddr
+		e.Timing = h.Timing
+	}
+	if ws.err != nil {
+		e.Error = ws.err.Error()
+	}
+
+	log.Add(e)
+}
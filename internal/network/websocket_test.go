@@ -0,0 +1,289 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsFrame 是服务端读到的客户端帧
+type wsFrame struct {
+	fin    bool
+	opcode int
+	masked bool
+	data   []byte
+}
+
+// writeServerFrame 写入服务端帧，服务端帧不加掩码
+func writeServerFrame(w io.Writer, fin bool, opcode int, data []byte) {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	switch n := len(data); {
+	case n < 126:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	w.Write(append(frame, data...))
+}
+
+// readClientFrame 读取并去除掩码
+func readClientFrame(br *bufio.Reader) (wsFrame, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(br, head); err != nil {
+		return wsFrame{}, err
+	}
+	f := wsFrame{fin: head[0]&0x80 != 0, opcode: int(head[0] & 0x0F), masked: head[1]&0x80 != 0}
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		io.ReadFull(br, ext)
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		io.ReadFull(br, ext)
+		length = binary.BigEndian.Uint64(ext)
+	}
+	mask := make([]byte, 4)
+	if f.masked {
+		io.ReadFull(br, mask)
+	}
+	f.data = make([]byte, length)
+	if _, err := io.ReadFull(br, f.data); err != nil {
+		return wsFrame{}, err
+	}
+	for i := range f.data {
+		f.data[i] ^= mask[i%4]
+	}
+	return f, nil
+}
+
+// wsServer 启动一个接受升级的服务，握手完成后在连接上执行script
+// accept 为空时返回正确的Sec-WebSocket-Accept
+func wsServer(t *testing.T, accept string, script func(conn net.Conn, br *bufio.Reader)) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+			http.Error(w, "not a websocket handshake", http.StatusBadRequest)
+			return
+		}
+		if accept == "" {
+			sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + wsGUID))
+			accept = base64.StdEncoding.EncodeToString(sum[:])
+		}
+
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Accept: "+accept+"\r\n\r\n")
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+		script(conn, brw.Reader)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func dialWS(t *testing.T, url string, config HTTPClientConfig) *WebSocket {
+	t.Helper()
+
+	ws, err := NewWebSocketClient(config).Dial(context.Background(), url, nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func TestWebSocketEchoAndMasking(t *testing.T) {
+	frames := make(chan wsFrame, 1)
+	url := wsServer(t, "", func(conn net.Conn, br *bufio.Reader) {
+		f, err := readClientFrame(br)
+		if err != nil {
+			return
+		}
+		frames <- f
+		writeServerFrame(conn, true, f.opcode, f.data)
+	})
+
+	ws := dialWS(t, url, DefaultHTTPClientConfig())
+	if ws.Handshake.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d", ws.Handshake.StatusCode)
+	}
+	if err := ws.SendText("hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	f := <-frames
+	if !f.masked || !f.fin || f.opcode != WSText || string(f.data) != "hello" {
+		t.Errorf("client frame = %+v, want a masked final text frame", f)
+	}
+	text, err := ws.ReadText()
+	if err != nil || text != "hello" {
+		t.Errorf("echo = %q, %v", text, err)
+	}
+}
+
+func TestWebSocketBadAcceptKey(t *testing.T) {
+	url := wsServer(t, "bm90LXRoZS1yaWdodC1rZXk=", func(conn net.Conn, br *bufio.Reader) {})
+
+	_, err := NewWebSocketClient(DefaultHTTPClientConfig()).Dial(context.Background(), url, nil)
+	var hs *WSHandshakeError
+	if !errors.As(err, &hs) || !strings.Contains(hs.Reason, "Accept") {
+		t.Fatalf("Dial error = %v, want accept key mismatch", err)
+	}
+}
+
+func TestWebSocketRejectedHandshake(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "login required", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := NewWebSocketClient(DefaultHTTPClientConfig()).Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	var hs *WSHandshakeError
+	if !errors.As(err, &hs) || hs.Response.StatusCode != http.StatusUnauthorized || !strings.Contains(string(hs.Response.Body), "login required") {
+		t.Fatalf("Dial error = %v, want handshake error with the 401 response", err)
+	}
+}
+
+func TestWebSocketFragmentationWithPong(t *testing.T) {
+	url := wsServer(t, "", func(conn net.Conn, br *bufio.Reader) {
+		writeServerFrame(conn, false, WSText, []byte("hel"))
+		writeServerFrame(conn, true, WSPong, []byte("p"))
+		writeServerFrame(conn, false, WSContinuation, []byte("lo "))
+		writeServerFrame(conn, true, WSContinuation, []byte("world"))
+		readClientFrame(br)
+	})
+	ws := dialWS(t, url, DefaultHTTPClientConfig())
+
+	msg, err := ws.Read()
+	if err != nil || msg.Type != WSPong || string(msg.Data) != "p" {
+		t.Fatalf("first read = %+v, %v, want the pong", msg, err)
+	}
+	msg, err = ws.Read()
+	if err != nil || msg.Type != WSText || msg.Text() != "hello world" {
+		t.Fatalf("second read = %+v, %v, want the reassembled message", msg, err)
+	}
+}
+
+func TestWebSocketDataFrameInsideFragmentedMessage(t *testing.T) {
+	url := wsServer(t, "", func(conn net.Conn, br *bufio.Reader) {
+		writeServerFrame(conn, false, WSText, []byte("part"))
+		writeServerFrame(conn, true, WSBinary, []byte("new"))
+		readClientFrame(br)
+	})
+	ws := dialWS(t, url, DefaultHTTPClientConfig())
+
+	if msg, err := ws.Read(); err == nil {
+		t.Fatalf("read = %+v, want protocol error", msg)
+	}
+}
+
+func TestWebSocketPingAutoPong(t *testing.T) {
+	pongs := make(chan wsFrame, 1)
+	url := wsServer(t, "", func(conn net.Conn, br *bufio.Reader) {
+		writeServerFrame(conn, true, WSPing, []byte("are you there"))
+		f, err := readClientFrame(br)
+		if err != nil {
+			return
+		}
+		pongs <- f
+		writeServerFrame(conn, true, WSText, []byte("done"))
+		readClientFrame(br)
+	})
+	ws := dialWS(t, url, DefaultHTTPClientConfig())
+
+	text, err := ws.ReadText()
+	if err != nil || text != "done" {
+		t.Fatalf("read = %q, %v", text, err)
+	}
+	if f := <-pongs; f.opcode != WSPong || !f.masked || string(f.data) != "are you there" {
+		t.Errorf("reply = %+v, want a masked pong with the ping payload", f)
+	}
+}
+
+func TestWebSocketClose(t *testing.T) {
+	replies := make(chan wsFrame, 1)
+	url := wsServer(t, "", func(conn net.Conn, br *bufio.Reader) {
+		writeServerFrame(conn, true, WSClose, append([]byte{0x03, 0xE9}, "going away"...))
+		if f, err := readClientFrame(br); err == nil {
+			replies <- f
+		}
+	})
+	ws := dialWS(t, url, DefaultHTTPClientConfig())
+
+	_, err := ws.Read()
+	var closeErr *WSCloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != 1001 || closeErr.Reason != "going away" {
+		t.Fatalf("read error = %v, want close 1001", err)
+	}
+	if f := <-replies; f.opcode != WSClose || binary.BigEndian.Uint16(f.data) != 1001 {
+		t.Errorf("close reply = %+v", f)
+	}
+}
+
+func TestWebSocketFrameLengthLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxBody int64
+		length  uint64
+	}{
+		{"top bit set", 0, 1 << 63},
+		{"unlimited config still capped", 0, 1 << 40},
+		{"over MaxBodySize", 1024, 2048},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := wsServer(t, "", func(conn net.Conn, br *bufio.Reader) {
+				frame := []byte{0x82, 127}
+				conn.Write(binary.BigEndian.AppendUint64(frame, tt.length))
+				readClientFrame(br)
+			})
+			config := DefaultHTTPClientConfig()
+			config.MaxBodySize = tt.maxBody
+			ws := dialWS(t, url, config)
+
+			if msg, err := ws.Read(); err == nil || !strings.Contains(err.Error(), "长度") {
+				t.Fatalf("read = %+v, %v, want a length error", msg, err)
+			}
+		})
+	}
+}
+
+func TestWebSocketFragmentsOverLimit(t *testing.T) {
+	url := wsServer(t, "", func(conn net.Conn, br *bufio.Reader) {
+		chunk := make([]byte, 600)
+		writeServerFrame(conn, false, WSBinary, chunk)
+		writeServerFrame(conn, true, WSContinuation, chunk)
+		readClientFrame(br)
+	})
+	config := DefaultHTTPClientConfig()
+	config.MaxBodySize = 1024
+	ws := dialWS(t, url, config)
+
+	if _, err := ws.Read(); err == nil || !strings.Contains(err.Error(), "上限") {
+		t.Fatalf("read error = %v, want message size error", err)
+	}
+}
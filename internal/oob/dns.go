@@ -0,0 +1,128 @@
+package oob
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DNS报文中使用的常量
+const (
+	dnsTypeA   = 1
+	dnsTypeANY = 255
+	dnsClassIN = 1
+)
+
+// serveDNS 处理DNS查询，记录查询的域名并对A记录返回配置的IP
+func (s *Server) serveDNS(conn net.PacketConn) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			// 连接关闭时退出
+			return
+		}
+
+		name, qtype, qend, err := parseDNSQuery(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		s.record("dns", addr.String(), fmt.Sprintf("%s %s", dnsTypeName(qtype), name))
+
+		resp := s.dnsResponse(buf[:qend], qtype)
+		conn.WriteTo(resp, addr)
+	}
+}
+
+// parseDNSQuery 解析查询报文的第一个问题，返回域名、查询类型和问题部分结束的位置
+func parseDNSQuery(msg []byte) (string, uint16, int, error) {
+	if len(msg) < 12 {
+		return "", 0, 0, fmt.Errorf("DNS报文过短")
+	}
+	if msg[2]&0x80 != 0 {
+		return "", 0, 0, fmt.Errorf("不是DNS查询")
+	}
+	if binary.BigEndian.Uint16(msg[4:6]) == 0 {
+		return "", 0, 0, fmt.Errorf("DNS查询没有问题")
+	}
+
+	var labels []string
+	i := 12
+	for {
+		if i >= len(msg) {
+			return "", 0, 0, fmt.Errorf("DNS域名不完整")
+		}
+		l := int(msg[i])
+		i++
+		if l == 0 {
+			break
+		}
+		// 查询报文的问题部分不应包含压缩指针
+		if l&0xc0 != 0 || i+l > len(msg) {
+			return "", 0, 0, fmt.Errorf("无效的DNS标签")
+		}
+		labels = append(labels, string(msg[i:i+l]))
+		i += l
+	}
+
+	if i+4 > len(msg) {
+		return "", 0, 0, fmt.Errorf("DNS问题不完整")
+	}
+	qtype := binary.BigEndian.Uint16(msg[i : i+2])
+
+	return strings.ToLower(strings.Join(labels, ".")), qtype, i + 4, nil
+}
+
+// dnsResponse 根据查询报文头和问题部分构造应答
+func (s *Server) dnsResponse(query []byte, qtype uint16) []byte {
+	ip := s.Config().IP.To4()
+	if ip == nil {
+		ip = net.IPv4(127, 0, 0, 1).To4()
+	}
+
+	resp := make([]byte, len(query), len(query)+16)
+	copy(resp, query)
+
+	// QR=1, AA=1，保留查询的RD位，RCODE=0
+	resp[2] = 0x84 | query[2]&0x01
+	resp[3] = 0x00
+	binary.BigEndian.PutUint16(resp[4:6], 1)
+	binary.BigEndian.PutUint16(resp[6:8], 0)
+	binary.BigEndian.PutUint16(resp[8:10], 0)
+	binary.BigEndian.PutUint16(resp[10:12], 0)
+
+	if qtype != dnsTypeA && qtype != dnsTypeANY {
+		return resp
+	}
+
+	binary.BigEndian.PutUint16(resp[6:8], 1)
+	resp = append(resp,
+		0xc0, 0x0c, // 指向问题中的域名
+		0, dnsTypeA,
+		0, dnsClassIN,
+		0, 0, 0, 0, // TTL为0，避免解析结果被缓存
+		0, 4,
+	)
+	return append(resp, ip...)
+}
+
+// dnsTypeName 返回常见查询类型的名称
+func dnsTypeName(qtype uint16) string {
+	switch qtype {
+	case 1:
+		return "A"
+	case 5:
+		return "CNAME"
+	case 15:
+		return "MX"
+	case 16:
+		return "TXT"
+	case 28:
+		return "AAAA"
+	case 255:
+		return "ANY"
+	}
+	return fmt.Sprintf("TYPE%d", qtype)
+}
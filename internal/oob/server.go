@@ -0,0 +1,430 @@
+// Package oob 实现带外回连服务，用于确认盲打类漏洞
+//
+// 服务在本地运行HTTP和DNS监听器，为每次插件执行分配唯一令牌，
+// 并将收到的请求按令牌关联回对应的插件和目标。
+package oob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seaung/Luna/pkg/helper"
+)
+
+const (
+	// tokenLength 是回连令牌的长度，令牌只包含小写字母和数字，可直接用作域名标签
+	tokenLength = 20
+	// maxLogSize 是保留的最近交互数量
+	maxLogSize = 1000
+	// maxDataSize 是单次交互记录的最大数据量
+	maxDataSize = 64 << 10
+	// DefaultTokenTTL 是令牌默认的有效期，部分目标会延迟很久才回连
+	DefaultTokenTTL = 24 * time.Hour
+)
+
+// Config 配置回连服务
+type Config struct {
+	// HTTPAddr 是HTTP监听地址，为空时不启动HTTP监听
+	HTTPAddr string
+	// DNSAddr 是DNS监听地址(UDP)，为空时不启动DNS监听
+	DNSAddr string
+	// Domain 是指向本服务的域名，设置后回连地址形如 <token>.<domain>
+	Domain string
+	// PublicHost 是目标访问本服务使用的主机名或IP，默认为HTTP监听地址
+	PublicHost string
+	// IP 是DNS查询应答的A记录，默认为127.0.0.1
+	IP net.IP
+	// TokenTTL 是令牌的有效期，过期的令牌及其交互被清理，为0时使用 DefaultTokenTTL
+	TokenTTL time.Duration
+}
+
+// DefaultConfig 返回只监听本机随机端口的配置
+func DefaultConfig() Config {
+	return Config{
+		HTTPAddr: "127.0.0.1:0",
+		DNSAddr:  "127.0.0.1:0",
+	}
+}
+
+// Callback 是分配给单次执行的回连地址
+type Callback struct {
+	Token  string
+	Domain string
+	URL    string
+}
+
+// Interaction 表示回连服务收到的一次交互
+type Interaction struct {
+	Token      string
+	Protocol   string
+	RemoteAddr string
+	Data       string
+	Time       time.Time
+	// Plugin 和 Target 是令牌所属的插件和目标，未匹配到令牌时为空
+	Plugin string
+	Target string
+}
+
+// registration 是一个已分配的令牌
+type registration struct {
+	plugin       string
+	target       string
+	expires      time.Time
+	interactions []Interaction
+	// notify 在收到交互时关闭并替换，用于唤醒等待者
+	notify chan struct{}
+}
+
+// Server 是带外回连服务
+type Server struct {
+	mu       sync.Mutex
+	config   Config
+	tokens   map[string]*registration
+	log      []Interaction
+	httpSrv  *http.Server
+	httpAddr net.Addr
+	dnsConn  net.PacketConn
+}
+
+// NewServer 创建回连服务，需调用Start启动监听
+func NewServer(config Config) *Server {
+	return &Server{
+		config: config,
+		tokens: make(map[string]*registration),
+	}
+}
+
+// Start 启动HTTP和DNS监听器
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.httpSrv != nil || s.dnsConn != nil {
+		return fmt.Errorf("回连服务已在运行")
+	}
+	if s.config.HTTPAddr == "" && s.config.DNSAddr == "" {
+		return fmt.Errorf("未配置任何监听地址")
+	}
+
+	if s.config.HTTPAddr != "" {
+		ln, err := net.Listen("tcp", s.config.HTTPAddr)
+		if err != nil {
+			return fmt.Errorf("启动HTTP监听失败: %w", err)
+		}
+		s.httpAddr = ln.Addr()
+		s.httpSrv = &http.Server{
+			Handler:           http.HandlerFunc(s.handleHTTP),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go s.httpSrv.Serve(ln)
+	}
+
+	if s.config.DNSAddr != "" {
+		conn, err := net.ListenPacket("udp", s.config.DNSAddr)
+		if err != nil {
+			if s.httpSrv != nil {
+				s.httpSrv.Close()
+				s.httpSrv = nil
+			}
+			return fmt.Errorf("启动DNS监听失败: %w", err)
+		}
+		s.dnsConn = conn
+		go s.serveDNS(conn)
+	}
+
+	return nil
+}
+
+// Stop 停止所有监听器，已分配的令牌和交互记录保留
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.httpSrv == nil && s.dnsConn == nil {
+		return fmt.Errorf("回连服务未运行")
+	}
+
+	if s.httpSrv != nil {
+		s.httpSrv.Close()
+		s.httpSrv = nil
+		s.httpAddr = nil
+	}
+	if s.dnsConn != nil {
+		s.dnsConn.Close()
+		s.dnsConn = nil
+	}
+	return nil
+}
+
+// Running 判断服务是否在运行
+func (s *Server) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.httpSrv != nil || s.dnsConn != nil
+}
+
+// HTTPAddr 返回HTTP监听的实际地址，未监听时为空
+func (s *Server) HTTPAddr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.httpAddr == nil {
+		return ""
+	}
+	return s.httpAddr.String()
+}
+
+// DNSAddr 返回DNS监听的实际地址，未监听时为空
+func (s *Server) DNSAddr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dnsConn == nil {
+		return ""
+	}
+	return s.dnsConn.LocalAddr().String()
+}
+
+// Config 返回服务的配置
+func (s *Server) Config() Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
+}
+
+// NewCallback 为插件和目标分配一个新令牌并生成回连地址
+func (s *Server) NewCallback(pluginName, target string) (*Callback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.httpSrv == nil && s.dnsConn == nil {
+		return nil, fmt.Errorf("回连服务未运行")
+	}
+
+	now := time.Now()
+	s.prune(now)
+
+	token := helper.RandomString(tokenLength)
+	s.tokens[token] = &registration{
+		plugin:  pluginName,
+		target:  target,
+		expires: now.Add(s.tokenTTL()),
+		notify:  make(chan struct{}),
+	}
+
+	cb := &Callback{Token: token}
+	host := s.config.PublicHost
+	if host == "" && s.httpAddr != nil {
+		host = s.httpAddr.String()
+	}
+
+	if s.config.Domain != "" {
+		cb.Domain = token + "." + strings.TrimPrefix(s.config.Domain, ".")
+		urlHost := cb.Domain
+		if s.httpAddr != nil {
+			// 域名只负责解析，端口沿用HTTP监听端口
+			if _, port, err := net.SplitHostPort(s.httpAddr.String()); err == nil && port != "80" {
+				urlHost = net.JoinHostPort(cb.Domain, port)
+			}
+		}
+		cb.URL = fmt.Sprintf("http://%s/", urlHost)
+	} else if host != "" {
+		cb.URL = fmt.Sprintf("http://%s/%s", host, token)
+	}
+
+	return cb, nil
+}
+
+// Wait 等待令牌收到交互，收到第一个交互或超时后返回已收到的全部交互
+func (s *Server) Wait(ctx context.Context, token string, timeout time.Duration) ([]Interaction, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for {
+		s.mu.Lock()
+		reg, ok := s.tokens[token]
+		if !ok {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("未知的回连令牌: %s", token)
+		}
+		if len(reg.interactions) > 0 {
+			out := append([]Interaction(nil), reg.interactions...)
+			s.mu.Unlock()
+			return out, nil
+		}
+		notify := reg.notify
+		s.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				// 超时不算错误，表示没有收到交互
+				return nil, nil
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Poll 返回令牌已收到的交互，不等待
+func (s *Server) Poll(token string) ([]Interaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reg, ok := s.tokens[token]
+	if !ok {
+		return nil, fmt.Errorf("未知的回连令牌: %s", token)
+	}
+	return append([]Interaction(nil), reg.interactions...), nil
+}
+
+// Unregister 释放令牌，之后该令牌收到的交互不再关联到插件
+func (s *Server) Unregister(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, token)
+}
+
+// Interactions 返回最近收到的交互，包括未匹配到令牌的交互
+func (s *Server) Interactions() []Interaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Interaction(nil), s.log...)
+}
+
+// Tokens 返回未过期的令牌数量
+func (s *Server) Tokens() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	return len(s.tokens)
+}
+
+// record 查找数据中出现的令牌并记录交互
+func (s *Server) record(protocol, remoteAddr, data string) {
+	if len(data) > maxDataSize {
+		data = data[:maxDataSize]
+	}
+	lower := strings.ToLower(data)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matched := false
+	for token, reg := range s.tokens {
+		if now.After(reg.expires) {
+			delete(s.tokens, token)
+			continue
+		}
+		if !strings.Contains(lower, token) {
+			continue
+		}
+		matched = true
+
+		it := Interaction{
+			Token:      token,
+			Protocol:   protocol,
+			RemoteAddr: remoteAddr,
+			Data:       data,
+			Time:       now,
+			Plugin:     reg.plugin,
+			Target:     reg.target,
+		}
+		reg.interactions = append(reg.interactions, it)
+		close(reg.notify)
+		reg.notify = make(chan struct{})
+		s.appendLog(it)
+	}
+
+	if !matched {
+		s.appendLog(Interaction{
+			Protocol:   protocol,
+			RemoteAddr: remoteAddr,
+			Data:       data,
+			Time:       now,
+		})
+	}
+}
+
+// tokenTTL 返回令牌的有效期
+func (s *Server) tokenTTL() time.Duration {
+	if s.config.TokenTTL > 0 {
+		return s.config.TokenTTL
+	}
+	return DefaultTokenTTL
+}
+
+// prune 删除过期的令牌，调用方需持有锁
+func (s *Server) prune(now time.Time) {
+	for token, reg := range s.tokens {
+		if now.After(reg.expires) {
+			delete(s.tokens, token)
+		}
+	}
+}
+
+// appendLog 追加交互记录，超出上限时丢弃最早的记录，调用方需持有锁
+func (s *Server) appendLog(it Interaction) {
+	s.log = append(s.log, it)
+	if len(s.log) > maxLogSize {
+		s.log = s.log[len(s.log)-maxLogSize:]
+	}
+}
+
+// handleHTTP 记录收到的HTTP请求，令牌可以出现在Host、路径、查询参数、请求头或请求体中
+func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxDataSize)
+	dump, err := httputil.DumpRequest(r, true)
+	if err != nil {
+		dump, _ = httputil.DumpRequest(r, false)
+	}
+	io.Copy(io.Discard, r.Body)
+
+	s.record("http", r.RemoteAddr, string(dump))
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("ok"))
+}
+
+// Client 返回绑定到插件和目标的回连客户端
+func (s *Server) Client(pluginName, target string) *Client {
+	return &Client{server: s, plugin: pluginName, target: target}
+}
+
+// Client 是单次插件执行使用的回连客户端
+type Client struct {
+	server *Server
+	plugin string
+	target string
+}
+
+// NewCallback 生成一个唯一的回连地址
+func (c *Client) NewCallback() (*Callback, error) {
+	return c.server.NewCallback(c.plugin, c.target)
+}
+
+// Poll 返回回连地址已收到的交互，不等待
+func (c *Client) Poll(cb *Callback) ([]Interaction, error) {
+	if cb == nil {
+		return nil, fmt.Errorf("回连地址为空")
+	}
+	return c.server.Poll(cb.Token)
+}
+
+// Wait 等待回连地址收到交互，超时后返回空结果
+func (c *Client) Wait(ctx context.Context, cb *Callback, timeout time.Duration) ([]Interaction, error) {
+	if cb == nil {
+		return nil, fmt.Errorf("回连地址为空")
+	}
+	return c.server.Wait(ctx, cb.Token, timeout)
+}
@@ -0,0 +1,168 @@
+package oob
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T, config Config) *Server {
+	t.Helper()
+
+	s := NewServer(config)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+	return s
+}
+
+// dnsQuery 构造查询name的A记录的DNS报文
+func dnsQuery(name string) []byte {
+	msg := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range strings.Split(name, ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	return append(msg, 0, 0, dnsTypeA, 0, dnsClassIN)
+}
+
+func TestHTTPInteraction(t *testing.T) {
+	s := startServer(t, DefaultConfig())
+	client := s.Client("ssrf_check", "http://target")
+
+	cb, err := client.NewCallback()
+	if err != nil {
+		t.Fatalf("NewCallback: %v", err)
+	}
+	if !strings.HasPrefix(cb.URL, "http://"+s.HTTPAddr()+"/") {
+		t.Fatalf("callback URL = %s, want on %s", cb.URL, s.HTTPAddr())
+	}
+
+	if got, err := client.Poll(cb); err != nil || len(got) != 0 {
+		t.Fatalf("Poll before hit = %v, %v", got, err)
+	}
+
+	resp, err := http.Get(cb.URL + "?from=target")
+	if err != nil {
+		t.Fatalf("GET callback: %v", err)
+	}
+	resp.Body.Close()
+
+	got, err := client.Poll(cb)
+	if err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("interactions = %d, want 1", len(got))
+	}
+	it := got[0]
+	if it.Protocol != "http" || it.Plugin != "ssrf_check" || it.Target != "http://target" || !strings.Contains(it.Data, "from=target") {
+		t.Errorf("interaction = %+v", it)
+	}
+}
+
+func TestDNSInteraction(t *testing.T) {
+	config := DefaultConfig()
+	config.Domain = "oob.test"
+	config.IP = net.IPv4(10, 1, 2, 3)
+	s := startServer(t, config)
+	client := s.Client("log4j", "http://target")
+
+	cb, err := client.NewCallback()
+	if err != nil {
+		t.Fatalf("NewCallback: %v", err)
+	}
+
+	conn, err := net.Dial("udp", s.DNSAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := conn.Write(dnsQuery("x." + strings.ToUpper(cb.Domain))); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read DNS answer: %v", err)
+	}
+	if answers := binary.BigEndian.Uint16(buf[6:8]); answers != 1 {
+		t.Fatalf("answers = %d, want 1", answers)
+	}
+	if ip := net.IP(buf[n-4 : n]); !ip.Equal(config.IP) {
+		t.Errorf("answer IP = %s, want %s", ip, config.IP)
+	}
+
+	got, err := client.Wait(context.Background(), cb, time.Second)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if len(got) != 1 || got[0].Protocol != "dns" || got[0].Plugin != "log4j" {
+		t.Fatalf("interactions = %+v", got)
+	}
+	if !strings.Contains(got[0].Data, cb.Token) {
+		t.Errorf("data = %q, want token %s", got[0].Data, cb.Token)
+	}
+}
+
+func TestUnmatchedInteractionLogged(t *testing.T) {
+	s := startServer(t, DefaultConfig())
+
+	resp, err := http.Get("http://" + s.HTTPAddr() + "/no-token-here")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	log := s.Interactions()
+	if len(log) != 1 || log[0].Token != "" {
+		t.Fatalf("log = %+v, want one unmatched interaction", log)
+	}
+}
+
+func TestExpiredTokensPruned(t *testing.T) {
+	config := DefaultConfig()
+	config.TokenTTL = 20 * time.Millisecond
+	s := startServer(t, config)
+
+	cb, err := s.NewCallback("p", "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Tokens() != 1 {
+		t.Fatalf("tokens = %d, want 1", s.Tokens())
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	s.record("http", "127.0.0.1:1", "GET /"+cb.Token)
+
+	if n := s.Tokens(); n != 0 {
+		t.Errorf("tokens after expiry = %d, want 0", n)
+	}
+	if _, err := s.Poll(cb.Token); err == nil {
+		t.Error("Poll on expired token should fail")
+	}
+	if log := s.Interactions(); len(log) != 1 || log[0].Token != "" {
+		t.Errorf("hit on expired token should be logged as unmatched, got %+v", log)
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	s := startServer(t, DefaultConfig())
+
+	cb, err := s.NewCallback("p", "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Unregister(cb.Token)
+	if s.Tokens() != 0 {
+		t.Errorf("tokens = %d, want 0", s.Tokens())
+	}
+}
@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/seaung/Luna/internal/network"
+	"github.com/seaung/Luna/internal/storage"
+	"github.com/seaung/Luna/pkg/helper"
+	"github.com/seaung/Luna/pkg/sdk"
+)
+
+const envPlugin = `package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/seaung/Luna/pkg/sdk"
+)
+
+type PluginMeta struct {
+	Name        string
+	Version     string
+	Description string
+}
+
+type envProbe struct{}
+
+var Plugin = &envProbe{}
+
+func (p *envProbe) Meta() PluginMeta {
+	return PluginMeta{Name: "env-probe", Version: "1.0"}
+}
+
+func (p *envProbe) Run(target string) (bool, error) {
+	return false, nil
+}
+
+func (p *envProbe) RunWithEnv(env *sdk.Env, target string) (bool, error) {
+	resp, err := env.HTTP.Get(context.Background(), target, nil)
+	if err != nil {
+		return false, err
+	}
+	runs, _ := env.KV.Get("runs")
+	env.KV.Set("runs", runs+"x")
+	env.Log.Infof("status %d runs %s", resp.StatusCode, runs+"x")
+	return strings.Contains(string(resp.Body), "admin"), nil
+}
+`
+
+func TestExecuteInjectsServices(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("admin panel"))
+	}))
+	defer srv.Close()
+
+	traffic := network.NewTrafficLog(0)
+	config := network.DefaultHTTPClientConfig()
+	config.Traffic = traffic
+
+	pm := NewPluginManager()
+	pm.SetServices(sdk.Services{
+		Scan:     "scan-1",
+		HTTP:     network.NewHTTPClient(config),
+		KV:       storage.NewKVStore(),
+		LogLevel: helper.LevelInfo,
+	})
+	loadScript(t, pm, envPlugin)
+
+	for _, want := range []string{"runs x", "runs xx"} {
+		result, err := pm.Execute("env-probe", srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Err != nil || !result.Vulnerable {
+			t.Fatalf("result = %v, %v", result.Vulnerable, result.Err)
+		}
+		// 日志写入本次执行的输出，同一目标的KV在多次执行间保留
+		if out := result.Output.String(); !strings.Contains(out, "[env-probe] status 200 "+want+"\n") {
+			t.Errorf("output = %q, want %q", out, want)
+		}
+	}
+
+	entries := traffic.List(network.TrafficFilter{Plugin: "env-probe", Target: srv.URL})
+	if len(entries) != 2 || entries[0].Tags.Scan != "scan-1" {
+		t.Errorf("tagged entries = %d", len(entries))
+	}
+}
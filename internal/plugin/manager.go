@@ -2,11 +2,16 @@ package plugin
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/seaung/Luna/pkg/matcher"
+	"github.com/seaung/Luna/pkg/sdk"
 	"github.com/traefik/yaegi/interp"
+	"github.com/traefik/yaegi/stdlib"
 )
 
 type PluginMeta struct {
@@ -20,14 +25,63 @@ type VulnPlugin interface {
 	Run(target string) (bool, error)
 }
 
+// EnvPlugin 是需要宿主服务的插件，执行时优先调用RunWithEnv
+type EnvPlugin interface {
+	VulnPlugin
+	RunWithEnv(env *sdk.Env, target string) (bool, error)
+}
+
+// ExecResult 保存一次插件执行的结果
+type ExecResult struct {
+	Plugin     string
+	Target     string
+	Vulnerable bool
+	Err        error
+	Output     *Output
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// loadedPlugin 保存已加载插件及其解释器的输出路由
+type loadedPlugin struct {
+	plugin VulnPlugin
+	stdout *outputRouter
+	stderr *outputRouter
+	// 同一解释器的输出路由是共享的，因此同一插件的执行需要串行化
+	runMu sync.Mutex
+}
+
 type PluginManager struct {
-	plugins map[string]VulnPlugin
-	mxt     sync.Mutex
+	plugins  map[string]*loadedPlugin
+	mxt      sync.Mutex
+	live     io.Writer
+	services sdk.Services
 }
 
 func NewPluginManager() *PluginManager {
 	return &PluginManager{
-		plugins: make(map[string]VulnPlugin),
+		plugins: make(map[string]*loadedPlugin),
+	}
+}
+
+// SetServices 设置注入插件执行环境的宿主服务
+func (pm *PluginManager) SetServices(svc sdk.Services) {
+	pm.mxt.Lock()
+	defer pm.mxt.Unlock()
+
+	pm.services = svc
+}
+
+// SetVerbose 设置插件输出是否实时显示在控制台
+// 关闭时插件输出只保存在执行结果中
+func (pm *PluginManager) SetVerbose(verbose bool) {
+	pm.mxt.Lock()
+	defer pm.mxt.Unlock()
+
+	if verbose {
+		pm.live = os.Stdout
+	} else {
+		pm.live = nil
 	}
 }
 
@@ -35,11 +89,20 @@ func (pm *PluginManager) LoadPlugin(path string) error {
 	pm.mxt.Lock()
 	defer pm.mxt.Unlock()
 
+	stdout := newOutputRouter(os.Stdout)
+	stderr := newOutputRouter(os.Stderr)
+
 	i := interp.New(interp.Options{
 		// DisableCapabilites: []string{"syscall", "os/exec"},
+		Stdout: stdout,
+		Stderr: stderr,
 	})
 
 	i.Use(interp.Symbols)
+	i.Use(stdlib.Symbols)
+	i.Use(sdk.Symbols)
+	i.Use(matcher.Symbols)
+	i.Use(stdioSymbols(stdout, stderr))
 
 	code, err := os.ReadFile(path)
 	if err != nil {
@@ -51,18 +114,17 @@ func (pm *PluginManager) LoadPlugin(path string) error {
 		return err
 	}
 
-	v, err := i.Eval("Plugin")
+	plugin, err := newScriptPlugin(i)
 	if err != nil {
-		return fmt.Errorf("plugin Symbol not found")
+		return err
 	}
 
-	plugin, ok := v.Interface().(VulnPlugin)
-	if !ok {
-		return fmt.Errorf("Invalid plugin type")
+	pm.plugins[plugin.Meta().Name] = &loadedPlugin{
+		plugin: plugin,
+		stdout: stdout,
+		stderr: stderr,
 	}
 
-	pm.plugins[plugin.Meta().Name] = plugin
-
 	return nil
 }
 
@@ -72,7 +134,7 @@ func (pm *PluginManager) ListPlugins() []VulnPlugin {
 
 	var list []VulnPlugin
 	for _, p := range pm.plugins {
-		list = append(list, p)
+		list = append(list, p.plugin)
 	}
 
 	return list
@@ -83,18 +145,66 @@ func (pm *PluginManager) GetPlugin(name string) (VulnPlugin, bool) {
 	pm.mxt.Lock()
 	defer pm.mxt.Unlock()
 
-	plugin, exists := pm.plugins[name]
-	return plugin, exists
+	p, exists := pm.plugins[name]
+	if !exists {
+		return nil, false
+	}
+	return p.plugin, true
 }
 
 // ExecutePlugin 根据插件名执行插件
 func (pm *PluginManager) ExecutePlugin(name string, target string) (bool, error) {
-	plugin, exists := pm.GetPlugin(name)
+	result, err := pm.Execute(name, target)
+	if err != nil {
+		return false, err
+	}
+
+	return result.Vulnerable, result.Err
+}
+
+// Execute 根据插件名执行插件，并捕获执行期间的输出
+// 返回的错误仅表示插件不存在，插件自身的错误保存在ExecResult.Err中
+func (pm *PluginManager) Execute(name string, target string) (*ExecResult, error) {
+	pm.mxt.Lock()
+	p, exists := pm.plugins[name]
+	live := pm.live
+	svc := pm.services
+	pm.mxt.Unlock()
+
 	if !exists {
-		return false, fmt.Errorf("插件 '%s' 不存在", name)
+		return nil, fmt.Errorf("插件 '%s' 不存在", name)
+	}
+
+	p.runMu.Lock()
+	defer p.runMu.Unlock()
+
+	out := NewOutput(name, target, live)
+	defer p.stdout.attach(out)()
+	defer p.stderr.attach(out)()
+
+	result := &ExecResult{
+		Plugin:    name,
+		Target:    target,
+		Output:    out,
+		StartedAt: time.Now(),
 	}
 
-	return plugin.Run(target)
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result.Err = fmt.Errorf("插件运行时异常: %v", r)
+			}
+		}()
+		if ep, ok := p.plugin.(EnvPlugin); ok {
+			env := sdk.NewEnv(svc, name, target, out)
+			result.Vulnerable, result.Err = ep.RunWithEnv(env, target)
+			return
+		}
+		result.Vulnerable, result.Err = p.plugin.Run(target)
+	}()
+
+	result.FinishedAt = time.Now()
+	return result, nil
 }
 
 // SearchPlugins 根据关键字搜索插件
@@ -106,10 +216,10 @@ func (pm *PluginManager) SearchPlugins(keyword string) []VulnPlugin {
 	keyword = strings.ToLower(keyword)
 
 	for _, p := range pm.plugins {
-		meta := p.Meta()
+		meta := p.plugin.Meta()
 		if strings.Contains(strings.ToLower(meta.Name), keyword) ||
 			strings.Contains(strings.ToLower(meta.Description), keyword) {
-			results = append(results, p)
+			results = append(results, p.plugin)
 		}
 	}
 
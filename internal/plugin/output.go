@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/traefik/yaegi/interp"
+)
+
+// Output 保存单次插件执行期间产生的标准输出和标准错误
+type Output struct {
+	Plugin string
+	Target string
+
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	live io.Writer
+	bol  bool
+}
+
+// NewOutput 创建一个按插件和目标标记的输出缓冲区
+// live 不为空时，输出会同时以 "[plugin@target]" 前缀实时写入 live
+func NewOutput(pluginName, target string, live io.Writer) *Output {
+	return &Output{
+		Plugin: pluginName,
+		Target: target,
+		live:   live,
+		bol:    true,
+	}
+}
+
+// Write 实现io.Writer接口
+func (o *Output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.buf.Write(p)
+
+	if o.live != nil {
+		o.writeLive(p)
+	}
+
+	return len(p), nil
+}
+
+// writeLive 按行添加前缀后写入实时输出
+func (o *Output) writeLive(p []byte) {
+	prefix := fmt.Sprintf("[%s@%s] ", o.Plugin, o.Target)
+	for len(p) > 0 {
+		if o.bol {
+			io.WriteString(o.live, prefix)
+			o.bol = false
+		}
+
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			o.live.Write(p)
+			return
+		}
+
+		o.live.Write(p[:i+1])
+		o.bol = true
+		p = p[i+1:]
+	}
+}
+
+// String 返回已捕获的全部输出
+func (o *Output) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.buf.String()
+}
+
+// Len 返回已捕获输出的字节数
+func (o *Output) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.buf.Len()
+}
+
+// outputRouter 将解释器的标准输出转发到当前执行的输出缓冲区
+// yaegi 在创建解释器时固定 Stdout/Stderr，因此需要一个可切换的写入器
+type outputRouter struct {
+	mu       sync.Mutex
+	current  io.Writer
+	fallback io.Writer
+}
+
+func newOutputRouter(fallback io.Writer) *outputRouter {
+	return &outputRouter{fallback: fallback}
+}
+
+// Write 实现io.Writer接口
+func (r *outputRouter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	w := r.current
+	if w == nil {
+		w = r.fallback
+	}
+	r.mu.Unlock()
+
+	return w.Write(p)
+}
+
+// attach 将后续输出转发到w，返回恢复函数
+func (r *outputRouter) attach(w io.Writer) func() {
+	r.mu.Lock()
+	r.current = w
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		r.current = nil
+		r.mu.Unlock()
+	}
+}
+
+// stdioSymbols 将解释器中的os.Stdout和os.Stderr替换为输出路由
+// yaegi 只替换fmt.Print等函数，脚本直接写os.Stdout或os.Stderr时仍会绕过捕获；
+// 替换后二者的类型为io.Writer，脚本不能再调用*os.File特有的方法
+func stdioSymbols(stdout, stderr io.Writer) interp.Exports {
+	return interp.Exports{
+		"os/os": {
+			"Stdout": reflect.ValueOf(&stdout).Elem(),
+			"Stderr": reflect.ValueOf(&stderr).Elem(),
+		},
+	}
+}
@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadScript 将插件源码写入临时文件并加载
+func loadScript(t *testing.T, pm *PluginManager, src string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "plugin.go")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.LoadPlugin(path); err != nil {
+		t.Fatalf("LoadPlugin: %v", err)
+	}
+}
+
+const printingPlugin = `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type PluginMeta struct {
+	Name        string
+	Version     string
+	Description string
+}
+
+type printer struct{}
+
+var Plugin = &printer{}
+
+func (p *printer) Meta() PluginMeta {
+	return PluginMeta{Name: "printer", Version: "1.0"}
+}
+
+func (p *printer) Run(target string) (bool, error) {
+	if target == "boom" {
+		panic("exploded")
+	}
+	fmt.Printf("checking %s\n", target)
+	fmt.Print("partial ")
+	fmt.Println("line")
+	fmt.Fprintln(os.Stderr, "warning for", target)
+	return target == "vuln", nil
+}
+`
+
+func TestOutputLivePrefix(t *testing.T) {
+	var live bytes.Buffer
+	out := NewOutput("p", "t", &live)
+
+	out.Write([]byte("one\ntw"))
+	out.Write([]byte("o\n"))
+	out.Write([]byte("three"))
+
+	if got := out.String(); got != "one\ntwo\nthree" {
+		t.Errorf("captured = %q", got)
+	}
+	if got := live.String(); got != "[p@t] one\n[p@t] two\n[p@t] three" {
+		t.Errorf("live = %q", got)
+	}
+	if out.Len() != len("one\ntwo\nthree") {
+		t.Errorf("Len = %d", out.Len())
+	}
+}
+
+func TestExecuteCapturesOutput(t *testing.T) {
+	pm := NewPluginManager()
+	loadScript(t, pm, printingPlugin)
+
+	var live bytes.Buffer
+	pm.live = &live
+
+	first, err := pm.Execute("printer", "vuln")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := pm.Execute("printer", "safe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !first.Vulnerable || second.Vulnerable || first.Err != nil {
+		t.Fatalf("results = %+v, %+v", first, second)
+	}
+	if got, want := first.Output.String(), "checking vuln\npartial line\nwarning for vuln\n"; got != want {
+		t.Errorf("first output = %q, want %q", got, want)
+	}
+	if strings.Contains(second.Output.String(), "vuln") {
+		t.Errorf("second output contains the first run: %q", second.Output.String())
+	}
+	if !strings.Contains(live.String(), "[printer@safe] partial line\n") {
+		t.Errorf("live output = %q", live.String())
+	}
+	if first.Output.Plugin != "printer" || first.Output.Target != "vuln" || first.FinishedAt.Before(first.StartedAt) {
+		t.Errorf("first result metadata = %+v", first)
+	}
+}
+
+func TestExecuteQuietAndPanics(t *testing.T) {
+	pm := NewPluginManager()
+	loadScript(t, pm, printingPlugin)
+	pm.SetVerbose(false)
+
+	result, err := pm.Execute("printer", "boom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "exploded") {
+		t.Errorf("panic error = %v", result.Err)
+	}
+
+	// 异常后输出路由已恢复，下一次执行仍然被捕获
+	result, _ = pm.Execute("printer", "after")
+	if !strings.HasPrefix(result.Output.String(), "checking after\n") {
+		t.Errorf("output after panic = %q", result.Output.String())
+	}
+
+	if _, err := pm.Execute("missing", "x"); err == nil {
+		t.Error("unknown plugin executed")
+	}
+}
@@ -1 +1,111 @@
 package plugin
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/seaung/Luna/pkg/sdk"
+	"github.com/traefik/yaegi/interp"
+)
+
+// scriptPlugin 将解释器中的Plugin变量适配为VulnPlugin
+// 插件脚本通常自行声明PluginMeta等类型，无法直接断言为宿主接口，
+// 因此通过方法值调用Meta和Run
+type scriptPlugin struct {
+	meta PluginMeta
+	run  func(string) (bool, error)
+}
+
+// newScriptPlugin 从解释器中解析Plugin变量
+func newScriptPlugin(i *interp.Interpreter) (VulnPlugin, error) {
+	v, err := i.Eval("Plugin")
+	if err != nil {
+		return nil, fmt.Errorf("plugin Symbol not found")
+	}
+
+	if p, ok := v.Interface().(VulnPlugin); ok {
+		return p, nil
+	}
+
+	metaFn, err := i.Eval("Plugin.Meta")
+	if err != nil || metaFn.Kind() != reflect.Func {
+		return nil, fmt.Errorf("Invalid plugin type: missing Meta method")
+	}
+
+	runFn, err := i.Eval("Plugin.Run")
+	if err != nil {
+		return nil, fmt.Errorf("Invalid plugin type: missing Run method")
+	}
+
+	run, ok := runFn.Interface().(func(string) (bool, error))
+	if !ok {
+		return nil, fmt.Errorf("Invalid plugin type: Run must be func(string) (bool, error)")
+	}
+
+	meta, err := toPluginMeta(metaFn.Call(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &scriptPlugin{meta: meta, run: run}
+
+	// RunWithEnv 是可选方法，插件借此获得宿主服务
+	runEnvFn, err := i.Eval("Plugin.RunWithEnv")
+	if err != nil {
+		return sp, nil
+	}
+
+	runEnv, ok := runEnvFn.Interface().(func(*sdk.Env, string) (bool, error))
+	if !ok {
+		return nil, fmt.Errorf("Invalid plugin type: RunWithEnv must be func(*sdk.Env, string) (bool, error)")
+	}
+
+	return &scriptEnvPlugin{scriptPlugin: sp, runEnv: runEnv}, nil
+}
+
+// toPluginMeta 按字段名读取脚本返回的元数据
+func toPluginMeta(out []reflect.Value) (PluginMeta, error) {
+	if len(out) != 1 || out[0].Kind() != reflect.Struct {
+		return PluginMeta{}, fmt.Errorf("Invalid plugin type: Meta must return a struct")
+	}
+
+	field := func(name string) string {
+		f := out[0].FieldByName(name)
+		if !f.IsValid() || f.Kind() != reflect.String {
+			return ""
+		}
+		return f.String()
+	}
+
+	meta := PluginMeta{
+		Name:        field("Name"),
+		Version:     field("Version"),
+		Description: field("Description"),
+	}
+	if meta.Name == "" {
+		return PluginMeta{}, fmt.Errorf("Invalid plugin type: empty plugin name")
+	}
+
+	return meta, nil
+}
+
+// Meta 返回插件的元数据
+func (p *scriptPlugin) Meta() PluginMeta {
+	return p.meta
+}
+
+// Run 执行插件
+func (p *scriptPlugin) Run(target string) (bool, error) {
+	return p.run(target)
+}
+
+// scriptEnvPlugin 是实现了RunWithEnv的脚本插件
+type scriptEnvPlugin struct {
+	*scriptPlugin
+	runEnv func(*sdk.Env, string) (bool, error)
+}
+
+// RunWithEnv 使用宿主服务执行插件
+func (p *scriptEnvPlugin) RunWithEnv(env *sdk.Env, target string) (bool, error) {
+	return p.runEnv(env, target)
+}
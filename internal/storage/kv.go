@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+)
+
+// KVStore 是按目标隔离的键值存储，插件可借此在多次执行之间共享数据
+type KVStore struct {
+	mu      sync.Mutex
+	buckets map[string]map[string]string
+}
+
+// NewKVStore 创建一个新的键值存储
+func NewKVStore() *KVStore {
+	return &KVStore{
+		buckets: make(map[string]map[string]string),
+	}
+}
+
+// Bucket 返回指定目标的键值空间
+func (s *KVStore) Bucket(target string) *KVBucket {
+	return &KVBucket{store: s, target: target}
+}
+
+// KVBucket 是单个目标的键值空间
+type KVBucket struct {
+	store  *KVStore
+	target string
+}
+
+// Get 获取键对应的值
+func (b *KVBucket) Get(key string) (string, bool) {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+
+	v, ok := b.store.buckets[b.target][key]
+	return v, ok
+}
+
+// Set 设置键对应的值
+func (b *KVBucket) Set(key, value string) {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+
+	bucket, ok := b.store.buckets[b.target]
+	if !ok {
+		bucket = make(map[string]string)
+		b.store.buckets[b.target] = bucket
+	}
+	bucket[key] = value
+}
+
+// Delete 删除键
+func (b *KVBucket) Delete(key string) {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+
+	delete(b.store.buckets[b.target], key)
+}
+
+// Keys 返回按字典序排列的所有键
+func (b *KVBucket) Keys() []string {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+
+	keys := make([]string, 0, len(b.store.buckets[b.target]))
+	for k := range b.store.buckets[b.target] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
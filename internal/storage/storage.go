@@ -1 +1,75 @@
 package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Result 表示一次插件执行的结果记录，供报告使用
+type Result struct {
+	ID         int
+	Plugin     string
+	Target     string
+	Vulnerable bool
+	Error      string
+	Output     string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Store 是执行结果存储的接口定义
+type Store interface {
+	Save(result *Result) (int, error)
+	Get(id int) (*Result, error)
+	List() ([]*Result, error)
+}
+
+// MemoryStore 是基于内存的结果存储
+type MemoryStore struct {
+	mu      sync.Mutex
+	results []*Result
+	nextID  int
+}
+
+// NewMemoryStore 创建一个新的内存结果存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{nextID: 1}
+}
+
+// Save 保存执行结果并分配ID
+func (s *MemoryStore) Save(result *Result) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result.ID = s.nextID
+	s.nextID++
+	s.results = append(s.results, result)
+
+	return result.ID, nil
+}
+
+// Get 根据ID获取执行结果
+func (s *MemoryStore) Get(id int) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.results {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+
+	return nil, fmt.Errorf("结果 #%d 不存在", id)
+}
+
+// List 返回所有执行结果
+func (s *MemoryStore) List() ([]*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]*Result, len(s.results))
+	copy(list, s.results)
+
+	return list, nil
+}
@@ -1 +1,126 @@
 package helper
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level 表示日志级别
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String 返回日志级别的名称
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+// ParseLevel 根据名称解析日志级别
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("未知的日志级别: %s", name)
+	}
+}
+
+// Logger 是带作用域的简单日志记录器
+type Logger struct {
+	mu    *sync.Mutex
+	out   io.Writer
+	level Level
+	scope string
+}
+
+// NewLogger 创建一个新的日志记录器
+func NewLogger(out io.Writer, level Level) *Logger {
+	return &Logger{
+		mu:    &sync.Mutex{},
+		out:   out,
+		level: level,
+	}
+}
+
+// Scope 返回一个带有子作用域的日志记录器，与父记录器共享输出
+func (l *Logger) Scope(name string) *Logger {
+	scope := name
+	if l.scope != "" {
+		scope = l.scope + "/" + name
+	}
+
+	return &Logger{
+		mu:    l.mu,
+		out:   l.out,
+		level: l.level,
+		scope: scope,
+	}
+}
+
+// Debugf 记录调试日志
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.logf(LevelDebug, format, args...)
+}
+
+// Infof 记录普通日志
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf(LevelInfo, format, args...)
+}
+
+// Warnf 记录警告日志
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logf(LevelWarn, format, args...)
+}
+
+// Errorf 记录错误日志
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logf(LevelError, format, args...)
+}
+
+// logf 按格式写入一条日志
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	if l == nil || l.out == nil || level < l.level {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(time.Now().Format("15:04:05"))
+	fmt.Fprintf(&b, " [%s]", level)
+	if l.scope != "" {
+		fmt.Fprintf(&b, " [%s]", l.scope)
+	}
+	b.WriteByte(' ')
+	fmt.Fprintf(&b, format, args...)
+	if !strings.HasSuffix(b.String(), "\n") {
+		b.WriteByte('\n')
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.out, b.String())
+}
@@ -0,0 +1,25 @@
+package helper
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+const markerAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// RandomString 生成由小写字母和数字组成的随机字符串
+// 生成结果可安全用于URL路径、DNS标签和响应匹配标记
+func RandomString(n int) string {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(markerAlphabet)))
+
+	for i := range b {
+		v, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = markerAlphabet[v.Int64()]
+	}
+
+	return string(b)
+}
@@ -0,0 +1,100 @@
+package matcher
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+// XPathMatcher 对HTML响应体执行XPath查询
+type XPathMatcher struct {
+	Expr string
+	expr *xpath.Expr
+}
+
+// XPath 编译XPath表达式并创建匹配器
+func XPath(expr string) (*XPathMatcher, error) {
+	e, err := xpath.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("无效的XPath %q: %w", expr, err)
+	}
+	return &XPathMatcher{Expr: expr, expr: e}, nil
+}
+
+// Match 实现Matcher接口
+// 表达式选择节点时提取节点文本（属性节点为属性值），计算结果为标量时提取其值
+func (m *XPathMatcher) Match(resp *network.HTTPResponse) Result {
+	doc, err := htmlquery.Parse(bytes.NewReader(resp.Body))
+	if err != nil {
+		return Result{}
+	}
+
+	var r Result
+	switch v := m.expr.Evaluate(htmlquery.CreateXPathNavigator(doc)).(type) {
+	case *xpath.NodeIterator:
+		for v.MoveNext() {
+			r.Extracts = append(r.Extracts, strings.TrimSpace(v.Current().Value()))
+		}
+		r.Matched = len(r.Extracts) > 0
+	case bool:
+		r.Matched = v
+	case string:
+		r.Matched = v != ""
+		if r.Matched {
+			r.Extracts = []string{v}
+		}
+	case float64:
+		r.Matched = v != 0
+		r.Extracts = []string{fmt.Sprint(v)}
+	}
+	return r
+}
+
+// CSSMatcher 对HTML响应体执行CSS选择器查询
+type CSSMatcher struct {
+	Selector string
+	// Attr 不为空时提取元素的属性值，否则提取元素文本
+	Attr string
+	sel  cascadia.Sel
+}
+
+// CSS 编译CSS选择器并创建匹配器，attr为空时提取元素文本
+func CSS(selector, attr string) (*CSSMatcher, error) {
+	sel, err := cascadia.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("无效的CSS选择器 %q: %w", selector, err)
+	}
+	return &CSSMatcher{Selector: selector, Attr: attr, sel: sel}, nil
+}
+
+// Match 实现Matcher接口，指定属性时只有带该属性的元素才算命中
+func (m *CSSMatcher) Match(resp *network.HTTPResponse) Result {
+	doc, err := html.Parse(bytes.NewReader(resp.Body))
+	if err != nil {
+		return Result{}
+	}
+
+	var r Result
+	for _, n := range cascadia.QueryAll(doc, m.sel) {
+		if m.Attr == "" {
+			r.Extracts = append(r.Extracts, strings.TrimSpace(htmlquery.InnerText(n)))
+			continue
+		}
+		for _, a := range n.Attr {
+			if a.Key == m.Attr {
+				r.Extracts = append(r.Extracts, a.Val)
+				break
+			}
+		}
+	}
+
+	r.Matched = len(r.Extracts) > 0
+	return r
+}
@@ -0,0 +1,92 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+const loginPage = `<html><head><title> Login </title>
+<meta name="generator" content="WordPress 6.4.2"></head>
+<body>
+<form id="login" action="/wp-login.php">
+	<input type="hidden" name="csrf" value="tok123">
+	<input name="user">
+</form>
+<ul class="links"><li><a href="/a">A</a></li><li><a href="/b">B</a></li><li>plain</li></ul>
+</body></html>`
+
+func TestXPathMatcher(t *testing.T) {
+	tests := []struct {
+		expr     string
+		matched  bool
+		extracts string
+	}{
+		{"//title", true, "Login"},
+		{"//meta[@name='generator']/@content", true, "WordPress 6.4.2"},
+		{"//input[@type='hidden']/@value", true, "tok123"},
+		{"//ul[@class='links']//a", true, "A,B"},
+		{"//table", false, ""},
+		{"count(//li)", true, "3"},
+		{"count(//table)", false, "0"},
+		{"boolean(//form[@id='login'])", true, ""},
+		{"boolean(//form[@id='signup'])", false, ""},
+		{"string(//form/@action)", true, "/wp-login.php"},
+		{"string(//form/@method)", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			m, err := XPath(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := m.Match(&network.HTTPResponse{Body: []byte(loginPage)})
+			if r.Matched != tt.matched || strings.Join(r.Extracts, ",") != tt.extracts {
+				t.Errorf("Match = %v %q, want %v %q", r.Matched, r.Extracts, tt.matched, tt.extracts)
+			}
+		})
+	}
+
+	for _, expr := range []string{"//", "//a[", "count(", "//a[@href='x'"} {
+		if _, err := XPath(expr); err == nil {
+			t.Errorf("XPath(%q) accepted", expr)
+		}
+	}
+}
+
+func TestCSSMatcher(t *testing.T) {
+	tests := []struct {
+		selector string
+		attr     string
+		matched  bool
+		extracts string
+	}{
+		{"title", "", true, "Login"},
+		{"ul.links a", "", true, "A,B"},
+		{"ul.links li", "href", false, ""},
+		{"ul.links a", "href", true, "/a,/b"},
+		{"input[type=hidden]", "value", true, "tok123"},
+		{"input", "value", true, "tok123"},
+		{"form#login", "action", true, "/wp-login.php"},
+		{"form#signup", "", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector+" "+tt.attr, func(t *testing.T) {
+			m, err := CSS(tt.selector, tt.attr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := m.Match(&network.HTTPResponse{Body: []byte(loginPage)})
+			if r.Matched != tt.matched || strings.Join(r.Extracts, ",") != tt.extracts {
+				t.Errorf("Match = %v %q, want %v %q", r.Matched, r.Extracts, tt.matched, tt.extracts)
+			}
+		})
+	}
+
+	for _, selector := range []string{"", "ul >", "a[href", "::"} {
+		if _, err := CSS(selector, ""); err == nil {
+			t.Errorf("CSS(%q) accepted", selector)
+		}
+	}
+}
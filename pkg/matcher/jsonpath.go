@@ -0,0 +1,552 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+// jsonStep 是JSONPath表达式中的一步
+type jsonStep struct {
+	// key 为对象键，"*" 表示所有子元素
+	key   string
+	index int
+	// isIndex 表示按数组下标取值，负数从末尾计数
+	isIndex bool
+	// recursive 表示在所有后代中查找（..）
+	recursive bool
+	// slice 是数组切片 [start:end:step]
+	slice *jsonSlice
+	// filter 是过滤表达式 [?(...)]，逐个判断数组元素或对象的值
+	filter *jsonFilter
+}
+
+// jsonSlice 是数组切片，省略的起止位置按步长方向取默认值
+type jsonSlice struct {
+	start, end, step int
+	hasStart, hasEnd bool
+}
+
+// jsonFilter 是过滤表达式，op为空时只要求相对路径存在
+type jsonFilter struct {
+	path  []jsonStep
+	op    string
+	value interface{}
+}
+
+// jsonFilterOps 是过滤表达式支持的比较运算符，两个字符的运算符在前
+var jsonFilterOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// parseJSONPath 解析JSONPath子集：$、.key、['key']、[n]、[*]、.*、..key、
+// [start:end:step] 切片和 [?(@.key op value)] 过滤表达式
+func parseJSONPath(path string) ([]jsonStep, error) {
+	p := strings.TrimSpace(path)
+	p = strings.TrimPrefix(p, "$")
+
+	var steps []jsonStep
+	for len(p) > 0 {
+		var step jsonStep
+		switch {
+		case strings.HasPrefix(p, ".."):
+			step.recursive = true
+			p = p[2:]
+		case p[0] == '.':
+			p = p[1:]
+		case p[0] == '[':
+		default:
+			if len(steps) > 0 {
+				return nil, fmt.Errorf("无效的JSONPath %q: 位置 %d", path, len(path)-len(p))
+			}
+		}
+
+		if strings.HasPrefix(p, "[") {
+			end := closingBracket(p)
+			if end < 0 {
+				return nil, fmt.Errorf("无效的JSONPath %q: 缺少 ]", path)
+			}
+			inner := strings.TrimSpace(p[1:end])
+			p = p[end+1:]
+			if err := parseBracket(inner, &step); err != nil {
+				return nil, fmt.Errorf("无效的JSONPath %q: %w", path, err)
+			}
+		} else {
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			step.key = p[:end]
+			p = p[end:]
+			if step.key == "" {
+				return nil, fmt.Errorf("无效的JSONPath %q: 空的键名", path)
+			}
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// closingBracket 返回与p开头的 [ 配对的 ] 的位置，跳过引号内和嵌套的括号，找不到时返回-1
+func closingBracket(p string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseBracket 解析方括号内的内容
+func parseBracket(inner string, step *jsonStep) error {
+	switch {
+	case inner == "*":
+		step.key = "*"
+	case isQuoted(inner):
+		step.key = inner[1 : len(inner)-1]
+	case strings.HasPrefix(inner, "?"):
+		f, err := parseJSONFilter(inner[1:])
+		if err != nil {
+			return err
+		}
+		step.filter = f
+	case strings.Contains(inner, ":"):
+		sl, err := parseJSONSlice(inner)
+		if err != nil {
+			return err
+		}
+		step.slice = sl
+	default:
+		n, err := strconv.Atoi(inner)
+		if err != nil {
+			return fmt.Errorf("[%s]", inner)
+		}
+		step.index, step.isIndex = n, true
+	}
+	return nil
+}
+
+// isQuoted 判断字符串是否被成对的单引号或双引号包围
+func isQuoted(s string) bool {
+	return len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]
+}
+
+// parseJSONSlice 解析 start:end:step，各部分均可省略
+func parseJSONSlice(inner string) (*jsonSlice, error) {
+	parts := strings.Split(inner, ":")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("无效的切片 [%s]", inner)
+	}
+
+	sl := &jsonSlice{step: 1}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("无效的切片 [%s]", inner)
+		}
+		switch i {
+		case 0:
+			sl.start, sl.hasStart = n, true
+		case 1:
+			sl.end, sl.hasEnd = n, true
+		case 2:
+			if n == 0 {
+				return nil, fmt.Errorf("切片步长不能为0: [%s]", inner)
+			}
+			sl.step = n
+		}
+	}
+	return sl, nil
+}
+
+// parseJSONFilter 解析 (@.path op value) 或 (@.path)，value 可以是字符串、数字、true、false 或 null
+func parseJSONFilter(expr string) (*jsonFilter, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "(") || !strings.HasSuffix(expr, ")") {
+		return nil, fmt.Errorf("过滤表达式需要括号: ?%s", expr)
+	}
+	expr = strings.TrimSpace(expr[1 : len(expr)-1])
+
+	left, op, right := expr, "", ""
+	if i, o := findFilterOp(expr); i >= 0 {
+		left, op, right = strings.TrimSpace(expr[:i]), o, strings.TrimSpace(expr[i+len(o):])
+	}
+	if !strings.HasPrefix(left, "@") {
+		return nil, fmt.Errorf("过滤表达式必须以 @ 开头: %s", expr)
+	}
+	path, err := parseJSONPath("$" + left[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	f := &jsonFilter{path: path, op: op}
+	if op == "" {
+		return f, nil
+	}
+
+	switch {
+	case isQuoted(right):
+		f.value = right[1 : len(right)-1]
+	case right == "true", right == "false":
+		f.value = right == "true"
+	case right == "null":
+	default:
+		n, err := strconv.ParseFloat(right, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的比较值: %s", right)
+		}
+		f.value = n
+	}
+	return f, nil
+}
+
+// findFilterOp 查找引号和方括号之外的第一个比较运算符
+func findFilterOp(expr string) (int, string) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+			continue
+		case c == '[':
+			depth++
+			continue
+		case c == ']':
+			depth--
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+		for _, op := range jsonFilterOps {
+			if strings.HasPrefix(expr[i:], op) {
+				return i, op
+			}
+		}
+	}
+	return -1, ""
+}
+
+// evalJSONPath 对解析后的JSON值执行查询
+func evalJSONPath(root interface{}, steps []jsonStep) []interface{} {
+	nodes := []interface{}{root}
+	for _, step := range steps {
+		var next []interface{}
+		for _, node := range nodes {
+			if step.recursive {
+				walkJSON(node, func(v interface{}) {
+					next = append(next, selectJSON(v, step)...)
+				})
+			} else {
+				next = append(next, selectJSON(node, step)...)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// selectJSON 返回节点中与步骤匹配的直接子元素
+func selectJSON(node interface{}, step jsonStep) []interface{} {
+	if step.filter != nil {
+		var out []interface{}
+		for _, child := range jsonChildren(node) {
+			if step.filter.match(child) {
+				out = append(out, child)
+			}
+		}
+		return out
+	}
+
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if step.isIndex || step.slice != nil {
+			return nil
+		}
+		if step.key == "*" {
+			return jsonChildren(v)
+		}
+		if child, ok := v[step.key]; ok {
+			return []interface{}{child}
+		}
+	case []interface{}:
+		if step.key == "*" {
+			return v
+		}
+		if step.slice != nil {
+			return step.slice.apply(v)
+		}
+		if step.isIndex {
+			i := step.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				return []interface{}{v[i]}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonChildren 返回数组元素或按键名排序的对象值
+func jsonChildren(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make([]interface{}, 0, len(v))
+		for _, k := range keys {
+			out = append(out, v[k])
+		}
+		return out
+	case []interface{}:
+		return v
+	}
+	return nil
+}
+
+// apply 按切片选取数组元素，越界的起止位置被截断到数组范围内
+func (sl *jsonSlice) apply(list []interface{}) []interface{} {
+	n := len(list)
+	norm := func(i, lo, hi int) int {
+		if i < 0 {
+			i += n
+		}
+		if i < lo {
+			return lo
+		}
+		if i > hi {
+			return hi
+		}
+		return i
+	}
+
+	var out []interface{}
+	if sl.step > 0 {
+		start, end := 0, n
+		if sl.hasStart {
+			start = norm(sl.start, 0, n)
+		}
+		if sl.hasEnd {
+			end = norm(sl.end, 0, n)
+		}
+		for i := start; i < end; i += sl.step {
+			out = append(out, list[i])
+		}
+		return out
+	}
+
+	// 负步长从后向前选取，end为-1表示一直到第一个元素
+	start, end := n-1, -1
+	if sl.hasStart {
+		start = norm(sl.start, -1, n-1)
+	}
+	if sl.hasEnd {
+		end = norm(sl.end, -1, n-1)
+	}
+	for i := start; i > end; i += sl.step {
+		out = append(out, list[i])
+	}
+	return out
+}
+
+// match 判断节点是否满足过滤条件，相对路径有多个结果时任意一个满足即可
+func (f *jsonFilter) match(node interface{}) bool {
+	results := evalJSONPath(node, f.path)
+	if f.op == "" {
+		return len(results) > 0
+	}
+	for _, v := range results {
+		if compareJSON(v, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareJSON 比较JSON值与过滤表达式中的字面量，类型不同时只有 != 成立
+func compareJSON(v interface{}, op string, want interface{}) bool {
+	cmp, ok := 0, false
+	switch w := want.(type) {
+	case string:
+		if s, isStr := v.(string); isStr {
+			cmp, ok = strings.Compare(s, w), true
+		}
+	case float64:
+		if n, isNum := v.(json.Number); isNum {
+			if f, err := n.Float64(); err == nil {
+				cmp, ok = compareFloat(f, w), true
+			}
+		}
+	case bool:
+		if b, isBool := v.(bool); isBool && (op == "==" || op == "!=") {
+			if b != w {
+				cmp = 1
+			}
+			ok = true
+		}
+	case nil:
+		if op == "==" || op == "!=" {
+			if v != nil {
+				cmp = 1
+			}
+			ok = true
+		}
+	}
+
+	if !ok {
+		return op == "!="
+	}
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// walkJSON 先序遍历节点及其所有后代，对象的值按键名顺序遍历
+func walkJSON(node interface{}, fn func(interface{})) {
+	fn(node)
+	for _, child := range jsonChildren(node) {
+		walkJSON(child, fn)
+	}
+}
+
+// jsonString 将查询结果转换为字符串，字符串值不带引号
+func jsonString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case json.Number:
+		return s.String()
+	case nil:
+		return "null"
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// QueryJSON 对JSON数据执行JSONPath查询，返回所有结果的字符串形式
+func QueryJSON(data []byte, path string) ([]string, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var root interface{}
+	if err := dec.Decode(&root); err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, v := range evalJSONPath(root, steps) {
+		out = append(out, jsonString(v))
+	}
+	return out, nil
+}
+
+// JSONPathMatcher 对JSON响应体执行JSONPath查询
+type JSONPathMatcher struct {
+	Path string
+	// Values 不为空时要求查询结果等于其中之一，否则只要求结果存在
+	Values []string
+	steps  []jsonStep
+}
+
+// JSONPath 创建JSONPath匹配器
+// 支持 $、.key、['key']、[n]、[-1]、[*]、.*、..key、[start:end:step] 和 [?(@.key op value)]
+func JSONPath(path string, values ...string) (*JSONPathMatcher, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONPathMatcher{Path: path, Values: values, steps: steps}, nil
+}
+
+// Match 实现Matcher接口，提取满足条件的查询结果
+// 响应体不是合法JSON时不匹配
+func (m *JSONPathMatcher) Match(resp *network.HTTPResponse) Result {
+	dec := json.NewDecoder(bytes.NewReader(resp.Body))
+	dec.UseNumber()
+	var root interface{}
+	if err := dec.Decode(&root); err != nil {
+		return Result{}
+	}
+
+	var r Result
+	for _, v := range evalJSONPath(root, m.steps) {
+		s := jsonString(v)
+		if len(m.Values) > 0 && !contains(m.Values, s) {
+			continue
+		}
+		r.Matched = true
+		r.Extracts = append(r.Extracts, s)
+	}
+	return r
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+const usersJSON = `{
+	"data": {
+		"role": "admin",
+		"users": [
+			{"name": "alice", "age": 31, "admin": true, "tags": ["ops", "dev"]},
+			{"name": "bob", "age": 17, "admin": false},
+			{"name": "carol", "age": 45, "email": null, "tags": ["dev"]},
+			{"name": "dave", "age": 23, "admin": true}
+		],
+		"meta": {"b": 2, "a": 1, "total": 4}
+	},
+	"odd key": "spaced",
+	"a]b": "bracket"
+}`
+
+func TestQueryJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"dot", "$.data.role", "admin"},
+		{"root omitted", "data.role", "admin"},
+		{"quoted key", "$['odd key']", "spaced"},
+		{"quoted key with bracket", `$["a]b"]`, "bracket"},
+		{"index", "$.data.users[1].name", "bob"},
+		{"negative index", "$.data.users[-1].name", "dave"},
+		{"index out of range", "$.data.users[9].name", ""},
+		{"object value", "$.data.meta", `{"a":1,"b":2,"total":4}`},
+		{"number", "$.data.meta.total", "4"},
+		{"null", "$.data.users[2].email", "null"},
+
+		{"array wildcard", "$.data.users[*].name", "alice,bob,carol,dave"},
+		{"dot wildcard", "$.data.users.*.age", "31,17,45,23"},
+		{"object wildcard sorted by key", "$.data.meta.*", "1,2,4"},
+		{"recursive", "$..tags[0]", "ops,dev"},
+		{"recursive key", "$..role", "admin"},
+
+		{"slice", "$.data.users[1:3].name", "bob,carol"},
+		{"slice open end", "$.data.users[2:].name", "carol,dave"},
+		{"slice open start", "$.data.users[:2].name", "alice,bob"},
+		{"slice negative start", "$.data.users[-2:].name", "carol,dave"},
+		{"slice step", "$.data.users[::2].name", "alice,carol"},
+		{"slice reverse", "$.data.users[::-1].name", "dave,carol,bob,alice"},
+		{"slice reverse range", "$.data.users[2:0:-1].name", "carol,bob"},
+		{"slice clamped", "$.data.users[-10:10].name", "alice,bob,carol,dave"},
+		{"slice empty", "$.data.users[3:1].name", ""},
+		{"slice on object", "$.data.meta[0:1]", ""},
+
+		{"filter equals", "$.data.users[?(@.name == 'bob')].age", "17"},
+		{"filter double quotes", `$.data.users[?(@.name=="carol")].age`, "45"},
+		{"filter not equals", "$.data.users[?(@.name != 'bob')].name", "alice,carol,dave"},
+		{"filter number", "$.data.users[?(@.age >= 23)].name", "alice,carol,dave"},
+		{"filter less", "$.data.users[?(@.age<18)].name", "bob"},
+		{"filter bool", "$.data.users[?(@.admin == true)].name", "alice,dave"},
+		{"filter null", "$.data.users[?(@.email == null)].name", "carol"},
+		{"filter exists", "$.data.users[?(@.tags)].name", "alice,carol"},
+		{"filter nested index", "$.data.users[?(@.tags[0] == 'dev')].name", "carol"},
+		{"filter any result", "$.data.users[?(@.tags[*] == 'dev')].name", "alice,carol"},
+		{"filter type mismatch", "$.data.users[?(@.age == '17')].name", ""},
+		{"filter on object values", "$.data.meta[?(@ > 1)]", "2,4"},
+		{"recursive filter", "$..[?(@.admin == false)].name", "bob"},
+		{"operator inside quotes", "$.data.users[?(@.name == 'a<b')].name", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := QueryJSON([]byte(usersJSON), tt.path)
+			if err != nil {
+				t.Fatalf("QueryJSON(%s): %v", tt.path, err)
+			}
+			if s := strings.Join(got, ","); s != tt.want {
+				t.Errorf("QueryJSON(%s) = %s, want %s", tt.path, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathMalformed(t *testing.T) {
+	for _, path := range []string{
+		"$.",
+		"$..",
+		"$.a.",
+		"$[",
+		"$['a",
+		"$[']",
+		"$[abc]",
+		"$[1.5]",
+		"$.a[0]b",
+		"$[1:2:3:4]",
+		"$[a:b]",
+		"$[::0]",
+		"$[?(@.a == 1]",
+		"$[?@.a == 1]",
+		"$[?()]",
+		"$[?(.a == 1)]",
+		"$[?(@.a == )]",
+		"$[?(@.a == bare)]",
+		"$[?(@. == 1)]",
+		"$[?(@.a[ == 1)]",
+	} {
+		t.Run(path, func(t *testing.T) {
+			if _, err := JSONPath(path); err == nil {
+				t.Errorf("JSONPath(%q) accepted", path)
+			}
+		})
+	}
+
+	if _, err := QueryJSON([]byte(`{"a":`), "$.a"); err == nil {
+		t.Error("QueryJSON accepted malformed JSON")
+	}
+}
+
+func TestJSONPathMatcher(t *testing.T) {
+	resp := &network.HTTPResponse{Body: []byte(usersJSON)}
+
+	tests := []struct {
+		path     string
+		values   []string
+		matched  bool
+		extracts string
+	}{
+		{"$.data.role", nil, true, "admin"},
+		{"$.data.role", []string{"user", "admin"}, true, "admin"},
+		{"$.data.role", []string{"user"}, false, ""},
+		{"$.data.users[*].age", []string{"17", "45"}, true, "17,45"},
+		{"$.data.missing", nil, false, ""},
+	}
+	for _, tt := range tests {
+		m, err := JSONPath(tt.path, tt.values...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := m.Match(resp)
+		if r.Matched != tt.matched || strings.Join(r.Extracts, ",") != tt.extracts {
+			t.Errorf("JSONPath(%s, %q) = %v %q, want %v %q", tt.path, tt.values, r.Matched, r.Extracts, tt.matched, tt.extracts)
+		}
+	}
+
+	m, _ := JSONPath("$.a")
+	if r := m.Match(&network.HTTPResponse{Body: []byte("<html>")}); r.Matched {
+		t.Error("non-JSON body matched")
+	}
+}
@@ -0,0 +1,386 @@
+// Package matcher 提供针对HTTP响应的可组合匹配器
+//
+// 匹配器既可以在插件中直接构造，也可以由声明式的 Spec 编译得到：
+//
+//	m := matcher.And(
+//		matcher.Status(200),
+//		matcher.MustRegex(`version: ([\d.]+)`),
+//	)
+//	if r := m.Match(resp); r.Matched {
+//		fmt.Println(r.Extracts)
+//	}
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+// Result 是一次匹配的结果
+type Result struct {
+	Matched bool
+	// Extracts 是匹配过程中提取的内容，如正则捕获组和查询结果
+	Extracts []string
+	// Named 是正则表达式命名捕获组的值
+	Named map[string]string
+}
+
+// Matcher 判断响应是否满足条件
+type Matcher interface {
+	Match(resp *network.HTTPResponse) Result
+}
+
+// MatcherFunc 将函数适配为Matcher
+type MatcherFunc func(resp *network.HTTPResponse) Result
+
+// Match 实现Matcher接口
+func (f MatcherFunc) Match(resp *network.HTTPResponse) Result {
+	return f(resp)
+}
+
+// Part 指定匹配响应的哪一部分
+type Part string
+
+const (
+	// PartBody 匹配响应体
+	PartBody Part = "body"
+	// PartHeader 匹配响应头
+	PartHeader Part = "header"
+	// PartAll 匹配状态行、响应头和响应体
+	PartAll Part = "all"
+)
+
+// partText 返回响应指定部分的文本
+func partText(resp *network.HTTPResponse, part Part) string {
+	switch part {
+	case PartHeader:
+		return headerText(resp)
+	case PartAll:
+		return fmt.Sprintf("HTTP %d\r\n%s\r\n%s", resp.StatusCode, headerText(resp), resp.Body)
+	default:
+		return string(resp.Body)
+	}
+}
+
+// headerText 将响应头按名称排序后格式化为 "Name: value" 行
+func headerText(resp *network.HTTPResponse) string {
+	names := make([]string, 0, len(resp.Headers))
+	for name := range resp.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		for _, v := range resp.Headers[name] {
+			b.WriteString(name)
+			b.WriteString(": ")
+			b.WriteString(v)
+			b.WriteString("\r\n")
+		}
+	}
+	return b.String()
+}
+
+// WordMatcher 检查响应中是否包含指定的字符串
+type WordMatcher struct {
+	Words []string
+	Part  Part
+	// All 为true时要求包含所有字符串，否则包含任意一个即可
+	All             bool
+	CaseInsensitive bool
+}
+
+// Word 创建匹配响应体中任意一个字符串的匹配器
+func Word(words ...string) *WordMatcher {
+	return &WordMatcher{Words: words, Part: PartBody}
+}
+
+// Match 实现Matcher接口，提取命中的字符串
+func (m *WordMatcher) Match(resp *network.HTTPResponse) Result {
+	text := partText(resp, m.Part)
+	if m.CaseInsensitive {
+		text = strings.ToLower(text)
+	}
+
+	var r Result
+	for _, word := range m.Words {
+		w := word
+		if m.CaseInsensitive {
+			w = strings.ToLower(w)
+		}
+		if strings.Contains(text, w) {
+			r.Extracts = append(r.Extracts, word)
+		} else if m.All {
+			return Result{}
+		}
+	}
+
+	r.Matched = len(r.Extracts) > 0
+	return r
+}
+
+// RegexMatcher 检查响应是否匹配正则表达式
+type RegexMatcher struct {
+	Patterns []*regexp.Regexp
+	Part     Part
+	// All 为true时要求匹配所有表达式，否则匹配任意一个即可
+	All bool
+}
+
+// Regex 编译正则表达式并创建匹配响应体的匹配器
+func Regex(patterns ...string) (*RegexMatcher, error) {
+	m := &RegexMatcher{Part: PartBody}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则表达式 %q: %w", p, err)
+		}
+		m.Patterns = append(m.Patterns, re)
+	}
+	return m, nil
+}
+
+// MustRegex 与Regex相同，表达式无效时panic
+func MustRegex(patterns ...string) *RegexMatcher {
+	m, err := Regex(patterns...)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Match 实现Matcher接口
+// 有捕获组时提取每次匹配的捕获组，否则提取整个匹配的文本
+func (m *RegexMatcher) Match(resp *network.HTTPResponse) Result {
+	text := partText(resp, m.Part)
+
+	var r Result
+	for _, re := range m.Patterns {
+		matches := re.FindAllStringSubmatch(text, -1)
+		if len(matches) == 0 {
+			if m.All {
+				return Result{}
+			}
+			continue
+		}
+
+		r.Matched = true
+		names := re.SubexpNames()
+		for _, match := range matches {
+			if len(match) == 1 {
+				r.Extracts = append(r.Extracts, match[0])
+				continue
+			}
+			for i, group := range match[1:] {
+				r.Extracts = append(r.Extracts, group)
+				if name := names[i+1]; name != "" {
+					if r.Named == nil {
+						r.Named = make(map[string]string)
+					}
+					if _, ok := r.Named[name]; !ok {
+						r.Named[name] = group
+					}
+				}
+			}
+		}
+	}
+
+	return r
+}
+
+// StatusMatcher 检查状态码是否在集合中
+type StatusMatcher struct {
+	Codes []int
+}
+
+// Status 创建匹配任意一个状态码的匹配器
+func Status(codes ...int) *StatusMatcher {
+	return &StatusMatcher{Codes: codes}
+}
+
+// Match 实现Matcher接口
+func (m *StatusMatcher) Match(resp *network.HTTPResponse) Result {
+	for _, code := range m.Codes {
+		if resp.StatusCode == code {
+			return Result{Matched: true}
+		}
+	}
+	return Result{}
+}
+
+// HeaderMatcher 检查响应头是否存在，或其值是否匹配正则表达式
+type HeaderMatcher struct {
+	Name string
+	// Value 为空时只检查响应头是否存在
+	Value *regexp.Regexp
+}
+
+// Header 创建检查响应头的匹配器，pattern为空时只检查是否存在
+func Header(name, pattern string) (*HeaderMatcher, error) {
+	m := &HeaderMatcher{Name: name}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则表达式 %q: %w", pattern, err)
+		}
+		m.Value = re
+	}
+	return m, nil
+}
+
+// Match 实现Matcher接口，提取匹配的响应头值
+func (m *HeaderMatcher) Match(resp *network.HTTPResponse) Result {
+	var r Result
+	for _, v := range resp.Headers.Values(m.Name) {
+		if m.Value == nil || m.Value.MatchString(v) {
+			r.Matched = true
+			r.Extracts = append(r.Extracts, v)
+		}
+	}
+	return r
+}
+
+// SizeMatcher 检查响应体长度是否在范围内
+type SizeMatcher struct {
+	// Min 和 Max 为0时表示不限制
+	Min, Max int
+}
+
+// Size 创建检查响应体长度的匹配器
+func Size(min, max int) *SizeMatcher {
+	return &SizeMatcher{Min: min, Max: max}
+}
+
+// Match 实现Matcher接口
+func (m *SizeMatcher) Match(resp *network.HTTPResponse) Result {
+	n := len(resp.Body)
+	if n < m.Min || (m.Max > 0 && n > m.Max) {
+		return Result{}
+	}
+	return Result{Matched: true}
+}
+
+// Phase 指定TimeMatcher检查的耗时阶段
+type Phase string
+
+const (
+	// PhaseTotal 检查整个请求的耗时
+	PhaseTotal Phase = "total"
+	// PhaseDNS 检查DNS解析耗时
+	PhaseDNS Phase = "dns"
+	// PhaseConnect 检查建立TCP连接的耗时
+	PhaseConnect Phase = "connect"
+	// PhaseTLS 检查TLS握手耗时
+	PhaseTLS Phase = "tls"
+	// PhaseTTFB 检查收到响应首字节的耗时，不受响应体大小影响，适合时间盲注
+	PhaseTTFB Phase = "ttfb"
+)
+
+// ParsePhase 解析耗时阶段，空字符串表示PhaseTotal
+func ParsePhase(value string) (Phase, error) {
+	switch p := Phase(value); p {
+	case "":
+		return PhaseTotal, nil
+	case PhaseTotal, PhaseDNS, PhaseConnect, PhaseTLS, PhaseTTFB:
+		return p, nil
+	}
+	return "", fmt.Errorf("未知的耗时阶段: %s", value)
+}
+
+// phaseDuration 返回响应在指定阶段的耗时
+func phaseDuration(resp *network.HTTPResponse, phase Phase) time.Duration {
+	switch phase {
+	case PhaseDNS:
+		return resp.Timing.DNS
+	case PhaseConnect:
+		return resp.Timing.Connect
+	case PhaseTLS:
+		return resp.Timing.TLS
+	case PhaseTTFB:
+		return resp.Timing.TTFB
+	}
+	return resp.Duration
+}
+
+// TimeMatcher 检查响应耗时是否在范围内
+type TimeMatcher struct {
+	// Min 和 Max 为0时表示不限制
+	Min, Max time.Duration
+	// Phase 为空时检查整个请求的耗时
+	Phase Phase
+}
+
+// Slower 创建匹配耗时不少于d的响应的匹配器
+func Slower(d time.Duration) *TimeMatcher {
+	return &TimeMatcher{Min: d}
+}
+
+// Faster 创建匹配耗时不超过d的响应的匹配器
+func Faster(d time.Duration) *TimeMatcher {
+	return &TimeMatcher{Max: d}
+}
+
+// Match 实现Matcher接口
+func (m *TimeMatcher) Match(resp *network.HTTPResponse) Result {
+	d := phaseDuration(resp, m.Phase)
+	if d < m.Min || (m.Max > 0 && d > m.Max) {
+		return Result{}
+	}
+	return Result{Matched: true}
+}
+
+// And 创建要求所有匹配器都命中的匹配器，合并所有提取结果
+func And(matchers ...Matcher) Matcher {
+	return MatcherFunc(func(resp *network.HTTPResponse) Result {
+		var r Result
+		for _, m := range matchers {
+			sub := m.Match(resp)
+			if !sub.Matched {
+				return Result{}
+			}
+			r.merge(sub)
+		}
+		r.Matched = len(matchers) > 0
+		return r
+	})
+}
+
+// Or 创建任意一个匹配器命中即可的匹配器，合并所有命中的提取结果
+func Or(matchers ...Matcher) Matcher {
+	return MatcherFunc(func(resp *network.HTTPResponse) Result {
+		var r Result
+		for _, m := range matchers {
+			if sub := m.Match(resp); sub.Matched {
+				r.Matched = true
+				r.merge(sub)
+			}
+		}
+		return r
+	})
+}
+
+// Not 创建对匹配结果取反的匹配器
+func Not(m Matcher) Matcher {
+	return MatcherFunc(func(resp *network.HTTPResponse) Result {
+		return Result{Matched: !m.Match(resp).Matched}
+	})
+}
+
+// merge 合并另一个结果的提取内容，已存在的命名捕获组不被覆盖
+func (r *Result) merge(other Result) {
+	r.Extracts = append(r.Extracts, other.Extracts...)
+	for k, v := range other.Named {
+		if r.Named == nil {
+			r.Named = make(map[string]string)
+		}
+		if _, ok := r.Named[k]; !ok {
+			r.Named[k] = v
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package matcher
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+func testResponse() *network.HTTPResponse {
+	return &network.HTTPResponse{
+		StatusCode: 200,
+		Headers: http.Header{
+			"Server":     {"nginx/1.18.0"},
+			"Set-Cookie": {"sid=1; HttpOnly", "lang=en"},
+		},
+		Body:     []byte(`<title>Admin Panel</title> version: 2.4.1 build: 77`),
+		Duration: 3 * time.Second,
+		Timing: network.Timing{
+			DNS:     10 * time.Millisecond,
+			Connect: 20 * time.Millisecond,
+			TLS:     30 * time.Millisecond,
+			TTFB:    2 * time.Second,
+			Total:   3 * time.Second,
+		},
+	}
+}
+
+func TestWordMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        *WordMatcher
+		matched  bool
+		extracts string
+	}{
+		{"any", Word("missing", "Admin"), true, "Admin"},
+		{"none", Word("missing"), false, ""},
+		{"all", &WordMatcher{Words: []string{"Admin", "version"}, All: true}, true, "Admin,version"},
+		{"all missing one", &WordMatcher{Words: []string{"Admin", "missing"}, All: true}, false, ""},
+		{"case sensitive", Word("admin panel"), false, ""},
+		{"case insensitive", &WordMatcher{Words: []string{"admin panel"}, CaseInsensitive: true}, true, "admin panel"},
+		{"header part", &WordMatcher{Words: []string{"nginx"}, Part: PartHeader}, true, "nginx"},
+		{"body only", Word("nginx"), false, ""},
+		{"all parts", &WordMatcher{Words: []string{"HTTP 200", "nginx", "build"}, Part: PartAll, All: true}, true, "HTTP 200,nginx,build"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.m.Match(testResponse())
+			if r.Matched != tt.matched || strings.Join(r.Extracts, ",") != tt.extracts {
+				t.Errorf("Match = %v %q, want %v %q", r.Matched, r.Extracts, tt.matched, tt.extracts)
+			}
+		})
+	}
+}
+
+func TestRegexMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		all      bool
+		part     Part
+		matched  bool
+		extracts string
+		named    map[string]string
+	}{
+		{"whole match", []string{`\d+\.\d+\.\d+`}, false, PartBody, true, "2.4.1", nil},
+		{"groups", []string{`version: (\d+)\.(\d+)`}, false, PartBody, true, "2,4", nil},
+		{"named", []string{`version: (?P<version>[\d.]+)`, `build: (?P<build>\d+)`}, false, PartBody, true, "2.4.1,77",
+			map[string]string{"version": "2.4.1", "build": "77"}},
+		{"every match", []string{`\d+`}, false, PartBody, true, "2,4,1,77", nil},
+		{"any", []string{`missing`, `Admin`}, false, PartBody, true, "Admin", nil},
+		{"all missing one", []string{`missing`, `Admin`}, true, PartBody, false, "", nil},
+		{"header", []string{`Server: nginx/([\d.]+)`}, false, PartHeader, true, "1.18.0", nil},
+		{"status line", []string{`^HTTP 200`}, false, PartAll, true, "HTTP 200", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Regex(tt.patterns...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.All, m.Part = tt.all, tt.part
+			r := m.Match(testResponse())
+			if r.Matched != tt.matched || strings.Join(r.Extracts, ",") != tt.extracts {
+				t.Errorf("Match = %v %q, want %v %q", r.Matched, r.Extracts, tt.matched, tt.extracts)
+			}
+			for k, v := range tt.named {
+				if r.Named[k] != v {
+					t.Errorf("Named[%s] = %q, want %q", k, r.Named[k], v)
+				}
+			}
+		})
+	}
+
+	if _, err := Regex(`ok`, `(unclosed`); err == nil {
+		t.Error("invalid regex accepted")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("MustRegex did not panic on an invalid pattern")
+		}
+	}()
+	MustRegex(`[`)
+}
+
+func TestStatusHeaderSizeMatchers(t *testing.T) {
+	mustHeader := func(name, pattern string) *HeaderMatcher {
+		m, err := Header(name, pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	tests := []struct {
+		name     string
+		m        Matcher
+		matched  bool
+		extracts string
+	}{
+		{"status in set", Status(301, 200), true, ""},
+		{"status not in set", Status(404, 500), false, ""},
+		{"status empty set", Status(), false, ""},
+		{"header exists", mustHeader("server", ""), true, "nginx/1.18.0"},
+		{"header missing", mustHeader("X-Powered-By", ""), false, ""},
+		{"header value", mustHeader("Server", `^nginx/1\.1`), true, "nginx/1.18.0"},
+		{"header value mismatch", mustHeader("Server", `apache`), false, ""},
+		{"header multiple values", mustHeader("Set-Cookie", `=`), true, "sid=1; HttpOnly,lang=en"},
+		{"header one of many", mustHeader("Set-Cookie", `HttpOnly`), true, "sid=1; HttpOnly"},
+		{"size in range", Size(10, 100), true, ""},
+		{"size unbounded", Size(0, 0), true, ""},
+		{"size too small", Size(100, 0), false, ""},
+		{"size too large", Size(0, 10), false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.m.Match(testResponse())
+			if r.Matched != tt.matched || strings.Join(r.Extracts, ",") != tt.extracts {
+				t.Errorf("Match = %v %q, want %v %q", r.Matched, r.Extracts, tt.matched, tt.extracts)
+			}
+		})
+	}
+
+	if _, err := Header("Server", `(`); err == nil {
+		t.Error("invalid header pattern accepted")
+	}
+}
+
+func TestTimeMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       *TimeMatcher
+		matched bool
+	}{
+		{"slower total", Slower(2 * time.Second), true},
+		{"not slower total", Slower(5 * time.Second), false},
+		{"faster total", Faster(5 * time.Second), true},
+		{"not faster total", Faster(time.Second), false},
+		{"ttfb", &TimeMatcher{Min: 2 * time.Second, Phase: PhaseTTFB}, true},
+		{"ttfb range", &TimeMatcher{Min: time.Second, Max: 1500 * time.Millisecond, Phase: PhaseTTFB}, false},
+		{"dns", &TimeMatcher{Max: 10 * time.Millisecond, Phase: PhaseDNS}, true},
+		{"connect", &TimeMatcher{Min: 25 * time.Millisecond, Phase: PhaseConnect}, false},
+		{"tls", &TimeMatcher{Min: 30 * time.Millisecond, Max: 30 * time.Millisecond, Phase: PhaseTLS}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if r := tt.m.Match(testResponse()); r.Matched != tt.matched {
+				t.Errorf("Match = %v, want %v", r.Matched, tt.matched)
+			}
+		})
+	}
+
+	for value, want := range map[string]Phase{"": PhaseTotal, "total": PhaseTotal, "dns": PhaseDNS, "ttfb": PhaseTTFB} {
+		if got, err := ParsePhase(value); err != nil || got != want {
+			t.Errorf("ParsePhase(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParsePhase("TTFB"); err == nil {
+		t.Error("ParsePhase accepted an unknown phase")
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	hit := MustRegex(`version: (?P<version>[\d.]+)`)
+	other := MustRegex(`build: (?P<version>\d+)`)
+	miss := Word("missing")
+
+	tests := []struct {
+		name     string
+		m        Matcher
+		matched  bool
+		extracts string
+		version  string
+	}{
+		{"and all hit", And(Status(200), hit, other), true, "2.4.1,77", "2.4.1"},
+		{"and one miss", And(hit, miss), false, "", ""},
+		{"and empty", And(), false, "", ""},
+		{"or any hit", Or(miss, other, hit), true, "77,2.4.1", "77"},
+		{"or none", Or(miss, Status(500)), false, "", ""},
+		{"or empty", Or(), false, "", ""},
+		{"not miss", Not(miss), true, "", ""},
+		{"not hit", Not(hit), false, "", ""},
+		{"nested", Or(And(hit, miss), Not(Status(404))), true, "", ""},
+		{"func", MatcherFunc(func(resp *network.HTTPResponse) Result {
+			return Result{Matched: resp.StatusCode == 200, Extracts: []string{"custom"}}
+		}), true, "custom", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.m.Match(testResponse())
+			if r.Matched != tt.matched || strings.Join(r.Extracts, ",") != tt.extracts {
+				t.Errorf("Match = %v %q, want %v %q", r.Matched, r.Extracts, tt.matched, tt.extracts)
+			}
+			// 先命中的命名捕获组不被覆盖
+			if r.Named["version"] != tt.version {
+				t.Errorf("Named[version] = %q, want %q", r.Named["version"], tt.version)
+			}
+		})
+	}
+}
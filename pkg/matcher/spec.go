@@ -0,0 +1,177 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Spec 是匹配器的声明式描述，可从JSON模板加载
+//
+//	{"type": "and", "matchers": [
+//	  {"type": "status", "status": [200]},
+//	  {"type": "regex", "regex": ["root:.*:0:0:"], "part": "body"},
+//	  {"type": "header", "name": "Server", "regex": ["nginx"], "negative": true}
+//	]}
+type Spec struct {
+	// Type 可取 word、regex、json、xpath、css、status、header、size、time、and、or
+	Type string `json:"type"`
+	// Part 指定 word 和 regex 匹配的部分：body（默认）、header 或 all
+	Part string `json:"part,omitempty"`
+	// Condition 为 and 时要求 words 或 regex 全部命中，默认为 or
+	Condition       string   `json:"condition,omitempty"`
+	CaseInsensitive bool     `json:"case_insensitive,omitempty"`
+	Words           []string `json:"words,omitempty"`
+	Regex           []string `json:"regex,omitempty"`
+	// Query 是 json、xpath 和 css 类型的查询表达式
+	Query string `json:"query,omitempty"`
+	// Values 是 json 查询结果的期望值
+	Values []string `json:"values,omitempty"`
+	// Attr 是 css 类型要提取的属性
+	Attr   string `json:"attr,omitempty"`
+	Status []int  `json:"status,omitempty"`
+	// Name 是 header 类型检查的响应头，Regex 只取第一个表达式用于匹配其值
+	Name string `json:"name,omitempty"`
+	// Min 和 Max 是 size 类型的字节数范围，或 time 类型的时长范围（如 "5s"）
+	Min string `json:"min,omitempty"`
+	Max string `json:"max,omitempty"`
+	// Phase 是 time 类型检查的耗时阶段：total（默认）、dns、connect、tls 或 ttfb
+	Phase string `json:"phase,omitempty"`
+	// Negative 对匹配结果取反
+	Negative bool   `json:"negative,omitempty"`
+	Matchers []Spec `json:"matchers,omitempty"`
+}
+
+// Parse 从JSON描述编译匹配器
+func Parse(data []byte) (Matcher, error) {
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("解析匹配器失败: %w", err)
+	}
+	return spec.Compile()
+}
+
+// Compile 将声明式描述编译为匹配器
+func (s Spec) Compile() (Matcher, error) {
+	m, err := s.compile()
+	if err != nil {
+		return nil, err
+	}
+	if s.Negative {
+		return Not(m), nil
+	}
+	return m, nil
+}
+
+func (s Spec) compile() (Matcher, error) {
+	part := PartBody
+	switch s.Part {
+	case "", "body":
+	case "header":
+		part = PartHeader
+	case "all":
+		part = PartAll
+	default:
+		return nil, fmt.Errorf("未知的匹配部分: %s", s.Part)
+	}
+	all := s.Condition == "and"
+
+	switch s.Type {
+	case "word":
+		return &WordMatcher{Words: s.Words, Part: part, All: all, CaseInsensitive: s.CaseInsensitive}, nil
+	case "regex":
+		patterns := s.Regex
+		if s.CaseInsensitive {
+			patterns = make([]string, len(s.Regex))
+			for i, p := range s.Regex {
+				patterns[i] = "(?i)" + p
+			}
+		}
+		m, err := Regex(patterns...)
+		if err != nil {
+			return nil, err
+		}
+		m.Part, m.All = part, all
+		return m, nil
+	case "json":
+		return JSONPath(s.Query, s.Values...)
+	case "xpath":
+		return XPath(s.Query)
+	case "css":
+		return CSS(s.Query, s.Attr)
+	case "status":
+		return Status(s.Status...), nil
+	case "header":
+		pattern := ""
+		if len(s.Regex) > 0 {
+			pattern = s.Regex[0]
+		}
+		return Header(s.Name, pattern)
+	case "size":
+		min, max, err := s.sizeRange()
+		if err != nil {
+			return nil, err
+		}
+		return Size(min, max), nil
+	case "time":
+		min, max, err := s.timeRange()
+		if err != nil {
+			return nil, err
+		}
+		phase, err := ParsePhase(s.Phase)
+		if err != nil {
+			return nil, err
+		}
+		return &TimeMatcher{Min: min, Max: max, Phase: phase}, nil
+	case "and", "or":
+		matchers := make([]Matcher, 0, len(s.Matchers))
+		for _, sub := range s.Matchers {
+			m, err := sub.Compile()
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, m)
+		}
+		if s.Type == "and" {
+			return And(matchers...), nil
+		}
+		return Or(matchers...), nil
+	default:
+		return nil, fmt.Errorf("未知的匹配器类型: %s", s.Type)
+	}
+}
+
+// sizeRange 解析 size 类型的字节数范围
+func (s Spec) sizeRange() (int, int, error) {
+	var min, max int
+	var err error
+	if s.Min != "" {
+		if min, err = strconv.Atoi(s.Min); err != nil {
+			return 0, 0, fmt.Errorf("无效的min: %s", s.Min)
+		}
+	}
+	if s.Max != "" {
+		if max, err = strconv.Atoi(s.Max); err != nil {
+			return 0, 0, fmt.Errorf("无效的max: %s", s.Max)
+		}
+	}
+	return min, max, nil
+}
+
+// timeRange 解析 time 类型的时长范围
+func (s Spec) timeRange() (time.Duration, time.Duration, error) {
+	var min, max time.Duration
+	var err error
+	if s.Min != "" {
+		if min, err = time.ParseDuration(s.Min); err != nil {
+			return 0, 0, fmt.Errorf("无效的min: %s", s.Min)
+		}
+	}
+	if s.Max != "" {
+		if max, err = time.ParseDuration(s.Max); err != nil {
+			return 0, 0, fmt.Errorf("无效的max: %s", s.Max)
+		}
+	}
+	return min, max, nil
+}
@@ -0,0 +1,83 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		matched  bool
+		extracts string
+	}{
+		{"word", `{"type":"word","words":["Admin"]}`, true, "Admin"},
+		{"word and", `{"type":"word","words":["Admin","missing"],"condition":"and"}`, false, ""},
+		{"word header", `{"type":"word","words":["nginx"],"part":"header"}`, true, "nginx"},
+		{"word case insensitive", `{"type":"word","words":["ADMIN"],"case_insensitive":true}`, true, "ADMIN"},
+		{"regex", `{"type":"regex","regex":["version: ([\\d.]+)"]}`, true, "2.4.1"},
+		{"regex case insensitive", `{"type":"regex","regex":["ADMIN (PANEL)"],"case_insensitive":true}`, true, "Panel"},
+		{"regex all", `{"type":"regex","regex":["Admin","build"],"condition":"and","part":"all"}`, true, "Admin,build"},
+		{"json", `{"type":"json","query":"$.a","values":["1"]}`, false, ""},
+		{"xpath", `{"type":"xpath","query":"//title"}`, true, "Admin Panel"},
+		{"css", `{"type":"css","query":"title"}`, true, "Admin Panel"},
+		{"status", `{"type":"status","status":[404,200]}`, true, ""},
+		{"header", `{"type":"header","name":"Server","regex":["^nginx"]}`, true, "nginx/1.18.0"},
+		{"header exists", `{"type":"header","name":"X-Missing"}`, false, ""},
+		{"size", `{"type":"size","min":"10","max":"1000"}`, true, ""},
+		{"time", `{"type":"time","min":"2s"}`, true, ""},
+		{"time phase", `{"type":"time","max":"1s","phase":"ttfb"}`, false, ""},
+		{"negative", `{"type":"status","status":[500],"negative":true}`, true, ""},
+		{"and", `{"type":"and","matchers":[
+			{"type":"status","status":[200]},
+			{"type":"regex","regex":["build: (\\d+)"]},
+			{"type":"header","name":"Server","regex":["apache"],"negative":true}
+		]}`, true, "77"},
+		{"or", `{"type":"or","matchers":[{"type":"word","words":["missing"]},{"type":"word","words":["Panel"]}]}`, true, "Panel"},
+		{"negated group", `{"type":"or","negative":true,"matchers":[{"type":"status","status":[200]}]}`, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse([]byte(tt.spec))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			r := m.Match(testResponse())
+			if r.Matched != tt.matched || strings.Join(r.Extracts, ",") != tt.extracts {
+				t.Errorf("Match = %v %q, want %v %q", r.Matched, r.Extracts, tt.matched, tt.extracts)
+			}
+		})
+	}
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{"not json", `{"type":`, "解析匹配器失败"},
+		{"unknown type", `{"type":"glob"}`, "未知的匹配器类型"},
+		{"missing type", `{}`, "未知的匹配器类型"},
+		{"unknown part", `{"type":"word","part":"cookie"}`, "未知的匹配部分"},
+		{"bad regex", `{"type":"regex","regex":["("]}`, "无效的正则表达式"},
+		{"bad header regex", `{"type":"header","name":"Server","regex":["["]}`, "无效的正则表达式"},
+		{"bad jsonpath", `{"type":"json","query":"$["}`, "无效的JSONPath"},
+		{"bad jsonpath filter", `{"type":"json","query":"$[?(@.a == )]"}`, "无效的JSONPath"},
+		{"bad xpath", `{"type":"xpath","query":"//a["}`, "无效的XPath"},
+		{"bad css", `{"type":"css","query":"a[href"}`, "无效的CSS选择器"},
+		{"bad size", `{"type":"size","min":"1k"}`, "无效的min"},
+		{"bad time", `{"type":"time","max":"soon"}`, "无效的max"},
+		{"bad phase", `{"type":"time","min":"1s","phase":"ssl"}`, "未知的耗时阶段"},
+		{"nested error", `{"type":"and","matchers":[{"type":"status","status":[200]},{"type":"or","matchers":[{"type":"nope"}]}]}`, "未知的匹配器类型"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.spec))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package matcher
+
+import (
+	"reflect"
+
+	"github.com/seaung/Luna/internal/network"
+)
+
+// Symbols 是导出给yaegi解释器的匹配器符号表
+// 插件脚本中 import "github.com/seaung/Luna/pkg/matcher" 时使用这些符号
+var Symbols = map[string]map[string]reflect.Value{}
+
+func init() {
+	Symbols["github.com/seaung/Luna/pkg/matcher/matcher"] = map[string]reflect.Value{
+		// 函数
+		"And":        reflect.ValueOf(And),
+		"CSS":        reflect.ValueOf(CSS),
+		"Faster":     reflect.ValueOf(Faster),
+		"Header":     reflect.ValueOf(Header),
+		"JSONPath":   reflect.ValueOf(JSONPath),
+		"MustRegex":  reflect.ValueOf(MustRegex),
+		"Not":        reflect.ValueOf(Not),
+		"Or":         reflect.ValueOf(Or),
+		"Parse":      reflect.ValueOf(Parse),
+		"ParsePhase": reflect.ValueOf(ParsePhase),
+		"QueryJSON":  reflect.ValueOf(QueryJSON),
+		"Regex":      reflect.ValueOf(Regex),
+		"Size":       reflect.ValueOf(Size),
+		"Slower":     reflect.ValueOf(Slower),
+		"Status":     reflect.ValueOf(Status),
+		"Word":       reflect.ValueOf(Word),
+		"XPath":      reflect.ValueOf(XPath),
+
+		// 常量
+		"PartAll":      reflect.ValueOf(PartAll),
+		"PartBody":     reflect.ValueOf(PartBody),
+		"PartHeader":   reflect.ValueOf(PartHeader),
+		"PhaseConnect": reflect.ValueOf(PhaseConnect),
+		"PhaseDNS":     reflect.ValueOf(PhaseDNS),
+		"PhaseTLS":     reflect.ValueOf(PhaseTLS),
+		"PhaseTTFB":    reflect.ValueOf(PhaseTTFB),
+		"PhaseTotal":   reflect.ValueOf(PhaseTotal),
+
+		// 类型
+		"CSSMatcher":      reflect.ValueOf((*CSSMatcher)(nil)),
+		"HeaderMatcher":   reflect.ValueOf((*HeaderMatcher)(nil)),
+		"JSONPathMatcher": reflect.ValueOf((*JSONPathMatcher)(nil)),
+		"Matcher":         reflect.ValueOf((*Matcher)(nil)),
+		"MatcherFunc":     reflect.ValueOf((*MatcherFunc)(nil)),
+		"Part":            reflect.ValueOf((*Part)(nil)),
+		"Phase":           reflect.ValueOf((*Phase)(nil)),
+		"RegexMatcher":    reflect.ValueOf((*RegexMatcher)(nil)),
+		"Result":          reflect.ValueOf((*Result)(nil)),
+		"SizeMatcher":     reflect.ValueOf((*SizeMatcher)(nil)),
+		"Spec":            reflect.ValueOf((*Spec)(nil)),
+		"StatusMatcher":   reflect.ValueOf((*StatusMatcher)(nil)),
+		"TimeMatcher":     reflect.ValueOf((*TimeMatcher)(nil)),
+		"WordMatcher":     reflect.ValueOf((*WordMatcher)(nil)),
+		"XPathMatcher":    reflect.ValueOf((*XPathMatcher)(nil)),
+
+		// 接口包装，允许脚本自行实现Matcher
+		"_Matcher": reflect.ValueOf((*_github_com_seaung_Luna_pkg_matcher_Matcher)(nil)),
+	}
+}
+
+// _github_com_seaung_Luna_pkg_matcher_Matcher 是yaegi用于包装脚本中Matcher实现的类型
+type _github_com_seaung_Luna_pkg_matcher_Matcher struct {
+	IValue interface{}
+	WMatch func(resp *network.HTTPResponse) Result
+}
+
+func (W _github_com_seaung_Luna_pkg_matcher_Matcher) Match(resp *network.HTTPResponse) Result {
+	return W.WMatch(resp)
+}
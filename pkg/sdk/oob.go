@@ -0,0 +1,67 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/seaung/Luna/internal/oob"
+	"github.com/seaung/Luna/pkg/helper"
+)
+
+// ErrOOBDisabled 表示未配置带外回连服务
+var ErrOOBDisabled = errors.New("未配置带外回连服务")
+
+// Callback 是分配给单次执行的回连地址
+type Callback = oob.Callback
+
+// Interaction 表示回连地址收到的一次交互
+type Interaction = oob.Interaction
+
+// OOB 是带外回连辅助接口，用于确认盲注类漏洞
+type OOB interface {
+	// NewCallback 生成一个唯一的回连地址
+	NewCallback() (*Callback, error)
+	// Wait 等待回连地址收到交互，超时后返回已收到的交互
+	Wait(ctx context.Context, cb *Callback, timeout time.Duration) ([]Interaction, error)
+}
+
+// disabledOOB 在未配置回连服务时使用
+type disabledOOB struct{}
+
+func (disabledOOB) NewCallback() (*Callback, error) {
+	return nil, ErrOOBDisabled
+}
+
+func (disabledOOB) Wait(ctx context.Context, cb *Callback, timeout time.Duration) ([]Interaction, error) {
+	return nil, ErrOOBDisabled
+}
+
+// StaticOOB 使用外部回连域名生成回连地址
+// 它只负责生成地址，无法获知交互是否发生，需要人工在外部平台确认
+type StaticOOB struct {
+	Domain string
+}
+
+// NewCallback 生成一个 <token>.<domain> 形式的回连地址
+func (o StaticOOB) NewCallback() (*Callback, error) {
+	if o.Domain == "" {
+		return nil, ErrOOBDisabled
+	}
+
+	token := helper.RandomString(16)
+	domain := token + "." + strings.TrimPrefix(o.Domain, ".")
+
+	return &Callback{
+		Token:  token,
+		Domain: domain,
+		URL:    fmt.Sprintf("http://%s/", domain),
+	}, nil
+}
+
+// Wait 静态回连无法接收交互，总是返回错误
+func (o StaticOOB) Wait(ctx context.Context, cb *Callback, timeout time.Duration) ([]Interaction, error) {
+	return nil, fmt.Errorf("回连域名 %s 由外部平台接收，请人工确认交互", o.Domain)
+}
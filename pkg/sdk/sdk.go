@@ -0,0 +1,316 @@
+// Package sdk 定义插件可使用的宿主服务
+//
+// 插件通过 import "github.com/seaung/Luna/pkg/sdk" 使用本包，
+// 并实现 RunWithEnv(env *sdk.Env, target string) (bool, error) 方法以获得执行环境
+package sdk
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/seaung/Luna/internal/crawler"
+	"github.com/seaung/Luna/internal/network"
+	"github.com/seaung/Luna/internal/storage"
+	"github.com/seaung/Luna/pkg/helper"
+)
+
+// HTTPClient 是宿主提供的HTTP客户端
+type HTTPClient = network.HTTPClient
+
+// HTTPResponse 是HTTP客户端返回的响应
+type HTTPResponse = network.HTTPResponse
+
+// Timing 是响应中DNS解析、建立连接、TLS握手、首字节和总耗时的分解
+type Timing = network.Timing
+
+// RawClient 是按字节原样发送HTTP请求的客户端
+type RawClient = network.RawClient
+
+// RawRequest 是原样发送的HTTP请求
+type RawRequest = network.RawRequest
+
+// RawH2Request 是由原始HTTP/2帧组成的请求，通过 RawClient.SendH2 发送
+type RawH2Request = network.RawH2Request
+
+// H2Frame 是一个原样发送的HTTP/2帧
+type H2Frame = network.H2Frame
+
+// H2Header 是按顺序编码的HTTP/2头部字段
+type H2Header = network.H2Header
+
+// H2Result 是原始HTTP/2请求的结果
+type H2Result = network.H2Result
+
+// HTTP/2帧类型和标志
+const (
+	H2FrameData         = network.H2FrameData
+	H2FrameHeaders      = network.H2FrameHeaders
+	H2FramePriority     = network.H2FramePriority
+	H2FrameRSTStream    = network.H2FrameRSTStream
+	H2FrameSettings     = network.H2FrameSettings
+	H2FramePushPromise  = network.H2FramePushPromise
+	H2FramePing         = network.H2FramePing
+	H2FrameGoAway       = network.H2FrameGoAway
+	H2FrameWindowUpdate = network.H2FrameWindowUpdate
+	H2FrameContinuation = network.H2FrameContinuation
+
+	H2FlagEndStream  = network.H2FlagEndStream
+	H2FlagAck        = network.H2FlagAck
+	H2FlagEndHeaders = network.H2FlagEndHeaders
+	H2FlagPadded     = network.H2FlagPadded
+	H2FlagPriority   = network.H2FlagPriority
+)
+
+// SocketClient 建立TCP/UDP连接，用于非HTTP协议
+type SocketClient = network.SocketClient
+
+// Socket 是一个TCP/UDP连接
+type Socket = network.Socket
+
+// WebSocketClient 建立WebSocket连接
+type WebSocketClient = network.WebSocketClient
+
+// WebSocket 是一个已完成握手的WebSocket连接
+type WebSocket = network.WebSocket
+
+// WSMessage 是收到的一条WebSocket消息
+type WSMessage = network.WSMessage
+
+// WSCloseError 表示对端发送了关闭帧
+type WSCloseError = network.WSCloseError
+
+// WSHandshakeError 表示服务器没有接受WebSocket握手
+type WSHandshakeError = network.WSHandshakeError
+
+// WebSocket操作码
+const (
+	WSContinuation = network.WSContinuation
+	WSText         = network.WSText
+	WSBinary       = network.WSBinary
+	WSClose        = network.WSClose
+	WSPing         = network.WSPing
+	WSPong         = network.WSPong
+)
+
+// RequestBody 是能够自行编码并给出Content-Type的请求体
+type RequestBody = network.RequestBody
+
+// FormBody 是保持字段顺序的URL编码表单
+type FormBody = network.FormBody
+
+// FormField 是表单中的一个字段
+type FormField = network.FormField
+
+// MultipartBody 是multipart/form-data请求体
+type MultipartBody = network.MultipartBody
+
+// MultipartPart 是multipart表单中的一个部分
+type MultipartPart = network.MultipartPart
+
+// XMLBody 是XML请求体
+type XMLBody = network.XMLBody
+
+// RawBody 是带显式Content-Type的原始请求体
+type RawBody = network.RawBody
+
+// RedirectPolicy 是重定向策略
+type RedirectPolicy = network.RedirectPolicy
+
+// 重定向处理方式
+const (
+	RedirectFollow = network.RedirectFollow
+	RedirectNone   = network.RedirectNone
+)
+
+// DelayDetector 通过统计检验检测时间盲注
+type DelayDetector = network.DelayDetector
+
+// DelayProbe 发送一次注入了指定延迟的请求
+type DelayProbe = network.DelayProbe
+
+// DelayResult 是时间盲注检测的结果
+type DelayResult = network.DelayResult
+
+// DelaySample 是一次请求注入的延迟和观测到的耗时
+type DelaySample = network.DelaySample
+
+// ResponseBaseline 从多次相同请求的响应中学习页面的动态内容
+type ResponseBaseline = network.ResponseBaseline
+
+// SimilarityVerdict 是两个响应的比较结果
+type SimilarityVerdict = network.SimilarityVerdict
+
+// Endpoint 是爬虫发现的端点
+type Endpoint = crawler.Endpoint
+
+// Param 是端点接受的一个参数
+type Param = crawler.Param
+
+// ParamLocation 是参数所在的位置
+type ParamLocation = crawler.ParamLocation
+
+// 参数位置
+const (
+	ParamQuery = crawler.ParamQuery
+	ParamBody  = crawler.ParamBody
+)
+
+// Inventory 是爬虫生成的端点清单
+type Inventory = crawler.Inventory
+
+// Logger 是带作用域的日志记录器
+type Logger = helper.Logger
+
+// KV 是按目标隔离的键值空间
+type KV = storage.KVBucket
+
+// Services 保存宿主在每次执行时注入插件的共享服务
+type Services struct {
+	// Scan 是本次扫描的标识，与插件名和目标一起标记插件产生的流量
+	Scan      string
+	HTTP      network.HTTPClient
+	Raw       *network.RawClient
+	Socket    *network.SocketClient
+	WebSocket *network.WebSocketClient
+	KV        *storage.KVStore
+	LogLevel  helper.Level
+	// OOB 为每次执行创建带外回连辅助对象，为空时插件无法使用回连
+	OOB func(pluginName, target string) OOB
+	// Endpoints 是爬虫生成的端点清单，为空时插件看不到已发现的端点
+	Endpoints *crawler.Inventory
+}
+
+// Env 是单次插件执行的环境
+type Env struct {
+	Plugin    string
+	Target    string
+	HTTP      HTTPClient
+	Raw       *RawClient
+	Socket    *SocketClient
+	WebSocket *WebSocketClient
+	Log       *Logger
+	KV        *KV
+	OOB       OOB
+	// Endpoints 是爬虫在目标主机上发现的端点，未爬取时为空
+	Endpoints []*Endpoint
+}
+
+// NewEnv 根据共享服务创建单次执行的环境
+// out 是本次执行的输出缓冲区，插件日志会写入其中
+func NewEnv(svc Services, pluginName, target string, out io.Writer) *Env {
+	tags := network.Tags{
+		Scan:   svc.Scan,
+		Plugin: pluginName,
+		Target: target,
+	}
+
+	env := &Env{
+		Plugin: pluginName,
+		Target: target,
+		HTTP:   network.Tagged(svc.HTTP, tags),
+		Log:    helper.NewLogger(out, svc.LogLevel).Scope(pluginName),
+	}
+
+	if svc.Raw != nil {
+		env.Raw = svc.Raw.WithTags(tags)
+	}
+
+	if svc.Socket != nil {
+		env.Socket = svc.Socket.WithTags(tags)
+		// 调试级别下输出套接字收发数据的十六进制转储
+		if svc.LogLevel <= helper.LevelDebug {
+			env.Socket = env.Socket.WithDump(debugWriter{env.Log})
+		}
+	}
+
+	if svc.WebSocket != nil {
+		env.WebSocket = svc.WebSocket.WithTags(tags)
+	}
+
+	if svc.KV != nil {
+		env.KV = svc.KV.Bucket(target)
+	}
+
+	if svc.OOB != nil {
+		env.OOB = svc.OOB(pluginName, target)
+	} else {
+		env.OOB = disabledOOB{}
+	}
+
+	if svc.Endpoints != nil {
+		env.Endpoints = svc.Endpoints.ForHost(target)
+	}
+
+	return env
+}
+
+// debugWriter 将写入的内容作为调试日志输出
+type debugWriter struct {
+	log *Logger
+}
+
+func (w debugWriter) Write(p []byte) (int, error) {
+	w.log.Debugf("%s", strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// NewRawRequest 根据目标URL创建原始请求
+func NewRawRequest(target string, data []byte) (*RawRequest, error) {
+	return network.NewRawRequest(target, data)
+}
+
+// NewForm 根据交替出现的名称和值创建保持顺序的表单
+func NewForm(pairs ...string) *FormBody {
+	return network.NewForm(pairs...)
+}
+
+// NewMultipart 创建multipart表单
+func NewMultipart() *MultipartBody {
+	return network.NewMultipart()
+}
+
+// NewRawH2Request 根据目标URL创建原始HTTP/2请求
+func NewRawH2Request(target string, frames ...H2Frame) (*RawH2Request, error) {
+	return network.NewRawH2Request(target, frames...)
+}
+
+// H2HeadersFrame 使用HPACK编码头部字段并创建HEADERS帧
+func H2HeadersFrame(streamID uint32, headers []H2Header, endStream bool) H2Frame {
+	return network.H2HeadersFrame(streamID, headers, endStream)
+}
+
+// H2DataFrame 创建DATA帧
+func H2DataFrame(streamID uint32, data []byte, endStream bool) H2Frame {
+	return network.H2DataFrame(streamID, data, endStream)
+}
+
+// NewDelayDetector 创建使用默认参数的时间盲注检测器
+func NewDelayDetector() *DelayDetector {
+	return network.NewDelayDetector()
+}
+
+// NewResponseBaseline 根据多次相同请求的响应学习动态内容，建议至少三个响应
+func NewResponseBaseline(responses ...*HTTPResponse) (*ResponseBaseline, error) {
+	return network.NewResponseBaseline(responses...)
+}
+
+// Similarity 返回两个响应体的相似度，0表示完全不同，1表示相同
+func Similarity(a, b *HTTPResponse) float64 {
+	return network.Similarity(a, b)
+}
+
+// WithRedirectPolicy 为单个请求指定重定向策略
+func WithRedirectPolicy(ctx context.Context, policy RedirectPolicy) context.Context {
+	return network.WithRedirectPolicy(ctx, policy)
+}
+
+// Marker 生成一个随机标记，用于在响应中确认注入的内容
+func (e *Env) Marker() string {
+	return "luna" + helper.RandomString(12)
+}
+
+// RandomString 生成指定长度的随机字符串
+func (e *Env) RandomString(n int) string {
+	return helper.RandomString(n)
+}
@@ -0,0 +1,204 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seaung/Luna/internal/network"
+	"github.com/seaung/Luna/internal/storage"
+	"github.com/seaung/Luna/pkg/helper"
+)
+
+// testServices 返回共享同一个流量记录的宿主服务
+func testServices(traffic *network.TrafficLog, level helper.Level) Services {
+	config := network.DefaultHTTPClientConfig()
+	config.Timeout = 2 * time.Second
+	config.Traffic = traffic
+
+	return Services{
+		Scan:      "scan-1",
+		HTTP:      network.NewHTTPClient(config),
+		Raw:       network.NewRawClient(config),
+		Socket:    network.NewSocketClient(config),
+		WebSocket: network.NewWebSocketClient(config),
+		KV:        storage.NewKVStore(),
+		LogLevel:  level,
+	}
+}
+
+func TestNewEnvTagsTraffic(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	addr := srv.Listener.Addr().String()
+
+	traffic := network.NewTrafficLog(0)
+	env := NewEnv(testServices(traffic, helper.LevelInfo), "probe", "example.com", &bytes.Buffer{})
+	if env.Plugin != "probe" || env.Target != "example.com" {
+		t.Fatalf("env = %s %s", env.Plugin, env.Target)
+	}
+
+	ctx := context.Background()
+	if _, err := env.HTTP.Get(ctx, srv.URL, nil); err != nil {
+		t.Fatalf("HTTP: %v", err)
+	}
+	if _, err := env.Raw.Send(ctx, &RawRequest{Addr: addr, Data: []byte("GET / HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n")}); err != nil {
+		t.Fatalf("Raw: %v", err)
+	}
+	s, err := env.Socket.Dial(ctx, "tcp", addr)
+	if err != nil {
+		t.Fatalf("Socket: %v", err)
+	}
+	s.SendString("GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+	s.ReadUntil([]byte("ok"))
+	s.Close()
+
+	want := network.Tags{Scan: "scan-1", Plugin: "probe", Target: "example.com"}
+	entries := traffic.List(network.TrafficFilter{})
+	if len(entries) != 3 {
+		t.Fatalf("entries = %d, want 3", len(entries))
+	}
+	for _, e := range entries {
+		if e.Tags != want {
+			t.Errorf("%s %s tags = %+v, want %+v", e.Method, e.URL, e.Tags, want)
+		}
+	}
+
+	// 其他插件的流量可以按插件名区分
+	other := NewEnv(testServices(traffic, helper.LevelInfo), "other", "example.com", &bytes.Buffer{})
+	other.HTTP.Get(ctx, srv.URL, nil)
+	if n := len(traffic.List(network.TrafficFilter{Plugin: "probe"})); n != 3 {
+		t.Errorf("probe entries = %d, want 3", n)
+	}
+	if n := len(traffic.List(network.TrafficFilter{Plugin: "other"})); n != 1 {
+		t.Errorf("other entries = %d, want 1", n)
+	}
+}
+
+func TestNewEnvKVPerTarget(t *testing.T) {
+	svc := testServices(nil, helper.LevelInfo)
+
+	a := NewEnv(svc, "p1", "a.example.com", nil)
+	a.KV.Set("token", "t-a")
+
+	// 同一目标的不同插件和后续执行共享数据
+	if v, ok := NewEnv(svc, "p2", "a.example.com", nil).KV.Get("token"); !ok || v != "t-a" {
+		t.Errorf("same target Get = %q, %v, want t-a", v, ok)
+	}
+	// 不同目标互相隔离
+	if v, ok := NewEnv(svc, "p1", "b.example.com", nil).KV.Get("token"); ok {
+		t.Errorf("other target Get = %q, want missing", v)
+	}
+
+	// 未提供存储时KV为空
+	if env := NewEnv(Services{HTTP: svc.HTTP}, "p1", "a.example.com", nil); env.KV != nil {
+		t.Error("KV set without a store")
+	}
+}
+
+func TestNewEnvLogger(t *testing.T) {
+	tests := []struct {
+		name  string
+		level helper.Level
+		want  []string
+		skip  []string
+	}{
+		{"info", helper.LevelInfo, []string{"[INFO] [probe] found x", "[WARN] [probe] careful"}, []string{"details"}},
+		{"warn", helper.LevelWarn, []string{"[WARN] [probe] careful"}, []string{"found x", "details"}},
+		{"debug", helper.LevelDebug, []string{"[DEBUG] [probe] details", "[INFO] [probe] found x"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			env := NewEnv(testServices(nil, tt.level), "probe", "example.com", &out)
+			env.Log.Debugf("details")
+			env.Log.Infof("found %s", "x")
+			env.Log.Warnf("careful")
+
+			for _, s := range tt.want {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("output %q missing %q", out.String(), s)
+				}
+			}
+			for _, s := range tt.skip {
+				if strings.Contains(out.String(), s) {
+					t.Errorf("output %q contains %q", out.String(), s)
+				}
+			}
+		})
+	}
+}
+
+func TestNewEnvSocketDump(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	for _, level := range []helper.Level{helper.LevelDebug, helper.LevelInfo} {
+		var out bytes.Buffer
+		env := NewEnv(testServices(nil, level), "probe", "example.com", &out)
+		s, err := env.Socket.Dial(context.Background(), "tcp", srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.SendString("PING")
+		s.Close()
+
+		// 只有调试级别输出收发数据的十六进制转储
+		dumped := strings.Contains(out.String(), "50 49 4e 47")
+		if dumped != (level == helper.LevelDebug) {
+			t.Errorf("level %s dumped = %v, output %q", level, dumped, out.String())
+		}
+	}
+}
+
+func TestNewEnvOOB(t *testing.T) {
+	env := NewEnv(testServices(nil, helper.LevelInfo), "probe", "example.com", nil)
+	if _, err := env.OOB.NewCallback(); !errors.Is(err, ErrOOBDisabled) {
+		t.Errorf("NewCallback error = %v, want ErrOOBDisabled", err)
+	}
+	if _, err := env.OOB.Wait(context.Background(), nil, time.Second); !errors.Is(err, ErrOOBDisabled) {
+		t.Errorf("Wait error = %v, want ErrOOBDisabled", err)
+	}
+
+	// 宿主按插件和目标创建回连辅助对象
+	svc := testServices(nil, helper.LevelInfo)
+	var got string
+	svc.OOB = func(pluginName, target string) OOB {
+		got = pluginName + "@" + target
+		return StaticOOB{Domain: "oob.test"}
+	}
+	env = NewEnv(svc, "probe", "example.com", nil)
+	cb, err := env.OOB.NewCallback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "probe@example.com" || !strings.HasSuffix(cb.Domain, ".oob.test") || cb.URL != "http://"+cb.Domain+"/" {
+		t.Errorf("callback %+v created for %q", cb, got)
+	}
+}
+
+func TestMarker(t *testing.T) {
+	env := NewEnv(testServices(nil, helper.LevelInfo), "probe", "example.com", nil)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		m := env.Marker()
+		if len(m) != 16 || !strings.HasPrefix(m, "luna") {
+			t.Fatalf("Marker = %q", m)
+		}
+		if seen[m] {
+			t.Fatalf("Marker repeated %q", m)
+		}
+		seen[m] = true
+	}
+	if s := env.RandomString(8); len(s) != 8 {
+		t.Errorf("RandomString(8) = %q", s)
+	}
+}
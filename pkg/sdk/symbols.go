@@ -0,0 +1,96 @@
+package sdk
+
+import "reflect"
+
+// Symbols 是导出给yaegi解释器的SDK符号表
+// 插件脚本中 import "github.com/seaung/Luna/pkg/sdk" 时使用这些符号
+var Symbols = map[string]map[string]reflect.Value{}
+
+func init() {
+	Symbols["github.com/seaung/Luna/pkg/sdk/sdk"] = map[string]reflect.Value{
+		// 函数
+		"H2DataFrame":         reflect.ValueOf(H2DataFrame),
+		"H2HeadersFrame":      reflect.ValueOf(H2HeadersFrame),
+		"NewDelayDetector":    reflect.ValueOf(NewDelayDetector),
+		"NewForm":             reflect.ValueOf(NewForm),
+		"NewMultipart":        reflect.ValueOf(NewMultipart),
+		"NewRawH2Request":     reflect.ValueOf(NewRawH2Request),
+		"NewRawRequest":       reflect.ValueOf(NewRawRequest),
+		"NewResponseBaseline": reflect.ValueOf(NewResponseBaseline),
+		"Similarity":          reflect.ValueOf(Similarity),
+		"WithRedirectPolicy":  reflect.ValueOf(WithRedirectPolicy),
+
+		// 常量
+		"H2FlagAck":           reflect.ValueOf(H2FlagAck),
+		"H2FlagEndHeaders":    reflect.ValueOf(H2FlagEndHeaders),
+		"H2FlagEndStream":     reflect.ValueOf(H2FlagEndStream),
+		"H2FlagPadded":        reflect.ValueOf(H2FlagPadded),
+		"H2FlagPriority":      reflect.ValueOf(H2FlagPriority),
+		"H2FrameContinuation": reflect.ValueOf(H2FrameContinuation),
+		"H2FrameData":         reflect.ValueOf(H2FrameData),
+		"H2FrameGoAway":       reflect.ValueOf(H2FrameGoAway),
+		"H2FrameHeaders":      reflect.ValueOf(H2FrameHeaders),
+		"H2FramePing":         reflect.ValueOf(H2FramePing),
+		"H2FramePriority":     reflect.ValueOf(H2FramePriority),
+		"H2FramePushPromise":  reflect.ValueOf(H2FramePushPromise),
+		"H2FrameRSTStream":    reflect.ValueOf(H2FrameRSTStream),
+		"H2FrameSettings":     reflect.ValueOf(H2FrameSettings),
+		"H2FrameWindowUpdate": reflect.ValueOf(H2FrameWindowUpdate),
+		"ParamBody":           reflect.ValueOf(ParamBody),
+		"ParamQuery":          reflect.ValueOf(ParamQuery),
+		"RedirectFollow":      reflect.ValueOf(RedirectFollow),
+		"RedirectNone":        reflect.ValueOf(RedirectNone),
+		"WSBinary":            reflect.ValueOf(WSBinary),
+		"WSClose":             reflect.ValueOf(WSClose),
+		"WSContinuation":      reflect.ValueOf(WSContinuation),
+		"WSPing":              reflect.ValueOf(WSPing),
+		"WSPong":              reflect.ValueOf(WSPong),
+		"WSText":              reflect.ValueOf(WSText),
+
+		// 变量
+		"ErrOOBDisabled": reflect.ValueOf(&ErrOOBDisabled).Elem(),
+
+		// 类型
+		"Callback":          reflect.ValueOf((*Callback)(nil)),
+		"DelayDetector":     reflect.ValueOf((*DelayDetector)(nil)),
+		"DelayProbe":        reflect.ValueOf((*DelayProbe)(nil)),
+		"DelayResult":       reflect.ValueOf((*DelayResult)(nil)),
+		"DelaySample":       reflect.ValueOf((*DelaySample)(nil)),
+		"Endpoint":          reflect.ValueOf((*Endpoint)(nil)),
+		"Env":               reflect.ValueOf((*Env)(nil)),
+		"FormBody":          reflect.ValueOf((*FormBody)(nil)),
+		"FormField":         reflect.ValueOf((*FormField)(nil)),
+		"H2Frame":           reflect.ValueOf((*H2Frame)(nil)),
+		"H2Header":          reflect.ValueOf((*H2Header)(nil)),
+		"H2Result":          reflect.ValueOf((*H2Result)(nil)),
+		"HTTPClient":        reflect.ValueOf((*HTTPClient)(nil)),
+		"HTTPResponse":      reflect.ValueOf((*HTTPResponse)(nil)),
+		"Interaction":       reflect.ValueOf((*Interaction)(nil)),
+		"Inventory":         reflect.ValueOf((*Inventory)(nil)),
+		"KV":                reflect.ValueOf((*KV)(nil)),
+		"Logger":            reflect.ValueOf((*Logger)(nil)),
+		"MultipartBody":     reflect.ValueOf((*MultipartBody)(nil)),
+		"MultipartPart":     reflect.ValueOf((*MultipartPart)(nil)),
+		"OOB":               reflect.ValueOf((*OOB)(nil)),
+		"Param":             reflect.ValueOf((*Param)(nil)),
+		"ParamLocation":     reflect.ValueOf((*ParamLocation)(nil)),
+		"RawBody":           reflect.ValueOf((*RawBody)(nil)),
+		"RawClient":         reflect.ValueOf((*RawClient)(nil)),
+		"RawH2Request":      reflect.ValueOf((*RawH2Request)(nil)),
+		"RawRequest":        reflect.ValueOf((*RawRequest)(nil)),
+		"RedirectPolicy":    reflect.ValueOf((*RedirectPolicy)(nil)),
+		"RequestBody":       reflect.ValueOf((*RequestBody)(nil)),
+		"ResponseBaseline":  reflect.ValueOf((*ResponseBaseline)(nil)),
+		"SimilarityVerdict": reflect.ValueOf((*SimilarityVerdict)(nil)),
+		"Socket":            reflect.ValueOf((*Socket)(nil)),
+		"SocketClient":      reflect.ValueOf((*SocketClient)(nil)),
+		"StaticOOB":         reflect.ValueOf((*StaticOOB)(nil)),
+		"Timing":            reflect.ValueOf((*Timing)(nil)),
+		"WSCloseError":      reflect.ValueOf((*WSCloseError)(nil)),
+		"WSHandshakeError":  reflect.ValueOf((*WSHandshakeError)(nil)),
+		"WSMessage":         reflect.ValueOf((*WSMessage)(nil)),
+		"WebSocket":         reflect.ValueOf((*WebSocket)(nil)),
+		"WebSocketClient":   reflect.ValueOf((*WebSocketClient)(nil)),
+		"XMLBody":           reflect.ValueOf((*XMLBody)(nil)),
+	}
+}
@@ -4,7 +4,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"strings"
+
+	"github.com/seaung/Luna/pkg/sdk"
 )
 
 // PluginMeta 定义插件的元数据
@@ -62,6 +66,28 @@ func (p *MyPlugin) Run(target string) (bool, error) {
 	return true, nil
 }
 
+// RunWithEnv 是可选方法，实现后Luna会优先调用它并注入宿主服务
+// env.HTTP: 共享的HTTP客户端，遵循shell中的超时、重试等设置
+// env.Log:  带作用域的日志记录器，输出随执行结果一起保存
+// env.KV:   当前目标的键值存储，可在多次执行间共享数据
+// env.OOB:  带外回连辅助对象
+func (p *MyPlugin) RunWithEnv(env *sdk.Env, target string) (bool, error) {
+	if !p.validateTarget(target) {
+		return false, fmt.Errorf("目标不能为空")
+	}
+
+	marker := env.Marker()
+	env.Log.Infof("使用标记 %s 检测目标 %s", marker, target)
+
+	resp, err := env.HTTP.Get(context.Background(), target+"?q="+marker, nil)
+	if err != nil {
+		return false, err
+	}
+
+	env.KV.Set("last_status", fmt.Sprint(resp.StatusCode))
+	return strings.Contains(resp.String(), marker), nil
+}
+
 // 以下是一些可选的辅助函数示例
 
 // 检查目标是否有效